@@ -0,0 +1,280 @@
+package cloudfirestore
+
+import (
+	"context"
+	"maps"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/rabee-inc/go-pkg/firebaseauth"
+	"github.com/rabee-inc/go-pkg/log"
+	"github.com/rabee-inc/go-pkg/timeutil"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// FieldDeletedAt ... 論理削除した日時のフィールド
+	FieldDeletedAt = "deleted_at"
+	// HistoryCollection ... 変更前のドキュメントを保存するサブコレクション
+	HistoryCollection = "_history"
+)
+
+// HistoryOperation ... 履歴を保存した書き込みの種類
+type HistoryOperation string
+
+const (
+	HistoryOperationSet    HistoryOperation = "set"
+	HistoryOperationUpdate HistoryOperation = "update"
+	HistoryOperationDelete HistoryOperation = "delete"
+)
+
+// History ... 書き込む前のドキュメントの履歴
+type History struct {
+	ID        string                 `firestore:"-" cloudfirestore:"id"`
+	Ref       *firestore.DocumentRef `firestore:"-" cloudfirestore:"ref"`
+	Operation HistoryOperation       `firestore:"operation"`
+	// 書き込んだユーザーのID (firebaseauth.GetUserID)
+	Actor string `firestore:"actor"`
+	// 変更前のデータ。ドキュメントがなかった場合は nil
+	Data      map[string]any `firestore:"data"`
+	CreatedAt time.Time      `firestore:"created_at"`
+}
+
+var auditCollections = struct {
+	mutex sync.RWMutex
+	ids   map[string]bool
+}{ids: map[string]bool{}}
+
+// EnableAudit ... コレクションIDを指定して論理削除と履歴の保存を有効にする
+// コレクションのパスではなくIDで判定するため、同じIDのサブコレクション (例: "users" を指定した場合の "groups/{id}/users") も対象になる。
+// Set, Update, Delete は変更前のドキュメントを HistoryCollection に保存し、 Delete は削除せずに FieldDeletedAt を設定する。
+// Get, GetMulti は論理削除したドキュメントを返さない。
+// GetByQuery, ListByQuery, ListByQueryCursor, ListByQueryCursorToken は FieldDeletedAt が null のドキュメントのみ取得するため、
+// Create, Set は src に FieldDeletedAt がない場合に null の FieldDeletedAt を追加して保存する。
+// トランザクションでは書き込む前に同じトランザクションで Get, GetMulti したドキュメントを変更前のドキュメントとし、
+// 取得していない場合、または同じトランザクションで書き込み済みの場合はエラーを返す
+func EnableAudit(collectionIDs ...string) {
+	auditCollections.mutex.Lock()
+	defer auditCollections.mutex.Unlock()
+	for _, id := range collectionIDs {
+		auditCollections.ids[id] = true
+	}
+}
+
+func hasAuditCollections() bool {
+	auditCollections.mutex.RLock()
+	defer auditCollections.mutex.RUnlock()
+	return len(auditCollections.ids) > 0
+}
+
+func isAuditCollection(collectionID string) bool {
+	auditCollections.mutex.RLock()
+	defer auditCollections.mutex.RUnlock()
+	return auditCollections.ids[collectionID]
+}
+
+// WithDeleted ... 論理削除したドキュメントも取得する
+func WithDeleted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxWithDeletedKey, true)
+}
+
+func isSoftDeleted(ctx context.Context, dsnp *firestore.DocumentSnapshot) bool {
+	if !isAuditCollection(dsnp.Ref.Parent.ID) {
+		return false
+	}
+	if withDeleted, _ := ctx.Value(ctxWithDeletedKey).(bool); withDeleted {
+		return false
+	}
+	deletedAt, err := dsnp.DataAt(FieldDeletedAt)
+	return err == nil && deletedAt != nil
+}
+
+// 論理削除を有効にしたコレクションのクエリの場合、論理削除したドキュメントを除く条件を追加する
+func excludeSoftDeleted(ctx context.Context, query firestore.Query) firestore.Query {
+	if !hasAuditCollections() {
+		return query
+	}
+	if withDeleted, _ := ctx.Value(ctxWithDeletedKey).(bool); withDeleted {
+		return query
+	}
+	req, err := serializeQuery(query)
+	if err != nil {
+		return query
+	}
+	for _, from := range req.GetStructuredQuery().GetFrom() {
+		if isAuditCollection(from.CollectionId) {
+			return query.Where(FieldDeletedAt, "==", nil)
+		}
+	}
+	return query
+}
+
+// 論理削除を有効にしたコレクションの場合、 FieldDeletedAt がない src に null の FieldDeletedAt を追加する
+// クエリで除外されないように、作成時から FieldDeletedAt を保存する
+func withDeletedAtField(colRef *firestore.CollectionRef, src any) (dst any) {
+	if !isAuditCollection(colRef.ID) {
+		return src
+	}
+	if m, ok := src.(map[string]any); ok {
+		if _, ok := m[FieldDeletedAt]; ok {
+			return src
+		}
+		dst := make(map[string]any, len(m)+1)
+		maps.Copy(dst, m)
+		dst[FieldDeletedAt] = nil
+		return dst
+	}
+	rv := reflect.Indirect(reflect.ValueOf(src))
+	if rv.Kind() != reflect.Struct || hasFirestoreField(rv.Type(), FieldDeletedAt) {
+		return src
+	}
+	// 埋め込めない型 (reflect.StructOf が対応していないメソッドを持つ型など) はそのまま保存する
+	defer func() {
+		if recover() != nil {
+			dst = src
+		}
+	}()
+	rt := reflect.StructOf([]reflect.StructField{
+		{Name: "Src", Type: rv.Type(), Anonymous: true},
+		{Name: "DeletedAt", Type: reflect.TypeFor[*time.Time](), Tag: `firestore:"deleted_at"`},
+	})
+	wrapped := reflect.New(rt)
+	wrapped.Elem().Field(0).Set(rv)
+	return wrapped.Interface()
+}
+
+// 埋め込んだ構造体を含めて、 firestore タグの名前が name のフィールドを持つか
+func hasFirestoreField(rt reflect.Type, name string) bool {
+	for i := range rt.NumField() {
+		field := rt.Field(i)
+		tag, _, _ := strings.Cut(field.Tag.Get("firestore"), ",")
+		if tag == name {
+			return true
+		}
+		if tag != "" || !field.Anonymous {
+			continue
+		}
+		ft := field.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct && hasFirestoreField(ft, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// 書き込む前のドキュメントの履歴
+type history struct {
+	docRef *firestore.DocumentRef
+	dsnp   *firestore.DocumentSnapshot
+	src    *History
+}
+
+// 論理削除を有効にしたコレクションの場合は変更前のドキュメントを取得する。有効でない場合は nil を返す
+func newHistory(ctx context.Context, docRef *firestore.DocumentRef, operation HistoryOperation) (*history, error) {
+	if !isAuditCollection(docRef.Parent.ID) {
+		return nil, nil
+	}
+	dsnp, ok := getContextTxSnapshot(ctx, docRef)
+	if !ok {
+		// トランザクションの外で取得すると、並行した書き込みにより履歴が実際の変更前のドキュメントと異なる場合がある
+		if tx := getContextTransaction(ctx); tx != nil {
+			err := log.Warninge(ctx, "cloudfirestore: %s must be read with Get or GetMulti in the transaction before writing", docRef.Path)
+			return nil, err
+		}
+		var err error
+		dsnp, err = docRef.Get(ctx)
+		if err != nil && status.Code(err) != codes.NotFound {
+			log.Warning(ctx, err)
+			return nil, err
+		}
+	}
+	src := &History{
+		Operation: operation,
+		Actor:     firebaseauth.GetUserID(ctx),
+		CreatedAt: timeutil.Now(),
+	}
+	if dsnp.Exists() {
+		src.Data = dsnp.Data()
+	}
+	return &history{docRef, dsnp, src}, nil
+}
+
+func (h *history) softDeleted() bool {
+	if !h.dsnp.Exists() {
+		return false
+	}
+	deletedAt, err := h.dsnp.DataAt(FieldDeletedAt)
+	return err == nil && deletedAt != nil
+}
+
+// 書き込みと同じトランザクション、 BulkWriter で履歴を保存する
+func (h *history) write(ctx context.Context) error {
+	if h == nil {
+		return nil
+	}
+	// トランザクションで取得したドキュメントは書き込み後のドキュメントと異なるため、再び書き込む場合はエラーにする
+	deleteContextTxSnapshot(ctx, h.docRef)
+	return Set(ctx, h.docRef.Collection(HistoryCollection).NewDoc(), h.src)
+}
+
+// ListHistories ... ドキュメントの履歴を新しい順に取得する(tx対応)
+func ListHistories(ctx context.Context, docRef *firestore.DocumentRef) ([]*History, error) {
+	query := docRef.Collection(HistoryCollection).OrderBy("created_at", firestore.Desc)
+	dsts := []*History{}
+	if err := ListByQuery(ctx, query, &dsts); err != nil {
+		return nil, err
+	}
+	return dsts, nil
+}
+
+// トランザクションで取得したドキュメント
+type txSnapshots struct {
+	mutex sync.Mutex
+	dsnps map[string]*firestore.DocumentSnapshot
+}
+
+func setContextTxSnapshots(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ctxTxSnapshotsKey, &txSnapshots{dsnps: map[string]*firestore.DocumentSnapshot{}})
+}
+
+func addContextTxSnapshots(ctx context.Context, dsnps ...*firestore.DocumentSnapshot) {
+	snapshots, ok := ctx.Value(ctxTxSnapshotsKey).(*txSnapshots)
+	if !ok {
+		return
+	}
+	snapshots.mutex.Lock()
+	defer snapshots.mutex.Unlock()
+	for _, dsnp := range dsnps {
+		if dsnp != nil && isAuditCollection(dsnp.Ref.Parent.ID) {
+			snapshots.dsnps[dsnp.Ref.Path] = dsnp
+		}
+	}
+}
+
+func getContextTxSnapshot(ctx context.Context, docRef *firestore.DocumentRef) (*firestore.DocumentSnapshot, bool) {
+	snapshots, ok := ctx.Value(ctxTxSnapshotsKey).(*txSnapshots)
+	if !ok {
+		return nil, false
+	}
+	snapshots.mutex.Lock()
+	defer snapshots.mutex.Unlock()
+	dsnp, ok := snapshots.dsnps[docRef.Path]
+	return dsnp, ok
+}
+
+func deleteContextTxSnapshot(ctx context.Context, docRef *firestore.DocumentRef) {
+	snapshots, ok := ctx.Value(ctxTxSnapshotsKey).(*txSnapshots)
+	if !ok {
+		return
+	}
+	snapshots.mutex.Lock()
+	defer snapshots.mutex.Unlock()
+	delete(snapshots.dsnps, docRef.Path)
+}
@@ -0,0 +1,409 @@
+package cloudfirestore_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/rabee-inc/go-pkg/cloudfirestore"
+	"github.com/rabee-inc/go-pkg/firebaseauth"
+)
+
+// 論理削除を有効にしたコレクションのドキュメント。クエリで除外するため deleted_at を常に保存する
+type auditUser struct {
+	ID        string                 `firestore:"-" cloudfirestore:"id"`
+	Ref       *firestore.DocumentRef `firestore:"-" cloudfirestore:"ref"`
+	Name      string                 `firestore:"name"`
+	Age       int                    `firestore:"age"`
+	DeletedAt *time.Time             `firestore:"deleted_at"`
+}
+
+// deleted_at を持たないドキュメント
+type auditPost struct {
+	ID    string `firestore:"-" cloudfirestore:"id"`
+	Title string `firestore:"title"`
+}
+
+// Authorization ヘッダーをユーザーIDとして認証する
+type testAuthService struct{}
+
+func (s *testAuthService) Authentication(ctx context.Context, ah string) (string, map[string]any, error) {
+	return ah, nil, nil
+}
+
+func (s *testAuthService) SetCustomClaims(ctx context.Context, userID string, claims map[string]any) error {
+	return nil
+}
+
+// 認証したユーザーIDを持つ context を作成する
+func newAuthContext(ctx context.Context, userID string) context.Context {
+	m := firebaseauth.NewMiddleware(&testAuthService{}, false)
+	r := httptest.NewRequestWithContext(ctx, http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", userID)
+	m.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx = r.Context()
+	})).ServeHTTP(httptest.NewRecorder(), r)
+	return ctx
+}
+
+func Test_Audit(t *testing.T) {
+	cloudfirestore.EnableAudit("audit_users", "audit_posts")
+
+	type args struct {
+		run func(ctx context.Context, cFirestore *firestore.Client) (string, error)
+	}
+	type want struct {
+		result    string
+		users     string
+		histories string
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "論理削除したドキュメントは取得しない",
+			args: args{
+				run: func(ctx context.Context, cFirestore *firestore.Client) (string, error) {
+					docRef := cFirestore.Doc("audit_users/u1")
+					if err := cloudfirestore.Delete(ctx, docRef); err != nil {
+						return "", err
+					}
+					user := &auditUser{}
+					exists, err := cloudfirestore.Get(ctx, docRef, user)
+					if err != nil {
+						return "", err
+					}
+					users := []*auditUser{}
+					err = cloudfirestore.GetMulti(ctx, cFirestore, []*firestore.DocumentRef{docRef}, &users)
+					if err != nil {
+						return "", err
+					}
+					found, err := cloudfirestore.GetByQuery(ctx, cFirestore.Collection("audit_users").OrderBy("age", firestore.Asc), user)
+					if err != nil {
+						return "", err
+					}
+					deleted, err := cloudfirestore.Get(cloudfirestore.WithDeleted(ctx), docRef, &auditUser{})
+					if err != nil {
+						return "", err
+					}
+					return fmt.Sprintf("%v:%d:%v:%s:%v", exists, len(users), found, user.ID, deleted), nil
+				},
+			},
+			want: want{
+				result:    "false:0:true:u2:true",
+				users:     "u2:jiro:30",
+				histories: "u1:delete:user1:20",
+			},
+		},
+		{
+			name: "書き込む前のドキュメントを履歴に保存する",
+			args: args{
+				run: func(ctx context.Context, cFirestore *firestore.Client) (string, error) {
+					docRef := cFirestore.Doc("audit_users/u1")
+					if err := cloudfirestore.Update(ctx, docRef, map[string]any{"age": 21}); err != nil {
+						return "", err
+					}
+					if err := cloudfirestore.Set(ctx, docRef, &auditUser{Name: "taro", Age: 22}); err != nil {
+						return "", err
+					}
+					if err := cloudfirestore.Set(ctx, cFirestore.Doc("audit_users/u3"), &auditUser{Name: "saburo", Age: 15}); err != nil {
+						return "", err
+					}
+					return "", nil
+				},
+			},
+			want: want{
+				users:     "u1:taro:22,u2:jiro:30,u3:saburo:15",
+				histories: "u1:set:user1:21,u1:update:user1:20,u3:set:user1:<nil>",
+			},
+		},
+		{
+			name: "論理削除したドキュメントを上書きすると復元する",
+			args: args{
+				run: func(ctx context.Context, cFirestore *firestore.Client) (string, error) {
+					docRef := cFirestore.Doc("audit_users/u2")
+					if err := cloudfirestore.Delete(ctx, docRef); err != nil {
+						return "", err
+					}
+					// 論理削除済みのドキュメントの削除は何もしない
+					if err := cloudfirestore.Delete(ctx, docRef); err != nil {
+						return "", err
+					}
+					return "", cloudfirestore.Set(ctx, docRef, &auditUser{Name: "jiro", Age: 31})
+				},
+			},
+			want: want{
+				users:     "u1:taro:20,u2:jiro:31",
+				histories: "u2:set:user1:30,u2:delete:user1:30",
+			},
+		},
+		{
+			name: "トランザクション",
+			args: args{
+				run: func(ctx context.Context, cFirestore *firestore.Client) (string, error) {
+					err := cloudfirestore.RunTransaction(ctx, cFirestore, func(ctx context.Context) error {
+						docRef := cFirestore.Doc("audit_users/u1")
+						user := &auditUser{}
+						if _, err := cloudfirestore.Get(ctx, docRef, user); err != nil {
+							return err
+						}
+						// 書き込むドキュメントは全て先に取得する
+						deleteDocRef := cFirestore.Doc("audit_users/u2")
+						if _, err := cloudfirestore.Get(ctx, deleteDocRef, &auditUser{}); err != nil {
+							return err
+						}
+						if err := cloudfirestore.Update(ctx, docRef, map[string]any{"age": user.Age + 5}); err != nil {
+							return err
+						}
+						return cloudfirestore.Delete(ctx, deleteDocRef)
+					})
+					return "", err
+				},
+			},
+			want: want{
+				users:     "u1:taro:25",
+				histories: "u1:update:user1:20,u2:delete:user1:30",
+			},
+		},
+		{
+			name: "トランザクションで取得していないドキュメントは書き込めない",
+			args: args{
+				run: func(ctx context.Context, cFirestore *firestore.Client) (string, error) {
+					err := cloudfirestore.RunTransaction(ctx, cFirestore, func(ctx context.Context) error {
+						return cloudfirestore.Update(ctx, cFirestore.Doc("audit_users/u1"), map[string]any{"age": 21})
+					})
+					return fmt.Sprint(err != nil), nil
+				},
+			},
+			want: want{
+				result:    "true",
+				users:     "u1:taro:20,u2:jiro:30",
+				histories: "",
+			},
+		},
+		{
+			name: "トランザクションで書き込んだドキュメントは再び書き込めない",
+			args: args{
+				run: func(ctx context.Context, cFirestore *firestore.Client) (string, error) {
+					err := cloudfirestore.RunTransaction(ctx, cFirestore, func(ctx context.Context) error {
+						docRef := cFirestore.Doc("audit_users/u1")
+						if _, err := cloudfirestore.Get(ctx, docRef, &auditUser{}); err != nil {
+							return err
+						}
+						if err := cloudfirestore.Update(ctx, docRef, map[string]any{"age": 21}); err != nil {
+							return err
+						}
+						// 取得したドキュメントは変更前のため、2回目の書き込みの履歴にできない
+						return cloudfirestore.Delete(ctx, docRef)
+					})
+					return fmt.Sprint(err != nil), nil
+				},
+			},
+			want: want{
+				result:    "true",
+				users:     "u1:taro:20,u2:jiro:30",
+				histories: "",
+			},
+		},
+		{
+			name: "deleted_at を持たないドキュメントも作成時に null を保存して取得する",
+			args: args{
+				run: func(ctx context.Context, cFirestore *firestore.Client) (string, error) {
+					colRef := cFirestore.Collection("audit_posts")
+					if err := cloudfirestore.Create(ctx, colRef, &auditPost{Title: "a"}); err != nil {
+						return "", err
+					}
+					if err := cloudfirestore.Create(ctx, colRef, map[string]any{"title": "b"}); err != nil {
+						return "", err
+					}
+					if err := cloudfirestore.Set(ctx, colRef.Doc("p3"), &auditPost{Title: "c"}); err != nil {
+						return "", err
+					}
+					bwCtx := cloudfirestore.RunBulkWriter(ctx, cFirestore)
+					if err := cloudfirestore.Create(bwCtx, colRef, &auditPost{Title: "d"}); err != nil {
+						return "", err
+					}
+					if _, err := cloudfirestore.CommitBulkWriter(bwCtx); err != nil {
+						return "", err
+					}
+					posts := []*auditPost{}
+					if err := cloudfirestore.ListByQuery(ctx, colRef.OrderBy("title", firestore.Asc), &posts); err != nil {
+						return "", err
+					}
+					titles := []string{}
+					for _, post := range posts {
+						titles = append(titles, post.Title)
+					}
+					return strings.Join(titles, ","), nil
+				},
+			},
+			want: want{
+				result:    "a,b,c,d",
+				users:     "u1:taro:20,u2:jiro:30",
+				histories: "",
+			},
+		},
+		{
+			name: "論理削除したドキュメントも取得する",
+			args: args{
+				run: func(ctx context.Context, cFirestore *firestore.Client) (string, error) {
+					if err := cloudfirestore.Delete(ctx, cFirestore.Doc("audit_users/u1")); err != nil {
+						return "", err
+					}
+					users := []*auditUser{}
+					query := cFirestore.Collection("audit_users").Query
+					if err := cloudfirestore.ListByQuery(cloudfirestore.WithDeleted(ctx), query, &users); err != nil {
+						return "", err
+					}
+					return fmt.Sprint(len(users)), nil
+				},
+			},
+			want: want{
+				result:    "2",
+				users:     "u2:jiro:30",
+				histories: "u1:delete:user1:20",
+			},
+		},
+		{
+			name: "BulkWriter",
+			args: args{
+				run: func(ctx context.Context, cFirestore *firestore.Client) (string, error) {
+					bwCtx := cloudfirestore.RunBulkWriter(ctx, cFirestore)
+					if err := cloudfirestore.Delete(bwCtx, cFirestore.Doc("audit_users/u1")); err != nil {
+						return "", err
+					}
+					if err := cloudfirestore.Set(bwCtx, cFirestore.Doc("audit_users/u3"), &auditUser{Name: "saburo", Age: 15}); err != nil {
+						return "", err
+					}
+					_, err := cloudfirestore.CommitBulkWriter(bwCtx)
+					return "", err
+				},
+			},
+			want: want{
+				users:     "u2:jiro:30,u3:saburo:15",
+				histories: "u1:delete:user1:20,u3:set:user1:<nil>",
+			},
+		},
+		{
+			name: "ページングは論理削除したドキュメントを除いて続きを返す",
+			args: args{
+				run: func(ctx context.Context, cFirestore *firestore.Client) (string, error) {
+					if err := cloudfirestore.Delete(ctx, cFirestore.Doc("audit_users/u1")); err != nil {
+						return "", err
+					}
+					query := cFirestore.Collection("audit_users").OrderBy("age", firestore.Asc)
+					pages := []string{}
+					var cursor *firestore.DocumentSnapshot
+					for {
+						users := []*auditUser{}
+						var err error
+						cursor, err = cloudfirestore.ListByQueryCursor(ctx, query, 1, cursor, &users)
+						if err != nil {
+							return "", err
+						}
+						pages = append(pages, fmt.Sprint(len(users)))
+						if cursor == nil {
+							break
+						}
+					}
+					return strings.Join(pages, ","), nil
+				},
+			},
+			want: want{
+				result:    "1,0",
+				users:     "u2:jiro:30",
+				histories: "u1:delete:user1:20",
+			},
+		},
+		{
+			name: "有効にしていないコレクションは削除する",
+			args: args{
+				run: func(ctx context.Context, cFirestore *firestore.Client) (string, error) {
+					docRef := cFirestore.Doc("users/u1")
+					if err := cloudfirestore.Set(ctx, docRef, &auditUser{Name: "taro"}); err != nil {
+						return "", err
+					}
+					if err := cloudfirestore.Delete(ctx, docRef); err != nil {
+						return "", err
+					}
+					dsnps, err := cFirestore.CollectionGroup(cloudfirestore.HistoryCollection).Documents(ctx).GetAll()
+					if err != nil {
+						return "", err
+					}
+					exists, err := cloudfirestore.Get(cloudfirestore.WithDeleted(ctx), docRef, &auditUser{})
+					return fmt.Sprintf("%v:%d", exists, len(dsnps)), err
+				},
+			},
+			want: want{
+				result:    "false:0",
+				users:     "u1:taro:20,u2:jiro:30",
+				histories: "",
+			},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := t.Context()
+			cFirestore, err := cloudfirestore.NewMemoryClient(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, user := range []*auditUser{
+				{ID: "u1", Name: "taro", Age: 20},
+				{ID: "u2", Name: "jiro", Age: 30},
+			} {
+				docRef := cFirestore.Collection("audit_users").Doc(user.ID)
+				if _, err := docRef.Set(ctx, user); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			result, err := tc.args.run(newAuthContext(ctx, "user1"), cFirestore)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result != tc.want.result {
+				t.Errorf("got: %v, want: %v", result, tc.want.result)
+			}
+
+			users := []*auditUser{}
+			err = cloudfirestore.ListByQuery(ctx, cFirestore.Collection("audit_users").Query, &users)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotUsers := []string{}
+			for _, user := range users {
+				gotUsers = append(gotUsers, fmt.Sprintf("%s:%s:%d", user.ID, user.Name, user.Age))
+			}
+			if got := strings.Join(gotUsers, ","); got != tc.want.users {
+				t.Errorf("got: %v, want: %v", got, tc.want.users)
+			}
+
+			gotHistories := []string{}
+			for _, id := range []string{"u1", "u2", "u3"} {
+				histories, err := cloudfirestore.ListHistories(ctx, cFirestore.Collection("audit_users").Doc(id))
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, history := range histories {
+					gotHistories = append(gotHistories, fmt.Sprintf("%s:%s:%s:%v", id, history.Operation, history.Actor, history.Data["age"]))
+				}
+			}
+			if got := strings.Join(gotHistories, ","); got != tc.want.histories {
+				t.Errorf("got: %v, want: %v", got, tc.want.histories)
+			}
+		})
+	}
+}
@@ -11,6 +11,9 @@ type contextKey string
 const (
 	ctxTxKey contextKey = "firestore:tx"
 	ctxBwKey contextKey = "firestore:bw"
+
+	ctxTxSnapshotsKey contextKey = "firestore:tx_snapshots"
+	ctxWithDeletedKey contextKey = "firestore:with_deleted"
 )
 
 func getContextTransaction(ctx context.Context) *firestore.Transaction {
@@ -0,0 +1,284 @@
+package cloudfirestore
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"github.com/rabee-inc/go-pkg/log"
+	"google.golang.org/api/iterator"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrInvalidCursorToken ... カーソルのトークンが不正、または別のクエリのトークン
+var ErrInvalidCursorToken = errors.New("cloudfirestore: invalid cursor token")
+
+// CursorSigner ... カーソルのトークンに署名する
+type CursorSigner struct {
+	secret []byte
+}
+
+// NewCursorSigner ... 署名の鍵を指定して CursorSigner を作成する
+func NewCursorSigner(secret []byte) *CursorSigner {
+	return &CursorSigner{secret}
+}
+
+// CursorPage ... トークンによるページングの結果
+type CursorPage struct {
+	// 次のページのトークン。次のページがない場合は空
+	NextToken string `json:"next_token"`
+	// 前のページのトークン。前のページがない場合は空
+	PrevToken string `json:"prev_token"`
+	HasNext   bool   `json:"has_next"`
+	HasPrev   bool   `json:"has_prev"`
+}
+
+// トークンの内容
+type cursorToken struct {
+	// 前のページのトークンかどうか
+	Backward bool `json:"b,omitempty"`
+	// クエリのハッシュ。別のクエリのトークンを検出する
+	Query string `json:"q"`
+	// 並び順のフィールドとドキュメントの参照の値 (firestorepb.Cursor)
+	Cursor []byte `json:"c"`
+}
+
+func (s *CursorSigner) sign(b []byte) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(b)
+	return mac.Sum(nil)
+}
+
+// トークンを作成する。 URL で使える文字のみを含む
+func (s *CursorSigner) encode(token *cursorToken) (string, error) {
+	payload, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(s.sign(payload)), nil
+}
+
+func (s *CursorSigner) decode(str string) (*cursorToken, error) {
+	enc := base64.RawURLEncoding
+	payloadStr, sigStr, ok := strings.Cut(str, ".")
+	if !ok {
+		return nil, ErrInvalidCursorToken
+	}
+	payload, err := enc.DecodeString(payloadStr)
+	if err != nil {
+		return nil, ErrInvalidCursorToken
+	}
+	sig, err := enc.DecodeString(sigStr)
+	if err != nil || !hmac.Equal(sig, s.sign(payload)) {
+		return nil, ErrInvalidCursorToken
+	}
+	token := &cursorToken{}
+	if err := json.Unmarshal(payload, token); err != nil {
+		return nil, ErrInvalidCursorToken
+	}
+	return token, nil
+}
+
+// ListByQueryCursorToken ... クエリで複数取得する（トークンによるページング）(tx対応)
+// token が空の場合は最初のページを取得し、結果の NextToken, PrevToken で前後のページを取得する。
+// 並び順の最後にドキュメントIDを追加し、1件多く取得して次のページの有無を判定する。
+// query には StartAt などのカーソルと Offset を指定できない
+func ListByQueryCursorToken(ctx context.Context, signer *CursorSigner, query firestore.Query, limit int, token string, dsts any) (*CursorPage, error) {
+	query = excludeSoftDeleted(ctx, query)
+	req, err := serializeQuery(query)
+	if err != nil {
+		log.Warning(ctx, err)
+		return nil, err
+	}
+	sq := req.GetStructuredQuery()
+	if sq.StartAt != nil || sq.EndAt != nil || sq.Offset != 0 {
+		err = log.Warninge(ctx, "cloudfirestore: cursors and offset cannot be used with cursor tokens")
+		return nil, err
+	}
+	sq.OrderBy = queryOrders(sq)
+	queryHash, err := hashQuery(sq)
+	if err != nil {
+		log.Warning(ctx, err)
+		return nil, err
+	}
+
+	var current *cursorToken
+	if token != "" {
+		current, err = signer.decode(token)
+		if err == nil && current.Query != queryHash {
+			err = ErrInvalidCursorToken
+		}
+		if err != nil {
+			log.Warning(ctx, err)
+			return nil, err
+		}
+	}
+	backward := current != nil && current.Backward
+
+	// 前のページは並び順を逆にして取得する
+	pageSq := proto.Clone(sq).(*firestorepb.StructuredQuery)
+	if backward {
+		for _, order := range pageSq.OrderBy {
+			order.Direction = reverseDirection(order.Direction)
+		}
+	}
+	if current != nil {
+		cursor := &firestorepb.Cursor{}
+		if err := proto.Unmarshal(current.Cursor, cursor); err != nil || len(cursor.Values) != len(pageSq.OrderBy) {
+			log.Warning(ctx, ErrInvalidCursorToken)
+			return nil, ErrInvalidCursorToken
+		}
+		// StartAfter
+		pageSq.StartAt = &firestorepb.Cursor{Values: cursor.Values, Before: false}
+	}
+	pageSq.Limit = nil
+	pageQuery, err := deserializeQuery(query, req, pageSq)
+	if err != nil {
+		log.Warning(ctx, err)
+		return nil, err
+	}
+	pageQuery = pageQuery.Limit(limit + 1)
+
+	var it *firestore.DocumentIterator
+	if tx := getContextTransaction(ctx); tx != nil {
+		it = tx.Documents(pageQuery)
+	} else {
+		it = pageQuery.Documents(ctx)
+	}
+	defer it.Stop()
+	dsnps := []*firestore.DocumentSnapshot{}
+	for {
+		dsnp, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			log.Warning(ctx, err)
+			return nil, err
+		}
+		dsnps = append(dsnps, dsnp)
+	}
+	hasMore := len(dsnps) > limit
+	if hasMore {
+		dsnps = dsnps[:limit]
+	}
+	if backward {
+		slices.Reverse(dsnps)
+	}
+
+	rv := reflect.Indirect(reflect.ValueOf(dsts))
+	rrt := rv.Type().Elem().Elem()
+	for _, dsnp := range dsnps {
+		v := reflect.New(rrt).Interface()
+		if err := dsnp.DataTo(v); err != nil {
+			log.Error(ctx, err)
+			return nil, err
+		}
+		rrv := reflect.ValueOf(v)
+		SetDocByDsts(rrv, rrt, dsnp.Ref)
+		SetEmptyBySlices(rrv, rrt)
+		SetEmptyByMaps(rrv, rrt)
+		rv.Set(reflect.Append(rv, rrv))
+	}
+
+	page := &CursorPage{
+		HasNext: hasMore,
+		HasPrev: current != nil,
+	}
+	if backward {
+		page.HasNext, page.HasPrev = true, hasMore
+	}
+	if len(dsnps) == 0 {
+		return page, nil
+	}
+	// 先頭と末尾のドキュメントの並び順の値からトークンを作成する
+	base, err := deserializeQuery(query, req, sq)
+	if err != nil {
+		log.Warning(ctx, err)
+		return nil, err
+	}
+	if page.HasNext {
+		page.NextToken, err = signer.newToken(base, queryHash, dsnps[len(dsnps)-1], false)
+		if err != nil {
+			log.Warning(ctx, err)
+			return nil, err
+		}
+	}
+	if page.HasPrev {
+		page.PrevToken, err = signer.newToken(base, queryHash, dsnps[0], true)
+		if err != nil {
+			log.Warning(ctx, err)
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+func (s *CursorSigner) newToken(base firestore.Query, queryHash string, dsnp *firestore.DocumentSnapshot, backward bool) (string, error) {
+	// ドキュメントの並び順の値はクエリのカーソルとして取得する
+	req, err := serializeQuery(base.StartAfter(dsnp))
+	if err != nil {
+		return "", err
+	}
+	cursor, err := proto.Marshal(req.GetStructuredQuery().GetStartAt())
+	if err != nil {
+		return "", err
+	}
+	return s.encode(&cursorToken{
+		Backward: backward,
+		Query:    queryHash,
+		Cursor:   cursor,
+	})
+}
+
+func serializeQuery(query firestore.Query) (*firestorepb.RunQueryRequest, error) {
+	b, err := query.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	req := &firestorepb.RunQueryRequest{}
+	if err := proto.Unmarshal(b, req); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// StructuredQuery を差し替えたクエリを作成する
+func deserializeQuery(query firestore.Query, req *firestorepb.RunQueryRequest, sq *firestorepb.StructuredQuery) (firestore.Query, error) {
+	req = proto.Clone(req).(*firestorepb.RunQueryRequest)
+	req.QueryType = &firestorepb.RunQueryRequest_StructuredQuery{StructuredQuery: sq}
+	b, err := proto.Marshal(req)
+	if err != nil {
+		return firestore.Query{}, err
+	}
+	return query.Deserialize(b)
+}
+
+// カーソルと件数を除いたクエリのハッシュ
+func hashQuery(sq *firestorepb.StructuredQuery) (string, error) {
+	sq = proto.Clone(sq).(*firestorepb.StructuredQuery)
+	sq.StartAt, sq.EndAt, sq.Offset, sq.Limit = nil, nil, 0, nil
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(sq)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+func reverseDirection(direction firestorepb.StructuredQuery_Direction) firestorepb.StructuredQuery_Direction {
+	if direction == firestorepb.StructuredQuery_DESCENDING {
+		return firestorepb.StructuredQuery_ASCENDING
+	}
+	return firestorepb.StructuredQuery_DESCENDING
+}
@@ -0,0 +1,173 @@
+package cloudfirestore_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"github.com/rabee-inc/go-pkg/cloudfirestore"
+)
+
+func Test_ListByQueryCursorToken(t *testing.T) {
+	type page struct {
+		ids     string
+		hasNext bool
+		hasPrev bool
+	}
+	type args struct {
+		// 各ページで次 ("next") または前 ("prev") のトークンを使う
+		moves []string
+		query func(cFirestore *firestore.Client) firestore.Query
+	}
+	type want struct {
+		pages []page
+		err   error
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	users := func(cFirestore *firestore.Client) firestore.Query {
+		return cFirestore.Collection("users").OrderBy("age", firestore.Desc)
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "次のページ",
+			args: args{
+				moves: []string{"next", "next"},
+				query: users,
+			},
+			want: want{
+				pages: []page{
+					{ids: "u5,u4", hasNext: true},
+					{ids: "u3,u2", hasNext: true, hasPrev: true},
+					{ids: "u1", hasPrev: true},
+				},
+			},
+		},
+		{
+			name: "前のページ",
+			args: args{
+				moves: []string{"next", "next", "prev", "prev"},
+				query: users,
+			},
+			want: want{
+				pages: []page{
+					{ids: "u5,u4", hasNext: true},
+					{ids: "u3,u2", hasNext: true, hasPrev: true},
+					{ids: "u1", hasPrev: true},
+					{ids: "u3,u2", hasNext: true, hasPrev: true},
+					{ids: "u5,u4", hasNext: true},
+				},
+			},
+		},
+		{
+			name: "並び順の指定がない場合はドキュメントIDの順",
+			args: args{
+				moves: []string{"next"},
+				query: func(cFirestore *firestore.Client) firestore.Query {
+					return cFirestore.Collection("users").Query
+				},
+			},
+			want: want{
+				pages: []page{
+					{ids: "u1,u2", hasNext: true},
+					{ids: "u3,u4", hasNext: true, hasPrev: true},
+				},
+			},
+		},
+		{
+			name: "改ざんされたトークンはエラー",
+			args: args{
+				moves: []string{"tampered"},
+				query: users,
+			},
+			want: want{
+				pages: []page{
+					{ids: "u5,u4", hasNext: true},
+				},
+				err: cloudfirestore.ErrInvalidCursorToken,
+			},
+		},
+		{
+			name: "別のクエリのトークンはエラー",
+			args: args{
+				moves: []string{"other"},
+				query: users,
+			},
+			want: want{
+				pages: []page{
+					{ids: "u5,u4", hasNext: true},
+				},
+				err: cloudfirestore.ErrInvalidCursorToken,
+			},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := t.Context()
+			cFirestore, err := cloudfirestore.NewMemoryClient(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for id, age := range map[string]int{"u1": 10, "u2": 20, "u3": 20, "u4": 30, "u5": 40} {
+				if err := cloudfirestore.Set(ctx, cFirestore.Collection("users").Doc(id), &testUser{Age: age}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			signer := cloudfirestore.NewCursorSigner([]byte("secret"))
+			query := tc.args.query(cFirestore)
+
+			list := func(ctx context.Context, query firestore.Query, token string) (page, *cloudfirestore.CursorPage, error) {
+				dsts := []*testUser{}
+				result, err := cloudfirestore.ListByQueryCursorToken(ctx, signer, query, 2, token, &dsts)
+				if err != nil {
+					return page{}, nil, err
+				}
+				ids := []string{}
+				for _, dst := range dsts {
+					ids = append(ids, dst.ID)
+				}
+				return page{strings.Join(ids, ","), result.HasNext, result.HasPrev}, result, nil
+			}
+
+			got := []page{}
+			p, result, err := list(ctx, query, "")
+			got = append(got, p)
+			for _, move := range tc.args.moves {
+				if err != nil {
+					break
+				}
+				switch move {
+				case "next":
+					p, result, err = list(ctx, query, result.NextToken)
+				case "prev":
+					p, result, err = list(ctx, query, result.PrevToken)
+				case "tampered":
+					_, _, err = list(ctx, query, result.NextToken[:len(result.NextToken)-2]+"AA")
+				case "other":
+					_, _, err = list(ctx, query.Where("age", ">", 0), result.NextToken)
+				}
+				if err == nil {
+					got = append(got, p)
+				}
+			}
+
+			if !errors.Is(err, tc.want.err) {
+				t.Errorf("got: %v, want: %v", err, tc.want.err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tc.want.pages) {
+				t.Errorf("got: %v, want: %v", got, tc.want.pages)
+			}
+		})
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/rabee-inc/go-pkg/log"
 	"github.com/rabee-inc/go-pkg/sliceutil"
 	"github.com/rabee-inc/go-pkg/stringutil"
+	"github.com/rabee-inc/go-pkg/timeutil"
 	"google.golang.org/api/iterator"
 )
 
@@ -92,6 +93,7 @@ func GenerateDocumentRef(cFirestore *firestore.Client, docRefs []*DocRef) *fires
 func RunTransaction(ctx context.Context, cFirestore *firestore.Client, fn func(ctx context.Context) error, opts ...firestore.TransactionOption) error {
 	return cFirestore.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
 		ctx = setContextTransaction(ctx, tx)
+		ctx = setContextTxSnapshots(ctx)
 		return fn(ctx)
 	}, opts...)
 }
@@ -122,6 +124,7 @@ func Get(ctx context.Context, docRef *firestore.DocumentRef, dst any) (bool, err
 	var err error
 	if tx := getContextTransaction(ctx); tx != nil {
 		dsnp, err = tx.Get(docRef)
+		addContextTxSnapshots(ctx, dsnp)
 	} else {
 		dsnp, err = docRef.Get(ctx)
 	}
@@ -132,6 +135,9 @@ func Get(ctx context.Context, docRef *firestore.DocumentRef, dst any) (bool, err
 		log.Warning(ctx, err)
 		return false, err
 	}
+	if isSoftDeleted(ctx, dsnp) {
+		return false, nil
+	}
 	err = dsnp.DataTo(dst)
 	if err != nil {
 		log.Error(ctx, err)
@@ -158,6 +164,7 @@ func GetMulti(ctx context.Context, cFirestore *firestore.Client, docRefs []*fire
 	var err error
 	if tx := getContextTransaction(ctx); tx != nil {
 		dsnps, err = tx.GetAll(docRefs)
+		addContextTxSnapshots(ctx, dsnps...)
 	} else {
 		dsnps, err = cFirestore.GetAll(ctx, docRefs)
 	}
@@ -168,7 +175,7 @@ func GetMulti(ctx context.Context, cFirestore *firestore.Client, docRefs []*fire
 	rv := reflect.Indirect(reflect.ValueOf(dsts))
 	rrt := rv.Type().Elem().Elem()
 	for _, dsnp := range dsnps {
-		if !dsnp.Exists() {
+		if !dsnp.Exists() || isSoftDeleted(ctx, dsnp) {
 			continue
 		}
 		v := reflect.New(rrt).Interface()
@@ -188,7 +195,7 @@ func GetMulti(ctx context.Context, cFirestore *firestore.Client, docRefs []*fire
 
 // クエリで単体取得する(tx対応)
 func GetByQuery(ctx context.Context, query firestore.Query, dst any) (bool, error) {
-	query = query.Limit(1)
+	query = excludeSoftDeleted(ctx, query).Limit(1)
 	var it *firestore.DocumentIterator
 	if tx := getContextTransaction(ctx); tx != nil {
 		it = tx.Documents(query)
@@ -217,6 +224,7 @@ func GetByQuery(ctx context.Context, query firestore.Query, dst any) (bool, erro
 
 // クエリで複数取得する(tx対応)
 func ListByQuery(ctx context.Context, query firestore.Query, dsts any) error {
+	query = excludeSoftDeleted(ctx, query)
 	var it *firestore.DocumentIterator
 	if tx := getContextTransaction(ctx); tx != nil {
 		it = tx.Documents(query)
@@ -256,7 +264,7 @@ func ListByQueryCursor(ctx context.Context, query firestore.Query, limit int, cu
 		query = query.StartAfter(cursor)
 	}
 	var it *firestore.DocumentIterator
-	query = query.Limit(limit)
+	query = excludeSoftDeleted(ctx, query).Limit(limit)
 	if tx := getContextTransaction(ctx); tx != nil {
 		it = tx.Documents(query)
 	} else {
@@ -302,11 +310,12 @@ func Create(ctx context.Context, colRef *firestore.CollectionRef, src any) error
 	}
 	SetEmptyBySlice(src)
 	SetEmptyByMap(src)
+	data := withDeletedAtField(colRef, src)
 	var docRef *firestore.DocumentRef
 	if tx := getContextTransaction(ctx); tx != nil {
 		id := stringutil.UniqueID()
 		docRef = colRef.Doc(id)
-		err := tx.Create(docRef, src)
+		err := tx.Create(docRef, data)
 		if err != nil {
 			log.Warning(ctx, err)
 			return err
@@ -314,14 +323,14 @@ func Create(ctx context.Context, colRef *firestore.CollectionRef, src any) error
 	} else if bw := getContextBulkWriter(ctx); bw != nil {
 		id := stringutil.UniqueID()
 		docRef = colRef.Doc(id)
-		_, err := bw.Create(docRef, src)
+		_, err := bw.Create(docRef, data)
 		if err != nil {
 			log.Warning(ctx, err)
 			return err
 		}
 	} else {
 		var err error
-		docRef, _, err = colRef.Add(ctx, src)
+		docRef, _, err = colRef.Add(ctx, data)
 		if err != nil {
 			log.Warning(ctx, err)
 			return err
@@ -337,11 +346,22 @@ func Update(ctx context.Context, docRef *firestore.DocumentRef, kv map[string]an
 	if !ValidateDocumentRef(docRef) {
 		return errors.New("Invalid Document Path: " + docRef.Path)
 	}
+	history, err := newHistory(ctx, docRef, HistoryOperationUpdate)
+	if err != nil {
+		return err
+	}
 	srcs := []firestore.Update{}
 	for k, v := range kv {
 		src := firestore.Update{Path: k, Value: v}
 		srcs = append(srcs, src)
 	}
+	if err := update(ctx, docRef, srcs); err != nil {
+		return err
+	}
+	return history.write(ctx)
+}
+
+func update(ctx context.Context, docRef *firestore.DocumentRef, srcs []firestore.Update) error {
 	if tx := getContextTransaction(ctx); tx != nil {
 		err := tx.Update(docRef, srcs)
 		if err != nil {
@@ -370,37 +390,58 @@ func Set(ctx context.Context, docRef *firestore.DocumentRef, src any) error {
 	if !ValidateDocumentRef(docRef) {
 		return errors.New("Invalid Document Path: " + docRef.Path)
 	}
+	history, err := newHistory(ctx, docRef, HistoryOperationSet)
+	if err != nil {
+		return err
+	}
 	SetEmptyBySlice(src)
 	SetEmptyByMap(src)
+	data := withDeletedAtField(docRef.Parent, src)
 	if tx := getContextTransaction(ctx); tx != nil {
-		err := tx.Set(docRef, src)
+		err := tx.Set(docRef, data)
 		if err != nil {
 			log.Warning(ctx, err)
 			return err
 		}
 	} else if bw := getContextBulkWriter(ctx); bw != nil {
-		_, err := bw.Set(docRef, src)
+		_, err := bw.Set(docRef, data)
 		if err != nil {
 			log.Warning(ctx, err)
 			return err
 		}
 	} else {
-		_, err := docRef.Set(ctx, src)
+		_, err := docRef.Set(ctx, data)
 		if err != nil {
 			log.Warning(ctx, err)
 			return err
 		}
 	}
 	SetDocByDst(src, docRef)
-	return nil
+	return history.write(ctx)
 }
 
 // 削除する(tx, bw対応)
+// 論理削除を有効にしたコレクションは削除せずに FieldDeletedAt を設定する
 func Delete(ctx context.Context, docRef *firestore.DocumentRef) error {
 	// 不正なIDがないかチェック
 	if !ValidateDocumentRef(docRef) {
 		return errors.New("Invalid Document Path: " + docRef.Path)
 	}
+	history, err := newHistory(ctx, docRef, HistoryOperationDelete)
+	if err != nil {
+		return err
+	}
+	if history != nil {
+		// 存在しない、または論理削除済みの場合は何もしない
+		if !history.dsnp.Exists() || history.softDeleted() {
+			return nil
+		}
+		srcs := []firestore.Update{{Path: FieldDeletedAt, Value: timeutil.Now()}}
+		if err := update(ctx, docRef, srcs); err != nil {
+			return err
+		}
+		return history.write(ctx)
+	}
 	if tx := getContextTransaction(ctx); tx != nil {
 		err := tx.Delete(docRef)
 		if err != nil {
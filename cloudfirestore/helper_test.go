@@ -1,7 +1,6 @@
 package cloudfirestore_test
 
 import (
-	"context"
 	"testing"
 
 	"cloud.google.com/go/firestore"
@@ -104,7 +103,10 @@ func Test_ValidateDocumentRef(t *testing.T) {
 	}
 
 	// 準備
-	cFirestore, _ := firestore.NewClient(context.Background(), "test_project_id")
+	cFirestore, err := cloudfirestore.NewMemoryClient(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	// テストケースの定義
 	tcs := []testCase{
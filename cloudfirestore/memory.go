@@ -0,0 +1,393 @@
+package cloudfirestore
+
+import (
+	"context"
+	"crypto/rand"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"google.golang.org/api/option"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const memoryProjectID = "memory"
+
+// NewMemoryClient ... メモリ上にデータを保持する Firestore のクライアントを作成する (テスト用)
+// Firestore の gRPC API をプロセス内で実装しているため、ネットワークに接続せずにヘルパーを実行できる。
+// ctx が終了するとサーバーを停止する
+func NewMemoryClient(ctx context.Context) (*firestore.Client, error) {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	firestorepb.RegisterFirestoreServer(srv, newMemoryServer())
+	go srv.Serve(lis)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///memory",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		srv.Stop()
+		return nil, err
+	}
+	client, err := firestore.NewClient(ctx, memoryProjectID, option.WithGRPCConn(conn))
+	if err != nil {
+		conn.Close()
+		srv.Stop()
+		return nil, err
+	}
+	go func() {
+		<-ctx.Done()
+		client.Close()
+		srv.Stop()
+	}()
+	return client, nil
+}
+
+// firestorepb.FirestoreServer のメモリ上の実装。
+// 取得、クエリ (条件、並び順、件数、カーソル、コレクショングループ) 、集計、書き込み、トランザクション、 BulkWriter に対応する。
+// トランザクションは全ての書き込みをまとめて反映し、競合は検出しない
+type memoryServer struct {
+	firestorepb.UnimplementedFirestoreServer
+	mutex    sync.Mutex
+	docs     map[string]*firestorepb.Document
+	lastTime time.Time
+}
+
+func newMemoryServer() *memoryServer {
+	return &memoryServer{
+		docs: map[string]*firestorepb.Document{},
+	}
+}
+
+// 書き込みごとに異なる時刻を返す
+func (s *memoryServer) now() *timestamppb.Timestamp {
+	now := time.Now()
+	if !now.After(s.lastTime) {
+		now = s.lastTime.Add(time.Microsecond)
+	}
+	s.lastTime = now
+	return timestamppb.New(now)
+}
+
+func (s *memoryServer) BatchGetDocuments(req *firestorepb.BatchGetDocumentsRequest, stream firestorepb.Firestore_BatchGetDocumentsServer) error {
+	s.mutex.Lock()
+	readTime := s.now()
+	resps := []*firestorepb.BatchGetDocumentsResponse{}
+	for _, name := range req.Documents {
+		resp := &firestorepb.BatchGetDocumentsResponse{ReadTime: readTime}
+		if doc, ok := s.docs[name]; ok {
+			resp.Result = &firestorepb.BatchGetDocumentsResponse_Found{Found: proto.Clone(doc).(*firestorepb.Document)}
+		} else {
+			resp.Result = &firestorepb.BatchGetDocumentsResponse_Missing{Missing: name}
+		}
+		resps = append(resps, resp)
+	}
+	s.mutex.Unlock()
+
+	if len(resps) > 0 && req.GetNewTransaction() != nil {
+		resps[0].Transaction = newMemoryTransactionID()
+	}
+	for _, resp := range resps {
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *memoryServer) RunQuery(req *firestorepb.RunQueryRequest, stream firestorepb.Firestore_RunQueryServer) error {
+	s.mutex.Lock()
+	readTime := s.now()
+	docs, err := runMemoryQuery(s.docs, req.Parent, req.GetStructuredQuery())
+	resps := []*firestorepb.RunQueryResponse{}
+	for _, doc := range docs {
+		resps = append(resps, &firestorepb.RunQueryResponse{
+			Document: proto.Clone(doc).(*firestorepb.Document),
+			ReadTime: readTime,
+		})
+	}
+	s.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if len(resps) == 0 {
+		resps = append(resps, &firestorepb.RunQueryResponse{ReadTime: readTime})
+	}
+	if req.GetNewTransaction() != nil {
+		resps[0].Transaction = newMemoryTransactionID()
+	}
+	for _, resp := range resps {
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *memoryServer) RunAggregationQuery(req *firestorepb.RunAggregationQueryRequest, stream firestorepb.Firestore_RunAggregationQueryServer) error {
+	query := req.GetStructuredAggregationQuery()
+	s.mutex.Lock()
+	readTime := s.now()
+	docs, err := runMemoryQuery(s.docs, req.Parent, query.GetStructuredQuery())
+	s.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	fields, err := runMemoryAggregation(docs, query.Aggregations)
+	if err != nil {
+		return err
+	}
+	resp := &firestorepb.RunAggregationQueryResponse{
+		Result:   &firestorepb.AggregationResult{AggregateFields: fields},
+		ReadTime: readTime,
+	}
+	if req.GetNewTransaction() != nil {
+		resp.Transaction = newMemoryTransactionID()
+	}
+	return stream.Send(resp)
+}
+
+func (s *memoryServer) BeginTransaction(ctx context.Context, req *firestorepb.BeginTransactionRequest) (*firestorepb.BeginTransactionResponse, error) {
+	return &firestorepb.BeginTransactionResponse{Transaction: newMemoryTransactionID()}, nil
+}
+
+func (s *memoryServer) Rollback(ctx context.Context, req *firestorepb.RollbackRequest) (*emptypb.Empty, error) {
+	return &emptypb.Empty{}, nil
+}
+
+// 全ての書き込みが成功した場合のみ反映する
+func (s *memoryServer) Commit(ctx context.Context, req *firestorepb.CommitRequest) (*firestorepb.CommitResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := s.now()
+	staged := map[string]*firestorepb.Document{}
+	get := func(name string) (*firestorepb.Document, bool) {
+		if doc, ok := staged[name]; ok {
+			return doc, doc != nil
+		}
+		doc, ok := s.docs[name]
+		return doc, ok
+	}
+	results := []*firestorepb.WriteResult{}
+	for _, write := range req.Writes {
+		name, doc, result, err := applyMemoryWrite(write, get, now)
+		if err != nil {
+			return nil, err
+		}
+		staged[name] = doc
+		results = append(results, result)
+	}
+	s.apply(staged)
+	return &firestorepb.CommitResponse{
+		WriteResults: results,
+		CommitTime:   now,
+	}, nil
+}
+
+// 書き込みごとに反映する
+func (s *memoryServer) BatchWrite(ctx context.Context, req *firestorepb.BatchWriteRequest) (*firestorepb.BatchWriteResponse, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := s.now()
+	get := func(name string) (*firestorepb.Document, bool) {
+		doc, ok := s.docs[name]
+		return doc, ok
+	}
+	resp := &firestorepb.BatchWriteResponse{}
+	for _, write := range req.Writes {
+		name, doc, result, err := applyMemoryWrite(write, get, now)
+		if err != nil {
+			st, _ := grpcstatus.FromError(err)
+			resp.WriteResults = append(resp.WriteResults, &firestorepb.WriteResult{})
+			resp.Status = append(resp.Status, st.Proto())
+			continue
+		}
+		s.apply(map[string]*firestorepb.Document{name: doc})
+		resp.WriteResults = append(resp.WriteResults, result)
+		resp.Status = append(resp.Status, &status.Status{Code: int32(codes.OK)})
+	}
+	return resp, nil
+}
+
+// nil のドキュメントは削除する
+func (s *memoryServer) apply(docs map[string]*firestorepb.Document) {
+	for name, doc := range docs {
+		if doc == nil {
+			delete(s.docs, name)
+		} else {
+			s.docs[name] = doc
+		}
+	}
+}
+
+// 書き込みを適用したドキュメントを作成する。削除の場合はドキュメントが nil
+func applyMemoryWrite(
+	write *firestorepb.Write,
+	get func(name string) (*firestorepb.Document, bool),
+	now *timestamppb.Timestamp,
+) (string, *firestorepb.Document, *firestorepb.WriteResult, error) {
+	var name string
+	switch op := write.Operation.(type) {
+	case *firestorepb.Write_Update:
+		name = op.Update.Name
+	case *firestorepb.Write_Delete:
+		name = op.Delete
+	case *firestorepb.Write_Transform:
+		name = op.Transform.Document
+	default:
+		return "", nil, nil, grpcstatus.Error(codes.InvalidArgument, "unknown write operation")
+	}
+	if !strings.Contains(name, "/documents/") {
+		return "", nil, nil, grpcstatus.Errorf(codes.InvalidArgument, "invalid document name: %s", name)
+	}
+	current, exists := get(name)
+
+	// 前提条件
+	if precondition := write.CurrentDocument; precondition != nil {
+		switch cond := precondition.ConditionType.(type) {
+		case *firestorepb.Precondition_Exists:
+			if cond.Exists && !exists {
+				return "", nil, nil, grpcstatus.Errorf(codes.NotFound, "no entity to update: %s", name)
+			}
+			if !cond.Exists && exists {
+				return "", nil, nil, grpcstatus.Errorf(codes.AlreadyExists, "entity already exists: %s", name)
+			}
+		case *firestorepb.Precondition_UpdateTime:
+			if !exists || !proto.Equal(current.UpdateTime, cond.UpdateTime) {
+				return "", nil, nil, grpcstatus.Errorf(codes.FailedPrecondition, "update time does not match: %s", name)
+			}
+		}
+	}
+
+	result := &firestorepb.WriteResult{UpdateTime: now}
+	if _, ok := write.Operation.(*firestorepb.Write_Delete); ok {
+		return name, nil, result, nil
+	}
+
+	doc := &firestorepb.Document{
+		Name:       name,
+		Fields:     map[string]*firestorepb.Value{},
+		CreateTime: now,
+		UpdateTime: now,
+	}
+	if exists {
+		doc.CreateTime = current.CreateTime
+	}
+	transforms := write.UpdateTransforms
+	switch op := write.Operation.(type) {
+	case *firestorepb.Write_Update:
+		if write.UpdateMask == nil {
+			// 上書き
+			doc.Fields = proto.Clone(op.Update).(*firestorepb.Document).Fields
+			if doc.Fields == nil {
+				doc.Fields = map[string]*firestorepb.Value{}
+			}
+		} else {
+			// マスクに含まれるフィールドのみ更新し、値がないフィールドは削除する
+			if exists {
+				doc.Fields = proto.Clone(current).(*firestorepb.Document).Fields
+			}
+			for _, fieldPath := range write.UpdateMask.FieldPaths {
+				path := parseFieldPath(fieldPath)
+				if value, ok := getField(op.Update.Fields, path); ok {
+					setField(doc.Fields, path, proto.Clone(value).(*firestorepb.Value))
+				} else {
+					deleteField(doc.Fields, path)
+				}
+			}
+		}
+	case *firestorepb.Write_Transform:
+		if exists {
+			doc.Fields = proto.Clone(current).(*firestorepb.Document).Fields
+		}
+		transforms = append(op.Transform.FieldTransforms, transforms...)
+	}
+
+	for _, transform := range transforms {
+		value, err := applyMemoryTransform(doc, transform, now)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		result.TransformResults = append(result.TransformResults, value)
+	}
+	return name, doc, result, nil
+}
+
+// フィールドの変換を適用して変換後の値を返す
+func applyMemoryTransform(doc *firestorepb.Document, transform *firestorepb.DocumentTransform_FieldTransform, now *timestamppb.Timestamp) (*firestorepb.Value, error) {
+	path := parseFieldPath(transform.FieldPath)
+	current, exists := getField(doc.Fields, path)
+	var value *firestorepb.Value
+	switch t := transform.TransformType.(type) {
+	case *firestorepb.DocumentTransform_FieldTransform_SetToServerValue:
+		value = &firestorepb.Value{ValueType: &firestorepb.Value_TimestampValue{TimestampValue: now}}
+	case *firestorepb.DocumentTransform_FieldTransform_Increment:
+		value = t.Increment
+		if exists && isNumberValue(current) {
+			value = addNumberValues(current, t.Increment)
+		}
+	case *firestorepb.DocumentTransform_FieldTransform_Maximum:
+		value = t.Maximum
+		if exists && isNumberValue(current) && compareValues(current, t.Maximum) >= 0 {
+			value = current
+		}
+	case *firestorepb.DocumentTransform_FieldTransform_Minimum:
+		value = t.Minimum
+		if exists && isNumberValue(current) && compareValues(current, t.Minimum) <= 0 {
+			value = current
+		}
+	case *firestorepb.DocumentTransform_FieldTransform_AppendMissingElements:
+		values := append([]*firestorepb.Value{}, current.GetArrayValue().GetValues()...)
+		for _, v := range t.AppendMissingElements.GetValues() {
+			if !containsValue(values, v) {
+				values = append(values, v)
+			}
+		}
+		value = &firestorepb.Value{ValueType: &firestorepb.Value_ArrayValue{ArrayValue: &firestorepb.ArrayValue{Values: values}}}
+	case *firestorepb.DocumentTransform_FieldTransform_RemoveAllFromArray:
+		values := []*firestorepb.Value{}
+		for _, v := range current.GetArrayValue().GetValues() {
+			if !containsValue(t.RemoveAllFromArray.GetValues(), v) {
+				values = append(values, v)
+			}
+		}
+		value = &firestorepb.Value{ValueType: &firestorepb.Value_ArrayValue{ArrayValue: &firestorepb.ArrayValue{Values: values}}}
+	default:
+		return nil, grpcstatus.Error(codes.InvalidArgument, "unknown field transform")
+	}
+	value = proto.Clone(value).(*firestorepb.Value)
+	setField(doc.Fields, path, value)
+	return value, nil
+}
+
+// 両方が整数の場合は整数、それ以外は浮動小数点数で加算する
+func addNumberValues(a, b *firestorepb.Value) *firestorepb.Value {
+	ai, aok := a.GetValueType().(*firestorepb.Value_IntegerValue)
+	bi, bok := b.GetValueType().(*firestorepb.Value_IntegerValue)
+	if aok && bok {
+		return &firestorepb.Value{ValueType: &firestorepb.Value_IntegerValue{IntegerValue: ai.IntegerValue + bi.IntegerValue}}
+	}
+	return &firestorepb.Value{ValueType: &firestorepb.Value_DoubleValue{DoubleValue: numberValue(a) + numberValue(b)}}
+}
+
+func newMemoryTransactionID() []byte {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return b
+}
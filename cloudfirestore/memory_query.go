@@ -0,0 +1,330 @@
+package cloudfirestore
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// クエリを実行する。 docs はドキュメント名をキーとした全てのドキュメント
+func runMemoryQuery(docs map[string]*firestorepb.Document, parent string, query *firestorepb.StructuredQuery) ([]*firestorepb.Document, error) {
+	if len(query.From) != 1 {
+		return nil, status.Error(codes.InvalidArgument, "query must have exactly one collection selector")
+	}
+	from := query.From[0]
+
+	// コレクション (コレクショングループの場合は parent 以下の同じIDのコレクション) のドキュメントで条件に一致するもの
+	orders := queryOrders(query)
+	dsts := []*firestorepb.Document{}
+	for name, doc := range docs {
+		if !inCollection(name, parent, from) {
+			continue
+		}
+		ok, err := matchFilter(doc, query.Where)
+		if err != nil {
+			return nil, err
+		}
+		if ok && hasOrderFields(doc, orders) && matchCursors(doc, orders, query.StartAt, query.EndAt) {
+			dsts = append(dsts, doc)
+		}
+	}
+	sort.SliceStable(dsts, func(i, j int) bool {
+		return compareDocuments(dsts[i], dsts[j], orders) < 0
+	})
+
+	offset := int(query.Offset)
+	if offset > len(dsts) {
+		offset = len(dsts)
+	}
+	dsts = dsts[offset:]
+	if query.Limit != nil && int(query.Limit.Value) < len(dsts) {
+		dsts = dsts[:query.Limit.Value]
+	}
+	if query.Select != nil {
+		for i, doc := range dsts {
+			dsts[i] = selectFields(doc, query.Select.Fields)
+		}
+	}
+	return dsts, nil
+}
+
+func matchFilter(doc *firestorepb.Document, filter *firestorepb.StructuredQuery_Filter) (bool, error) {
+	if filter == nil {
+		return true, nil
+	}
+	switch f := filter.FilterType.(type) {
+	case *firestorepb.StructuredQuery_Filter_CompositeFilter:
+		isOr := f.CompositeFilter.Op == firestorepb.StructuredQuery_CompositeFilter_OR
+		for _, sub := range f.CompositeFilter.Filters {
+			ok, err := matchFilter(doc, sub)
+			if err != nil {
+				return false, err
+			}
+			if ok == isOr {
+				return isOr, nil
+			}
+		}
+		return !isOr, nil
+	case *firestorepb.StructuredQuery_Filter_FieldFilter:
+		return matchFieldFilter(doc, f.FieldFilter)
+	case *firestorepb.StructuredQuery_Filter_UnaryFilter:
+		return matchUnaryFilter(doc, f.UnaryFilter)
+	}
+	return false, status.Error(codes.InvalidArgument, "unknown filter type")
+}
+
+func matchFieldFilter(doc *firestorepb.Document, filter *firestorepb.StructuredQuery_FieldFilter) (bool, error) {
+	value, ok := getDocumentField(doc, filter.Field.FieldPath)
+	if !ok {
+		return false, nil
+	}
+	target := filter.Value
+	switch filter.Op {
+	case firestorepb.StructuredQuery_FieldFilter_EQUAL:
+		return compareValues(value, target) == 0, nil
+	case firestorepb.StructuredQuery_FieldFilter_NOT_EQUAL:
+		return valueTypeOrder(value) != 0 && compareValues(value, target) != 0, nil
+	case firestorepb.StructuredQuery_FieldFilter_LESS_THAN,
+		firestorepb.StructuredQuery_FieldFilter_LESS_THAN_OR_EQUAL,
+		firestorepb.StructuredQuery_FieldFilter_GREATER_THAN,
+		firestorepb.StructuredQuery_FieldFilter_GREATER_THAN_OR_EQUAL:
+		// 範囲の条件は同じ型の値のみ一致する
+		if valueTypeOrder(value) != valueTypeOrder(target) || isNaNValue(value) || isNaNValue(target) {
+			return false, nil
+		}
+		c := compareValues(value, target)
+		switch filter.Op {
+		case firestorepb.StructuredQuery_FieldFilter_LESS_THAN:
+			return c < 0, nil
+		case firestorepb.StructuredQuery_FieldFilter_LESS_THAN_OR_EQUAL:
+			return c <= 0, nil
+		case firestorepb.StructuredQuery_FieldFilter_GREATER_THAN:
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	case firestorepb.StructuredQuery_FieldFilter_ARRAY_CONTAINS:
+		return containsValue(value.GetArrayValue().GetValues(), target), nil
+	case firestorepb.StructuredQuery_FieldFilter_IN:
+		return containsValue(target.GetArrayValue().GetValues(), value), nil
+	case firestorepb.StructuredQuery_FieldFilter_ARRAY_CONTAINS_ANY:
+		for _, v := range target.GetArrayValue().GetValues() {
+			if containsValue(value.GetArrayValue().GetValues(), v) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case firestorepb.StructuredQuery_FieldFilter_NOT_IN:
+		return valueTypeOrder(value) != 0 && !containsValue(target.GetArrayValue().GetValues(), value), nil
+	}
+	return false, status.Errorf(codes.InvalidArgument, "unknown field filter operator: %s", filter.Op)
+}
+
+func matchUnaryFilter(doc *firestorepb.Document, filter *firestorepb.StructuredQuery_UnaryFilter) (bool, error) {
+	value, ok := getDocumentField(doc, filter.GetField().GetFieldPath())
+	if !ok {
+		return false, nil
+	}
+	switch filter.Op {
+	case firestorepb.StructuredQuery_UnaryFilter_IS_NAN:
+		return isNaNValue(value), nil
+	case firestorepb.StructuredQuery_UnaryFilter_IS_NULL:
+		return valueTypeOrder(value) == 0, nil
+	case firestorepb.StructuredQuery_UnaryFilter_IS_NOT_NAN:
+		return isNumberValue(value) && !isNaNValue(value), nil
+	case firestorepb.StructuredQuery_UnaryFilter_IS_NOT_NULL:
+		return valueTypeOrder(value) != 0, nil
+	}
+	return false, status.Errorf(codes.InvalidArgument, "unknown unary filter operator: %s", filter.Op)
+}
+
+func inCollection(name string, parent string, from *firestorepb.StructuredQuery_CollectionSelector) bool {
+	if !strings.HasPrefix(name, parent+"/") {
+		return false
+	}
+	segments := strings.Split(name[len(parent)+1:], "/")
+	if len(segments) < 2 || segments[len(segments)-2] != from.CollectionId {
+		return false
+	}
+	return from.AllDescendants || len(segments) == 2
+}
+
+// 並び替えの対象のフィールドがないドキュメントは結果に含めない
+func hasOrderFields(doc *firestorepb.Document, orders []*firestorepb.StructuredQuery_Order) bool {
+	for _, order := range orders {
+		if _, ok := getDocumentField(doc, order.Field.FieldPath); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// クエリの並び順。指定がなく範囲の条件がある場合はそのフィールドで並び替え、最後にドキュメント名で並び替える
+func queryOrders(query *firestorepb.StructuredQuery) []*firestorepb.StructuredQuery_Order {
+	orders := slices.Clone(query.OrderBy)
+	for _, order := range orders {
+		if order.Field.FieldPath == fieldPathDocumentName {
+			return orders
+		}
+	}
+	if len(orders) == 0 {
+		if field := inequalityField(query.Where); field != "" {
+			orders = append(orders, &firestorepb.StructuredQuery_Order{
+				Field:     &firestorepb.StructuredQuery_FieldReference{FieldPath: field},
+				Direction: firestorepb.StructuredQuery_ASCENDING,
+			})
+		}
+	}
+	direction := firestorepb.StructuredQuery_ASCENDING
+	if len(orders) > 0 {
+		direction = orders[len(orders)-1].Direction
+	}
+	return append(orders, &firestorepb.StructuredQuery_Order{
+		Field:     &firestorepb.StructuredQuery_FieldReference{FieldPath: fieldPathDocumentName},
+		Direction: direction,
+	})
+}
+
+func inequalityField(filter *firestorepb.StructuredQuery_Filter) string {
+	switch f := filter.GetFilterType().(type) {
+	case *firestorepb.StructuredQuery_Filter_CompositeFilter:
+		for _, sub := range f.CompositeFilter.Filters {
+			if field := inequalityField(sub); field != "" {
+				return field
+			}
+		}
+	case *firestorepb.StructuredQuery_Filter_FieldFilter:
+		switch f.FieldFilter.Op {
+		case firestorepb.StructuredQuery_FieldFilter_EQUAL,
+			firestorepb.StructuredQuery_FieldFilter_ARRAY_CONTAINS,
+			firestorepb.StructuredQuery_FieldFilter_ARRAY_CONTAINS_ANY,
+			firestorepb.StructuredQuery_FieldFilter_IN:
+			return ""
+		}
+		return f.FieldFilter.Field.FieldPath
+	}
+	return ""
+}
+
+// カーソルの範囲に含まれるか判定する
+func matchCursors(doc *firestorepb.Document, orders []*firestorepb.StructuredQuery_Order, startAt, endAt *firestorepb.Cursor) bool {
+	if startAt != nil {
+		c := compareCursor(doc, orders, startAt)
+		// before: StartAt, !before: StartAfter
+		if c < 0 || (c == 0 && !startAt.Before) {
+			return false
+		}
+	}
+	if endAt != nil {
+		c := compareCursor(doc, orders, endAt)
+		// before: EndBefore, !before: EndAt
+		if c > 0 || (c == 0 && endAt.Before) {
+			return false
+		}
+	}
+	return true
+}
+
+// カーソルの値の数だけ並び順のフィールドを比較する
+func compareCursor(doc *firestorepb.Document, orders []*firestorepb.StructuredQuery_Order, cursor *firestorepb.Cursor) int {
+	for i, value := range cursor.Values {
+		if i >= len(orders) {
+			break
+		}
+		v, _ := getDocumentField(doc, orders[i].Field.FieldPath)
+		c := compareValues(v, value)
+		if orders[i].Direction == firestorepb.StructuredQuery_DESCENDING {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func compareDocuments(a, b *firestorepb.Document, orders []*firestorepb.StructuredQuery_Order) int {
+	for _, order := range orders {
+		av, _ := getDocumentField(a, order.Field.FieldPath)
+		bv, _ := getDocumentField(b, order.Field.FieldPath)
+		c := compareValues(av, bv)
+		if order.Direction == firestorepb.StructuredQuery_DESCENDING {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// 指定したフィールドのみを含むドキュメントを作成する
+func selectFields(doc *firestorepb.Document, fields []*firestorepb.StructuredQuery_FieldReference) *firestorepb.Document {
+	dst := &firestorepb.Document{
+		Name:       doc.Name,
+		Fields:     map[string]*firestorepb.Value{},
+		CreateTime: doc.CreateTime,
+		UpdateTime: doc.UpdateTime,
+	}
+	for _, field := range fields {
+		if field.FieldPath == fieldPathDocumentName {
+			continue
+		}
+		path := parseFieldPath(field.FieldPath)
+		if value, ok := getField(doc.Fields, path); ok {
+			setField(dst.Fields, path, value)
+		}
+	}
+	return dst
+}
+
+// 集計クエリを実行する。合計は全て整数の場合は整数、それ以外は浮動小数点数になる
+func runMemoryAggregation(docs []*firestorepb.Document, aggregations []*firestorepb.StructuredAggregationQuery_Aggregation) (map[string]*firestorepb.Value, error) {
+	dst := map[string]*firestorepb.Value{}
+	for _, aggregation := range aggregations {
+		switch op := aggregation.Operator.(type) {
+		case *firestorepb.StructuredAggregationQuery_Aggregation_Count_:
+			count := int64(len(docs))
+			if upTo := op.Count.GetUpTo(); upTo != nil && upTo.Value < count {
+				count = upTo.Value
+			}
+			dst[aggregation.Alias] = &firestorepb.Value{ValueType: &firestorepb.Value_IntegerValue{IntegerValue: count}}
+		case *firestorepb.StructuredAggregationQuery_Aggregation_Sum_:
+			values := numberValues(docs, op.Sum.Field.FieldPath)
+			sum := &firestorepb.Value{ValueType: &firestorepb.Value_IntegerValue{IntegerValue: 0}}
+			for _, value := range values {
+				sum = addNumberValues(sum, value)
+			}
+			dst[aggregation.Alias] = sum
+		case *firestorepb.StructuredAggregationQuery_Aggregation_Avg_:
+			values := numberValues(docs, op.Avg.Field.FieldPath)
+			if len(values) == 0 {
+				dst[aggregation.Alias] = &firestorepb.Value{ValueType: &firestorepb.Value_NullValue{}}
+				continue
+			}
+			var sum float64
+			for _, value := range values {
+				sum += numberValue(value)
+			}
+			dst[aggregation.Alias] = &firestorepb.Value{ValueType: &firestorepb.Value_DoubleValue{DoubleValue: sum / float64(len(values))}}
+		default:
+			return nil, status.Error(codes.InvalidArgument, "unknown aggregation")
+		}
+	}
+	return dst, nil
+}
+
+// 数値のフィールドの値のみを取得する
+func numberValues(docs []*firestorepb.Document, fieldPath string) []*firestorepb.Value {
+	values := []*firestorepb.Value{}
+	for _, doc := range docs {
+		if value, ok := getDocumentField(doc, fieldPath); ok && isNumberValue(value) {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,251 @@
+package cloudfirestore_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"github.com/rabee-inc/go-pkg/cloudfirestore"
+)
+
+func Test_MemoryClient(t *testing.T) {
+	type args struct {
+		run func(ctx context.Context, cFirestore *firestore.Client) (string, error)
+	}
+	type want struct {
+		result string
+		err    bool
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	// users を ID の一覧にする
+	ids := func(users []*testUser) string {
+		dsts := []string{}
+		for _, user := range users {
+			dsts = append(dsts, user.ID)
+		}
+		return strings.Join(dsts, ",")
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "クエリで単体取得",
+			args: args{
+				run: func(ctx context.Context, cFirestore *firestore.Client) (string, error) {
+					user := &testUser{}
+					exists, err := cloudfirestore.GetByQuery(ctx, cFirestore.Collection("users").Where("name", "==", "jiro"), user)
+					return fmt.Sprintf("%v:%s", exists, user.ID), err
+				},
+			},
+			want: want{
+				result: "true:u2",
+			},
+		},
+		{
+			name: "条件と並び順",
+			args: args{
+				run: func(ctx context.Context, cFirestore *firestore.Client) (string, error) {
+					results := []string{}
+					for _, query := range []firestore.Query{
+						cFirestore.Collection("users").Where("tags", "array-contains", "a").OrderBy("age", firestore.Desc),
+						cFirestore.Collection("users").Where("age", "in", []int{20, 50}),
+						cFirestore.Collection("users").Where("age", "!=", 30).Limit(2),
+						cFirestore.Collection("users").WhereEntity(firestore.OrFilter{
+							Filters: []firestore.EntityFilter{
+								firestore.PropertyFilter{Path: "age", Operator: "<", Value: 25},
+								firestore.PropertyFilter{Path: "tags", Operator: "array-contains-any", Value: []string{"c"}},
+							},
+						}),
+						cFirestore.Collection("users").OrderBy("name", firestore.Asc).Offset(1).Limit(2),
+					} {
+						users := []*testUser{}
+						if err := cloudfirestore.ListByQuery(ctx, query, &users); err != nil {
+							return "", err
+						}
+						results = append(results, ids(users))
+					}
+					return strings.Join(results, " "), nil
+				},
+			},
+			want: want{
+				result: "u3,u1 u1,u4 u1,u3 u1,u5 u2,u3",
+			},
+		},
+		{
+			name: "カーソルでページング",
+			args: args{
+				run: func(ctx context.Context, cFirestore *firestore.Client) (string, error) {
+					query := cFirestore.Collection("users").OrderBy("age", firestore.Desc)
+					pages := []string{}
+					var cursor *firestore.DocumentSnapshot
+					for {
+						users := []*testUser{}
+						next, err := cloudfirestore.ListByQueryCursor(ctx, query, 2, cursor, &users)
+						if err != nil {
+							return "", err
+						}
+						pages = append(pages, ids(users))
+						if next == nil {
+							break
+						}
+						cursor = next
+					}
+					users := []*testUser{}
+					err := cloudfirestore.ListByQuery(ctx, cFirestore.Collection("users").OrderBy("age", firestore.Asc).StartAt(30).EndBefore(50), &users)
+					return strings.Join(pages, " ") + " " + ids(users), err
+				},
+			},
+			want: want{
+				result: "u5,u4 u3,u2 u1 u2,u3",
+			},
+		},
+		{
+			name: "集計",
+			args: args{
+				run: func(ctx context.Context, cFirestore *firestore.Client) (string, error) {
+					query := cFirestore.Collection("users").Where("age", ">", 20)
+					count, err := cloudfirestore.Count(ctx, query)
+					if err != nil {
+						return "", err
+					}
+					sum, err := cloudfirestore.Sum(ctx, query, "age")
+					if err != nil {
+						return "", err
+					}
+					avg, err := cloudfirestore.Avg(ctx, query, "age")
+					return fmt.Sprintf("%d %d %v", count, sum, avg), err
+				},
+			},
+			want: want{
+				result: "4 180 45",
+			},
+		},
+		{
+			name: "コレクショングループ",
+			args: args{
+				run: func(ctx context.Context, cFirestore *firestore.Client) (string, error) {
+					users := []*testUser{}
+					err := cloudfirestore.ListByQuery(ctx, cFirestore.CollectionGroup("posts").OrderBy("age", firestore.Asc), &users)
+					return ids(users), err
+				},
+			},
+			want: want{
+				result: "p2,p1",
+			},
+		},
+		{
+			name: "トランザクション",
+			args: args{
+				run: func(ctx context.Context, cFirestore *firestore.Client) (string, error) {
+					err := cloudfirestore.RunTransaction(ctx, cFirestore, func(ctx context.Context) error {
+						users := []*testUser{}
+						docRefs := []*firestore.DocumentRef{cFirestore.Doc("users/u1"), cFirestore.Doc("users/u2")}
+						if err := cloudfirestore.GetMulti(ctx, cFirestore, docRefs, &users); err != nil {
+							return err
+						}
+						for _, user := range users {
+							if err := cloudfirestore.Update(ctx, user.Ref, map[string]any{"age": user.Age * 2}); err != nil {
+								return err
+							}
+						}
+						return nil
+					})
+					if err != nil {
+						return "", err
+					}
+					users := []*testUser{}
+					err = cloudfirestore.ListByQuery(ctx, cFirestore.Collection("users").Where("age", ">=", 60), &users)
+					return ids(users), err
+				},
+			},
+			want: want{
+				result: "u2,u5",
+			},
+		},
+		{
+			name: "既に存在するドキュメントの作成はエラー",
+			args: args{
+				run: func(ctx context.Context, cFirestore *firestore.Client) (string, error) {
+					_, err := cFirestore.Doc("users/u1").Create(ctx, &testUser{Name: "taro"})
+					return "", err
+				},
+			},
+			want: want{
+				err: true,
+			},
+		},
+		{
+			name: "BatchGetter",
+			args: args{
+				run: func(ctx context.Context, cFirestore *firestore.Client) (string, error) {
+					bg := cloudfirestore.NewBatchGetter(cFirestore)
+					cbg := cloudfirestore.NewConvertibleBatchGetter(
+						bg,
+						func(ids ...string) *firestore.DocumentRef {
+							return cFirestore.Collection("users").Doc(ids[0])
+						},
+						func(name *string) string { return *name },
+						func(user *testUser) *string { return &user.Name },
+					)
+					results := []string{}
+					cbg.Add("u1").After(func(name *string) {
+						results = append(results, *name)
+						// 取得後に追加した場合は再度コミットされる
+						cbg.Add("u2").After(func(name *string) {
+							results = append(results, *name)
+						})
+					})
+					cbg.Add("none").OnEmpty(func() {
+						results = append(results, "empty")
+					})
+					if err := cbg.Commit(ctx); err != nil {
+						return "", err
+					}
+					return strings.Join(results, ",") + " " + fmt.Sprint(len(cbg.GetMap())), nil
+				},
+			},
+			want: want{
+				result: "taro,empty,jiro 3",
+			},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := t.Context()
+			cFirestore, err := cloudfirestore.NewMemoryClient(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for path, user := range map[string]*testUser{
+				"users/u1":          {Name: "taro", Age: 20, Tags: []string{"a"}},
+				"users/u2":          {Name: "jiro", Age: 30, Tags: []string{"b"}},
+				"users/u3":          {Name: "saburo", Age: 40, Tags: []string{"a", "b"}},
+				"users/u4":          {Name: "shiro", Age: 50},
+				"users/u5":          {Name: "goro", Age: 60, Tags: []string{"c"}},
+				"users/u1/posts/p1": {Name: "post1", Age: 2},
+				"users/u2/posts/p2": {Name: "post2", Age: 1},
+			} {
+				if err := cloudfirestore.Set(ctx, cFirestore.Doc(path), user); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := tc.args.run(ctx, cFirestore)
+			if (err != nil) != tc.want.err {
+				t.Errorf("got: %v, want: %v", err, tc.want.err)
+			}
+			if got != tc.want.result {
+				t.Errorf("got: %v, want: %v", got, tc.want.result)
+			}
+		})
+	}
+}
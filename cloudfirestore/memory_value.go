@@ -0,0 +1,215 @@
+package cloudfirestore
+
+import (
+	"bytes"
+	"cmp"
+	"math"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+)
+
+// ドキュメント名を表すフィールドパス
+const fieldPathDocumentName = "__name__"
+
+// フィールドパスを分割する。バッククォートで囲まれたセグメントに対応する
+func parseFieldPath(path string) []string {
+	segments := []string{}
+	var current strings.Builder
+	quoted := false
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch {
+		case quoted && c == '\\' && i+1 < len(path):
+			i++
+			current.WriteByte(path[i])
+		case c == '`':
+			quoted = !quoted
+		case !quoted && c == '.':
+			segments = append(segments, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(c)
+		}
+	}
+	return append(segments, current.String())
+}
+
+// ドキュメントのフィールドの値を取得する
+func getDocumentField(doc *firestorepb.Document, path string) (*firestorepb.Value, bool) {
+	if path == fieldPathDocumentName {
+		return &firestorepb.Value{ValueType: &firestorepb.Value_ReferenceValue{ReferenceValue: doc.Name}}, true
+	}
+	return getField(doc.Fields, parseFieldPath(path))
+}
+
+func getField(fields map[string]*firestorepb.Value, path []string) (*firestorepb.Value, bool) {
+	value, ok := fields[path[0]]
+	if !ok {
+		return nil, false
+	}
+	if len(path) == 1 {
+		return value, true
+	}
+	m := value.GetMapValue()
+	if m == nil {
+		return nil, false
+	}
+	return getField(m.Fields, path[1:])
+}
+
+// 途中のマップがない場合は作成してフィールドに値を設定する
+func setField(fields map[string]*firestorepb.Value, path []string, value *firestorepb.Value) {
+	if len(path) == 1 {
+		fields[path[0]] = value
+		return
+	}
+	m := fields[path[0]].GetMapValue()
+	if m == nil {
+		m = &firestorepb.MapValue{Fields: map[string]*firestorepb.Value{}}
+		fields[path[0]] = &firestorepb.Value{ValueType: &firestorepb.Value_MapValue{MapValue: m}}
+	}
+	if m.Fields == nil {
+		m.Fields = map[string]*firestorepb.Value{}
+	}
+	setField(m.Fields, path[1:], value)
+}
+
+func deleteField(fields map[string]*firestorepb.Value, path []string) {
+	if len(path) == 1 {
+		delete(fields, path[0])
+		return
+	}
+	if m := fields[path[0]].GetMapValue(); m != nil {
+		deleteField(m.Fields, path[1:])
+	}
+}
+
+// 型の並び順
+// https://firebase.google.com/docs/firestore/manage-data/data-types#value_type_ordering
+func valueTypeOrder(v *firestorepb.Value) int {
+	switch v.GetValueType().(type) {
+	case *firestorepb.Value_NullValue:
+		return 0
+	case *firestorepb.Value_BooleanValue:
+		return 1
+	case *firestorepb.Value_IntegerValue, *firestorepb.Value_DoubleValue:
+		return 2
+	case *firestorepb.Value_TimestampValue:
+		return 3
+	case *firestorepb.Value_StringValue:
+		return 4
+	case *firestorepb.Value_BytesValue:
+		return 5
+	case *firestorepb.Value_ReferenceValue:
+		return 6
+	case *firestorepb.Value_GeoPointValue:
+		return 7
+	case *firestorepb.Value_ArrayValue:
+		return 8
+	default:
+		return 9
+	}
+}
+
+func isNumberValue(v *firestorepb.Value) bool {
+	return valueTypeOrder(v) == 2
+}
+
+func isNaNValue(v *firestorepb.Value) bool {
+	d, ok := v.GetValueType().(*firestorepb.Value_DoubleValue)
+	return ok && math.IsNaN(d.DoubleValue)
+}
+
+func numberValue(v *firestorepb.Value) float64 {
+	if i, ok := v.GetValueType().(*firestorepb.Value_IntegerValue); ok {
+		return float64(i.IntegerValue)
+	}
+	return v.GetDoubleValue()
+}
+
+// Firestore の並び順で値を比較する
+func compareValues(a, b *firestorepb.Value) int {
+	if c := cmp.Compare(valueTypeOrder(a), valueTypeOrder(b)); c != 0 {
+		return c
+	}
+	switch av := a.GetValueType().(type) {
+	case *firestorepb.Value_BooleanValue:
+		return cmp.Compare(boolOrder(av.BooleanValue), boolOrder(b.GetBooleanValue()))
+	case *firestorepb.Value_IntegerValue:
+		if bi, ok := b.GetValueType().(*firestorepb.Value_IntegerValue); ok {
+			return cmp.Compare(av.IntegerValue, bi.IntegerValue)
+		}
+		return cmp.Compare(numberValue(a), numberValue(b))
+	case *firestorepb.Value_DoubleValue:
+		return cmp.Compare(numberValue(a), numberValue(b))
+	case *firestorepb.Value_TimestampValue:
+		at, bt := av.TimestampValue.AsTime(), b.GetTimestampValue().AsTime()
+		return at.Compare(bt)
+	case *firestorepb.Value_StringValue:
+		return cmp.Compare(av.StringValue, b.GetStringValue())
+	case *firestorepb.Value_BytesValue:
+		return bytes.Compare(av.BytesValue, b.GetBytesValue())
+	case *firestorepb.Value_ReferenceValue:
+		as, bs := strings.Split(av.ReferenceValue, "/"), strings.Split(b.GetReferenceValue(), "/")
+		for i := 0; i < len(as) && i < len(bs); i++ {
+			if c := cmp.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(len(as), len(bs))
+	case *firestorepb.Value_GeoPointValue:
+		bg := b.GetGeoPointValue()
+		if c := cmp.Compare(av.GeoPointValue.Latitude, bg.Latitude); c != 0 {
+			return c
+		}
+		return cmp.Compare(av.GeoPointValue.Longitude, bg.Longitude)
+	case *firestorepb.Value_ArrayValue:
+		as, bs := av.ArrayValue.GetValues(), b.GetArrayValue().GetValues()
+		for i := 0; i < len(as) && i < len(bs); i++ {
+			if c := compareValues(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(len(as), len(bs))
+	case *firestorepb.Value_MapValue:
+		am, bm := av.MapValue.GetFields(), b.GetMapValue().GetFields()
+		aKeys, bKeys := sortedKeys(am), sortedKeys(bm)
+		for i := 0; i < len(aKeys) && i < len(bKeys); i++ {
+			if c := cmp.Compare(aKeys[i], bKeys[i]); c != 0 {
+				return c
+			}
+			if c := compareValues(am[aKeys[i]], bm[bKeys[i]]); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(len(aKeys), len(bKeys))
+	}
+	return 0
+}
+
+func boolOrder(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func sortedKeys(m map[string]*firestorepb.Value) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func containsValue(values []*firestorepb.Value, v *firestorepb.Value) bool {
+	for _, value := range values {
+		if compareValues(value, v) == 0 {
+			return true
+		}
+	}
+	return false
+}
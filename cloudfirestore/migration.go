@@ -0,0 +1,53 @@
+package cloudfirestore
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+)
+
+// MigrationFunc ... ドキュメントを変換する。更新するフィールドを返し、 nil の場合は更新しない。
+// フィールドを削除する場合は firestore.Delete を値に指定する
+type MigrationFunc func(ctx context.Context, dsnp *firestore.DocumentSnapshot) (map[string]any, error)
+
+// Migration ... コレクションのドキュメントを変換するマイグレーション
+type Migration struct {
+	// 番号の順に適用する。1以上で重複しないこと
+	Version int
+	Name    string
+	// コレクションのパス (例: "users", "users/{id}/posts") 。 CollectionGroup の場合はコレクションID
+	Collection      string
+	CollectionGroup bool
+	Up              MigrationFunc
+	// ロールバックの変換。 nil の場合はロールバックできない
+	Down MigrationFunc
+}
+
+// MigrationResult ... マイグレーションの実行結果
+type MigrationResult struct {
+	Version int
+	Name    string
+	// 読み込んだドキュメントの数 (中断したところから再開した場合は前回までの数を含む)
+	Processed int
+	// 更新した (DryRun の場合は更新する) ドキュメントの数
+	Updated int
+	DryRun  bool
+}
+
+// MigratorOption ... マイグレーションの設定
+type MigratorOption struct {
+	// 適用状態を保存するコレクション。空の場合は "_migrations"
+	MetadataCollection string
+	// 1回の BulkWriter で書き込むドキュメントの数。0の場合は500
+	ChunkSize int
+	// true の場合は書き込まずに更新するドキュメントの数のみ数える
+	DryRun bool
+}
+
+// Migrator ... マイグレーションを実行する
+type Migrator interface {
+	// Migrate ... 未適用のマイグレーションを番号の順に適用する。中断したマイグレーションは続きから再開する
+	Migrate(ctx context.Context) ([]*MigrationResult, error)
+	// Rollback ... version より大きい番号の適用済みのマイグレーションを番号の逆順にロールバックする
+	Rollback(ctx context.Context, version int) ([]*MigrationResult, error)
+}
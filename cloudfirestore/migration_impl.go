@@ -0,0 +1,288 @@
+package cloudfirestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/rabee-inc/go-pkg/log"
+	"github.com/rabee-inc/go-pkg/timeutil"
+)
+
+const (
+	defaultMigrationCollection = "_migrations"
+	defaultMigrationChunkSize  = 500
+)
+
+// マイグレーションの適用状態
+const (
+	migrationStatusApplying    = "applying"
+	migrationStatusApplied     = "applied"
+	migrationStatusRollingBack = "rolling_back"
+	migrationStatusRolledBack  = "rolled_back"
+)
+
+// マイグレーションの適用状態のドキュメント
+type migrationState struct {
+	Version int    `firestore:"version"`
+	Name    string `firestore:"name"`
+	Status  string `firestore:"status"`
+	// 最後に書き込んだドキュメントのパス。中断した場合はこの次から再開する
+	Cursor    string    `firestore:"cursor"`
+	Processed int       `firestore:"processed"`
+	Updated   int       `firestore:"updated"`
+	UpdatedAt time.Time `firestore:"updated_at"`
+}
+
+type migrator struct {
+	cFirestore *firestore.Client
+	migrations []*Migration
+	option     *MigratorOption
+}
+
+// NewMigrator ... Migrator を作成する
+func NewMigrator(cFirestore *firestore.Client, migrations []*Migration, opt *MigratorOption) (Migrator, error) {
+	if opt == nil {
+		opt = &MigratorOption{}
+	}
+	dst := &MigratorOption{
+		MetadataCollection: opt.MetadataCollection,
+		ChunkSize:          opt.ChunkSize,
+		DryRun:             opt.DryRun,
+	}
+	if dst.MetadataCollection == "" {
+		dst.MetadataCollection = defaultMigrationCollection
+	}
+	if dst.ChunkSize <= 0 {
+		dst.ChunkSize = defaultMigrationChunkSize
+	}
+
+	migrations = append([]*Migration{}, migrations...)
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	for i, migration := range migrations {
+		if migration.Version <= 0 || migration.Up == nil {
+			return nil, fmt.Errorf("cloudfirestore: invalid migration: %d %s", migration.Version, migration.Name)
+		}
+		if i > 0 && migrations[i-1].Version == migration.Version {
+			return nil, fmt.Errorf("cloudfirestore: duplicate migration version: %d", migration.Version)
+		}
+	}
+	return &migrator{
+		cFirestore: cFirestore,
+		migrations: migrations,
+		option:     dst,
+	}, nil
+}
+
+func (m *migrator) Migrate(ctx context.Context) ([]*MigrationResult, error) {
+	results := []*MigrationResult{}
+	for _, migration := range m.migrations {
+		state, err := m.getState(ctx, migration)
+		if err != nil {
+			return results, err
+		}
+		if state.Status == migrationStatusApplied {
+			continue
+		}
+		if state.Status != migrationStatusApplying {
+			state = &migrationState{Version: migration.Version, Name: migration.Name}
+		}
+		result, err := m.run(ctx, migration, migration.Up, state, migrationStatusApplying, migrationStatusApplied)
+		if result != nil {
+			results = append(results, result)
+		}
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+func (m *migrator) Rollback(ctx context.Context, version int) ([]*MigrationResult, error) {
+	results := []*MigrationResult{}
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if migration.Version <= version {
+			break
+		}
+		state, err := m.getState(ctx, migration)
+		if err != nil {
+			return results, err
+		}
+		if state.Status == "" || state.Status == migrationStatusRolledBack {
+			continue
+		}
+		if migration.Down == nil {
+			err = log.Errore(ctx, "cloudfirestore: migration %d %s cannot be rolled back", migration.Version, migration.Name)
+			return results, err
+		}
+		if state.Status != migrationStatusRollingBack {
+			state = &migrationState{Version: migration.Version, Name: migration.Name}
+		}
+		result, err := m.run(ctx, migration, migration.Down, state, migrationStatusRollingBack, migrationStatusRolledBack)
+		if result != nil {
+			results = append(results, result)
+		}
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+func (m *migrator) stateDocRef(migration *Migration) *firestore.DocumentRef {
+	return m.cFirestore.Collection(m.option.MetadataCollection).Doc(strconv.Itoa(migration.Version))
+}
+
+func (m *migrator) getState(ctx context.Context, migration *Migration) (*migrationState, error) {
+	state := &migrationState{}
+	if _, err := Get(ctx, m.stateDocRef(migration), state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// 対象のドキュメントをドキュメントIDの順に ChunkSize ずつ変換する。
+// チャンクごとに BulkWriter で書き込み、適用状態に続きの位置を保存する
+func (m *migrator) run(ctx context.Context, migration *Migration, fn MigrationFunc, state *migrationState, runningStatus string, doneStatus string) (*MigrationResult, error) {
+	dryRun := m.option.DryRun
+	result := &MigrationResult{
+		Version: migration.Version,
+		Name:    migration.Name,
+		DryRun:  dryRun,
+	}
+	if dryRun {
+		state = &migrationState{}
+	} else {
+		result.Processed = state.Processed
+		result.Updated = state.Updated
+		log.Infof(ctx, "migration %d %s: %s (resume from: %q)", migration.Version, migration.Name, runningStatus, state.Cursor)
+	}
+
+	var query firestore.Query
+	if migration.CollectionGroup {
+		query = m.cFirestore.CollectionGroup(migration.Collection).Query
+	} else {
+		colRef := m.cFirestore.Collection(migration.Collection)
+		if colRef == nil {
+			return nil, fmt.Errorf("cloudfirestore: invalid collection path: %s", migration.Collection)
+		}
+		query = colRef.Query
+	}
+	query = query.OrderBy(firestore.DocumentID, firestore.Asc).Limit(m.option.ChunkSize)
+
+	cursor := state.Cursor
+	for {
+		q := query
+		if cursor != "" {
+			q = q.StartAfter(m.cFirestore.Doc(cursor))
+		}
+		dsnps, err := q.Documents(ctx).GetAll()
+		if err != nil {
+			log.Warning(ctx, err)
+			return result, err
+		}
+		if len(dsnps) == 0 {
+			break
+		}
+
+		updates := map[*firestore.DocumentRef]map[string]any{}
+		for _, dsnp := range dsnps {
+			kv, err := fn(ctx, dsnp)
+			if err != nil {
+				log.Warning(ctx, err)
+				return result, err
+			}
+			if kv != nil {
+				updates[dsnp.Ref] = kv
+			}
+		}
+		result.Processed += len(dsnps)
+		result.Updated += len(updates)
+		cursor = relativeDocumentPath(dsnps[len(dsnps)-1].Ref)
+
+		if !dryRun {
+			if err := m.write(ctx, updates); err != nil {
+				return result, err
+			}
+			state.Status = runningStatus
+			state.Cursor = cursor
+			state.Processed = result.Processed
+			state.Updated = result.Updated
+			if err := m.setState(ctx, migration, state); err != nil {
+				return result, err
+			}
+		}
+		if len(dsnps) < m.option.ChunkSize {
+			break
+		}
+	}
+
+	if !dryRun {
+		state.Status = doneStatus
+		state.Cursor = ""
+		if err := m.setState(ctx, migration, state); err != nil {
+			return result, err
+		}
+		log.Infof(ctx, "migration %d %s: %s (processed: %d, updated: %d)", migration.Version, migration.Name, doneStatus, result.Processed, result.Updated)
+	}
+	return result, nil
+}
+
+func (m *migrator) write(ctx context.Context, updates map[*firestore.DocumentRef]map[string]any) error {
+	if len(updates) == 0 {
+		return nil
+	}
+	bwCtx := RunBulkWriter(ctx, m.cFirestore)
+	bw := getContextBulkWriter(bwCtx)
+	jobs := []*firestore.BulkWriterJob{}
+	for docRef, kv := range updates {
+		srcs := []firestore.Update{}
+		for k, v := range kv {
+			srcs = append(srcs, firestore.Update{Path: k, Value: v})
+		}
+		job, err := bw.Update(docRef, srcs)
+		if err != nil {
+			log.Warning(ctx, err)
+			return err
+		}
+		jobs = append(jobs, job)
+	}
+	if _, err := CommitBulkWriter(bwCtx); err != nil {
+		return err
+	}
+	// 失敗した書き込みがある場合はチャンクの続きの位置を保存せずに中断する
+	errs := []error{}
+	for _, job := range jobs {
+		if _, err := job.Results(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		log.Warning(ctx, err)
+		return err
+	}
+	return nil
+}
+
+func (m *migrator) setState(ctx context.Context, migration *Migration, state *migrationState) error {
+	state.Version = migration.Version
+	state.Name = migration.Name
+	state.UpdatedAt = timeutil.Now()
+	return Set(ctx, m.stateDocRef(migration), state)
+}
+
+// データベースのルートからのドキュメントのパス
+func relativeDocumentPath(docRef *firestore.DocumentRef) string {
+	if _, path, ok := strings.Cut(docRef.Path, "/documents/"); ok {
+		return path
+	}
+	return docRef.Path
+}
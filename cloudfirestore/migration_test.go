@@ -0,0 +1,233 @@
+package cloudfirestore_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"github.com/rabee-inc/go-pkg/cloudfirestore"
+)
+
+func Test_Migrator(t *testing.T) {
+	// 1: 20歳以上に adult を追加する
+	addAdult := &cloudfirestore.Migration{
+		Version:    1,
+		Name:       "add_adult",
+		Collection: "users",
+		Up: func(ctx context.Context, dsnp *firestore.DocumentSnapshot) (map[string]any, error) {
+			if age, _ := dsnp.DataAt("age"); age.(int64) < 20 {
+				return nil, nil
+			}
+			return map[string]any{"adult": true}, nil
+		},
+		Down: func(ctx context.Context, dsnp *firestore.DocumentSnapshot) (map[string]any, error) {
+			if _, err := dsnp.DataAt("adult"); err != nil {
+				return nil, nil
+			}
+			return map[string]any{"adult": firestore.Delete}, nil
+		},
+	}
+	// 2: age を1増やす (ロールバックできない)
+	incrementAge := &cloudfirestore.Migration{
+		Version:    2,
+		Name:       "increment_age",
+		Collection: "users",
+		Up: func(ctx context.Context, dsnp *firestore.DocumentSnapshot) (map[string]any, error) {
+			return map[string]any{"age": firestore.Increment(1)}, nil
+		},
+	}
+
+	type args struct {
+		migrations []*cloudfirestore.Migration
+		run        func(ctx context.Context, cFirestore *firestore.Client, migrations []*cloudfirestore.Migration) ([]*cloudfirestore.MigrationResult, error)
+	}
+	type want struct {
+		results []string
+		users   []string
+		err     bool
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	migrate := func(opt *cloudfirestore.MigratorOption) func(ctx context.Context, cFirestore *firestore.Client, migrations []*cloudfirestore.Migration) ([]*cloudfirestore.MigrationResult, error) {
+		return func(ctx context.Context, cFirestore *firestore.Client, migrations []*cloudfirestore.Migration) ([]*cloudfirestore.MigrationResult, error) {
+			migrator, err := cloudfirestore.NewMigrator(cFirestore, migrations, opt)
+			if err != nil {
+				return nil, err
+			}
+			return migrator.Migrate(ctx)
+		}
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "番号の順に適用する",
+			args: args{
+				migrations: []*cloudfirestore.Migration{incrementAge, addAdult},
+				run:        migrate(&cloudfirestore.MigratorOption{ChunkSize: 2}),
+			},
+			want: want{
+				results: []string{"1:5:3:false", "2:5:5:false"},
+				users:   []string{"u1:11:false", "u2:21:true", "u3:31:true", "u4:41:true", "u5:20:false"},
+			},
+		},
+		{
+			name: "適用済みのマイグレーションは再実行しない",
+			args: args{
+				migrations: []*cloudfirestore.Migration{addAdult, incrementAge},
+				run: func(ctx context.Context, cFirestore *firestore.Client, migrations []*cloudfirestore.Migration) ([]*cloudfirestore.MigrationResult, error) {
+					if _, err := migrate(nil)(ctx, cFirestore, migrations); err != nil {
+						return nil, err
+					}
+					return migrate(nil)(ctx, cFirestore, migrations)
+				},
+			},
+			want: want{
+				results: []string{},
+				users:   []string{"u1:11:false", "u2:21:true", "u3:31:true", "u4:41:true", "u5:20:false"},
+			},
+		},
+		{
+			name: "DryRunは書き込まずに数える",
+			args: args{
+				migrations: []*cloudfirestore.Migration{addAdult},
+				run: func(ctx context.Context, cFirestore *firestore.Client, migrations []*cloudfirestore.Migration) ([]*cloudfirestore.MigrationResult, error) {
+					if _, err := migrate(&cloudfirestore.MigratorOption{DryRun: true, ChunkSize: 2})(ctx, cFirestore, migrations); err != nil {
+						return nil, err
+					}
+					// 適用状態も保存しない
+					return migrate(&cloudfirestore.MigratorOption{DryRun: true})(ctx, cFirestore, migrations)
+				},
+			},
+			want: want{
+				results: []string{"1:5:3:true"},
+				users:   []string{"u1:10:false", "u2:20:false", "u3:30:false", "u4:40:false", "u5:19:false"},
+			},
+		},
+		{
+			name: "中断したところから再開する",
+			args: args{
+				migrations: []*cloudfirestore.Migration{incrementAge},
+				run: func(ctx context.Context, cFirestore *firestore.Client, migrations []*cloudfirestore.Migration) ([]*cloudfirestore.MigrationResult, error) {
+					failing := *incrementAge
+					failing.Up = func(ctx context.Context, dsnp *firestore.DocumentSnapshot) (map[string]any, error) {
+						if dsnp.Ref.ID == "u4" {
+							return nil, errors.New("failed")
+						}
+						return incrementAge.Up(ctx, dsnp)
+					}
+					if _, err := migrate(&cloudfirestore.MigratorOption{ChunkSize: 2})(ctx, cFirestore, []*cloudfirestore.Migration{&failing}); err == nil {
+						return nil, errors.New("no error")
+					}
+					return migrate(&cloudfirestore.MigratorOption{ChunkSize: 2})(ctx, cFirestore, migrations)
+				},
+			},
+			want: want{
+				results: []string{"2:5:5:false"},
+				users:   []string{"u1:11:false", "u2:21:false", "u3:31:false", "u4:41:false", "u5:20:false"},
+			},
+		},
+		{
+			name: "ロールバック",
+			args: args{
+				migrations: []*cloudfirestore.Migration{addAdult},
+				run: func(ctx context.Context, cFirestore *firestore.Client, migrations []*cloudfirestore.Migration) ([]*cloudfirestore.MigrationResult, error) {
+					migrator, err := cloudfirestore.NewMigrator(cFirestore, migrations, &cloudfirestore.MigratorOption{ChunkSize: 2})
+					if err != nil {
+						return nil, err
+					}
+					if _, err := migrator.Migrate(ctx); err != nil {
+						return nil, err
+					}
+					return migrator.Rollback(ctx, 0)
+				},
+			},
+			want: want{
+				results: []string{"1:5:3:false"},
+				users:   []string{"u1:10:false", "u2:20:false", "u3:30:false", "u4:40:false", "u5:19:false"},
+			},
+		},
+		{
+			name: "ロールバックできないマイグレーション",
+			args: args{
+				migrations: []*cloudfirestore.Migration{addAdult, incrementAge},
+				run: func(ctx context.Context, cFirestore *firestore.Client, migrations []*cloudfirestore.Migration) ([]*cloudfirestore.MigrationResult, error) {
+					migrator, err := cloudfirestore.NewMigrator(cFirestore, migrations, nil)
+					if err != nil {
+						return nil, err
+					}
+					if _, err := migrator.Migrate(ctx); err != nil {
+						return nil, err
+					}
+					return migrator.Rollback(ctx, 0)
+				},
+			},
+			want: want{
+				results: []string{},
+				users:   []string{"u1:11:false", "u2:21:true", "u3:31:true", "u4:41:true", "u5:20:false"},
+				err:     true,
+			},
+		},
+		{
+			name: "番号の重複",
+			args: args{
+				migrations: []*cloudfirestore.Migration{addAdult, addAdult},
+				run:        migrate(nil),
+			},
+			want: want{
+				results: []string{},
+				users:   []string{"u1:10:false", "u2:20:false", "u3:30:false", "u4:40:false", "u5:19:false"},
+				err:     true,
+			},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := t.Context()
+			cFirestore, err := cloudfirestore.NewMemoryClient(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, age := range []int{10, 20, 30, 40, 19} {
+				docRef := cFirestore.Collection("users").Doc(fmt.Sprintf("u%d", i+1))
+				if err := cloudfirestore.Set(ctx, docRef, map[string]any{"age": age}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			results, err := tc.args.run(ctx, cFirestore, tc.args.migrations)
+			if (err != nil) != tc.want.err {
+				t.Errorf("got: %v, want: %v", err, tc.want.err)
+			}
+			gotResults := []string{}
+			for _, result := range results {
+				gotResults = append(gotResults, fmt.Sprintf("%d:%d:%d:%v", result.Version, result.Processed, result.Updated, result.DryRun))
+			}
+			if fmt.Sprint(gotResults) != fmt.Sprint(tc.want.results) {
+				t.Errorf("got: %v, want: %v", gotResults, tc.want.results)
+			}
+
+			dsnps, err := cFirestore.Collection("users").Documents(ctx).GetAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			users := []string{}
+			for _, dsnp := range dsnps {
+				age, _ := dsnp.DataAt("age")
+				adult, _ := dsnp.DataAt("adult")
+				users = append(users, fmt.Sprintf("%s:%v:%v", dsnp.Ref.ID, age, adult == true))
+			}
+			if fmt.Sprint(users) != fmt.Sprint(tc.want.users) {
+				t.Errorf("got: %v, want: %v", users, tc.want.users)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package cloudfirestore
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+)
+
+// Repository ... コレクションに紐づいた型付きのリポジトリ。
+// 取得、作成したデータには `cloudfirestore:"id"` と `cloudfirestore:"ref"` のフィールドを設定する
+type Repository[T any] interface {
+	// CollectionRef ... コレクションの参照を取得
+	CollectionRef() *firestore.CollectionRef
+	// DocRef ... ドキュメントの参照を取得
+	DocRef(id string) *firestore.DocumentRef
+	// Query ... コレクションのクエリを取得。条件を追加して List に渡す
+	Query() firestore.Query
+	// Get ... 単体取得する(tx対応)
+	Get(ctx context.Context, id string) (*T, bool, error)
+	// GetMulti ... 複数取得する(tx対応)。存在しないドキュメントは含めない
+	GetMulti(ctx context.Context, ids []string) ([]*T, error)
+	// List ... クエリで複数取得する(tx対応)
+	List(ctx context.Context, query firestore.Query) ([]*T, error)
+	// Create ... 作成する(tx, bw対応)
+	Create(ctx context.Context, src *T) error
+	// Set ... 上書きする(tx, bw対応)
+	Set(ctx context.Context, id string, src *T) error
+	// Update ... 更新する(tx, bw対応)
+	Update(ctx context.Context, id string, kv map[string]any) error
+	// Delete ... 削除する(tx, bw対応)
+	Delete(ctx context.Context, id string) error
+}
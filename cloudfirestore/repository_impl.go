@@ -0,0 +1,107 @@
+package cloudfirestore
+
+import (
+	"context"
+	"fmt"
+
+	"cloud.google.com/go/firestore"
+)
+
+type repository[T any] struct {
+	cFirestore *firestore.Client
+	colRef     *firestore.CollectionRef
+}
+
+// NewRepository ... コレクションのパス (例: "users", "users/{id}/posts") に紐づいたリポジトリを作成する
+func NewRepository[T any](cFirestore *firestore.Client, path string) Repository[T] {
+	colRef := cFirestore.Collection(path)
+	if colRef == nil {
+		panic(fmt.Sprintf("cloudfirestore: invalid collection path: %s", path))
+	}
+	return &repository[T]{
+		cFirestore: cFirestore,
+		colRef:     colRef,
+	}
+}
+
+func (r *repository[T]) CollectionRef() *firestore.CollectionRef {
+	return r.colRef
+}
+
+func (r *repository[T]) DocRef(id string) *firestore.DocumentRef {
+	return r.colRef.Doc(id)
+}
+
+// 書き込み用のドキュメントの参照を取得する
+func (r *repository[T]) validDocRef(id string) (*firestore.DocumentRef, error) {
+	docRef := r.DocRef(id)
+	if docRef == nil {
+		return nil, fmt.Errorf("Invalid Document ID: %q", id)
+	}
+	return docRef, nil
+}
+
+func (r *repository[T]) Query() firestore.Query {
+	return r.colRef.Query
+}
+
+func (r *repository[T]) Get(ctx context.Context, id string) (*T, bool, error) {
+	docRef := r.DocRef(id)
+	if docRef == nil {
+		return nil, false, nil
+	}
+	dst := new(T)
+	exists, err := Get(ctx, docRef, dst)
+	if err != nil || !exists {
+		return nil, false, err
+	}
+	return dst, true, nil
+}
+
+func (r *repository[T]) GetMulti(ctx context.Context, ids []string) ([]*T, error) {
+	docRefs := make([]*firestore.DocumentRef, 0, len(ids))
+	for _, id := range ids {
+		docRefs = append(docRefs, r.DocRef(id))
+	}
+	dsts := []*T{}
+	if err := GetMulti(ctx, r.cFirestore, docRefs, &dsts); err != nil {
+		return nil, err
+	}
+	return dsts, nil
+}
+
+func (r *repository[T]) List(ctx context.Context, query firestore.Query) ([]*T, error) {
+	dsts := []*T{}
+	if err := ListByQuery(ctx, query, &dsts); err != nil {
+		return nil, err
+	}
+	return dsts, nil
+}
+
+func (r *repository[T]) Create(ctx context.Context, src *T) error {
+	return Create(ctx, r.colRef, src)
+}
+
+func (r *repository[T]) Set(ctx context.Context, id string, src *T) error {
+	docRef, err := r.validDocRef(id)
+	if err != nil {
+		return err
+	}
+	return Set(ctx, docRef, src)
+}
+
+func (r *repository[T]) Update(ctx context.Context, id string, kv map[string]any) error {
+	docRef, err := r.validDocRef(id)
+	if err != nil {
+		return err
+	}
+	return Update(ctx, docRef, kv)
+}
+
+func (r *repository[T]) Delete(ctx context.Context, id string) error {
+	docRef, err := r.validDocRef(id)
+	if err != nil {
+		return err
+	}
+	return Delete(ctx, docRef)
+}
@@ -0,0 +1,227 @@
+package cloudfirestore_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"github.com/rabee-inc/go-pkg/cloudfirestore"
+)
+
+type testUser struct {
+	ID   string                 `firestore:"-" cloudfirestore:"id"`
+	Ref  *firestore.DocumentRef `firestore:"-" cloudfirestore:"ref"`
+	Name string                 `firestore:"name"`
+	Age  int                    `firestore:"age"`
+	Tags []string               `firestore:"tags"`
+}
+
+func Test_Repository(t *testing.T) {
+	type args struct {
+		run func(ctx context.Context, cFirestore *firestore.Client, repo cloudfirestore.Repository[testUser]) error
+	}
+	type want struct {
+		users []string
+		err   bool
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "作成したデータにIDと参照を設定する",
+			args: args{
+				run: func(ctx context.Context, cFirestore *firestore.Client, repo cloudfirestore.Repository[testUser]) error {
+					user := &testUser{Name: "saburo", Age: 15}
+					if err := repo.Create(ctx, user); err != nil {
+						return err
+					}
+					if user.ID == "" || user.Ref == nil || user.Ref.ID != user.ID {
+						return fmt.Errorf("id: %s, ref: %v", user.ID, user.Ref)
+					}
+					return nil
+				},
+			},
+			want: want{
+				users: []string{"*:saburo:15", "u1:taro:20", "u2:jiro:30"},
+			},
+		},
+		{
+			name: "取得",
+			args: args{
+				run: func(ctx context.Context, cFirestore *firestore.Client, repo cloudfirestore.Repository[testUser]) error {
+					user, exists, err := repo.Get(ctx, "u1")
+					if err != nil {
+						return err
+					}
+					if !exists || user.ID != "u1" || user.Ref.ID != "u1" || user.Name != "taro" || user.Tags == nil {
+						return fmt.Errorf("user: %+v", user)
+					}
+					_, exists, err = repo.Get(ctx, "none")
+					if err != nil || exists {
+						return fmt.Errorf("exists: %v, err: %v", exists, err)
+					}
+					users, err := repo.GetMulti(ctx, []string{"u2", "none", "u1"})
+					if err != nil {
+						return err
+					}
+					if len(users) != 2 || users[0].ID != "u2" || users[1].ID != "u1" {
+						return fmt.Errorf("users: %v", users)
+					}
+					return nil
+				},
+			},
+			want: want{
+				users: []string{"u1:taro:20", "u2:jiro:30"},
+			},
+		},
+		{
+			name: "クエリで取得",
+			args: args{
+				run: func(ctx context.Context, cFirestore *firestore.Client, repo cloudfirestore.Repository[testUser]) error {
+					users, err := repo.List(ctx, repo.Query().Where("age", ">=", 25).OrderBy("age", firestore.Desc))
+					if err != nil {
+						return err
+					}
+					if len(users) != 1 || users[0].ID != "u2" {
+						return fmt.Errorf("users: %v", users)
+					}
+					return nil
+				},
+			},
+			want: want{
+				users: []string{"u1:taro:20", "u2:jiro:30"},
+			},
+		},
+		{
+			name: "上書き、更新、削除",
+			args: args{
+				run: func(ctx context.Context, cFirestore *firestore.Client, repo cloudfirestore.Repository[testUser]) error {
+					if err := repo.Set(ctx, "u3", &testUser{Name: "shiro", Age: 40}); err != nil {
+						return err
+					}
+					if err := repo.Update(ctx, "u1", map[string]any{"age": firestore.Increment(1)}); err != nil {
+						return err
+					}
+					return repo.Delete(ctx, "u2")
+				},
+			},
+			want: want{
+				users: []string{"u1:taro:21", "u3:shiro:40"},
+			},
+		},
+		{
+			name: "存在しないドキュメントの更新はエラー",
+			args: args{
+				run: func(ctx context.Context, cFirestore *firestore.Client, repo cloudfirestore.Repository[testUser]) error {
+					return repo.Update(ctx, "none", map[string]any{"age": 1})
+				},
+			},
+			want: want{
+				users: []string{"u1:taro:20", "u2:jiro:30"},
+				err:   true,
+			},
+		},
+		{
+			name: "不正なIDはエラー",
+			args: args{
+				run: func(ctx context.Context, cFirestore *firestore.Client, repo cloudfirestore.Repository[testUser]) error {
+					return repo.Set(ctx, "", &testUser{})
+				},
+			},
+			want: want{
+				users: []string{"u1:taro:20", "u2:jiro:30"},
+				err:   true,
+			},
+		},
+		{
+			name: "トランザクション内ではまとめて書き込む",
+			args: args{
+				run: func(ctx context.Context, cFirestore *firestore.Client, repo cloudfirestore.Repository[testUser]) error {
+					return cloudfirestore.RunTransaction(ctx, cFirestore, func(ctx context.Context) error {
+						user, _, err := repo.Get(ctx, "u1")
+						if err != nil {
+							return err
+						}
+						if err := repo.Update(ctx, "u1", map[string]any{"age": user.Age + 5}); err != nil {
+							return err
+						}
+						if err := repo.Delete(ctx, "u2"); err != nil {
+							return err
+						}
+						return fmt.Errorf("rollback")
+					})
+				},
+			},
+			want: want{
+				users: []string{"u1:taro:20", "u2:jiro:30"},
+				err:   true,
+			},
+		},
+		{
+			name: "BulkWriter",
+			args: args{
+				run: func(ctx context.Context, cFirestore *firestore.Client, repo cloudfirestore.Repository[testUser]) error {
+					ctx = cloudfirestore.RunBulkWriter(ctx, cFirestore)
+					if err := repo.Set(ctx, "u3", &testUser{Name: "shiro", Age: 40}); err != nil {
+						return err
+					}
+					if err := repo.Delete(ctx, "u1"); err != nil {
+						return err
+					}
+					_, err := cloudfirestore.CommitBulkWriter(ctx)
+					return err
+				},
+			},
+			want: want{
+				users: []string{"u2:jiro:30", "u3:shiro:40"},
+			},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := t.Context()
+			cFirestore, err := cloudfirestore.NewMemoryClient(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			repo := cloudfirestore.NewRepository[testUser](cFirestore, "users")
+			for _, user := range []*testUser{
+				{ID: "u1", Name: "taro", Age: 20},
+				{ID: "u2", Name: "jiro", Age: 30},
+			} {
+				if err := repo.Set(ctx, user.ID, user); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err = tc.args.run(ctx, cFirestore, repo)
+			if (err != nil) != tc.want.err {
+				t.Errorf("got: %v, want: %v", err, tc.want.err)
+			}
+
+			users, err := repo.List(ctx, repo.Query().OrderBy("age", firestore.Asc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for i, user := range users {
+				id := user.ID
+				if i < len(tc.want.users) && tc.want.users[i][0] == '*' {
+					id = "*"
+				}
+				got = append(got, fmt.Sprintf("%s:%s:%d", id, user.Name, user.Age))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tc.want.users) {
+				t.Errorf("got: %v, want: %v", got, tc.want.users)
+			}
+		})
+	}
+}
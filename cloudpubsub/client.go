@@ -43,8 +43,10 @@ func (c *Client) Publish(
 		log.Error(ctx, err)
 		return err
 	}
+	// 受信側でトレースを引き継げるように属性に付与する
 	if _, err := c.cPubSub.Topic(topicID).Publish(ctx, &pubsub.Message{
-		Data: bMsg,
+		Data:       bMsg,
+		Attributes: log.GetTraceHeaders(ctx),
 	}).Get(ctx); err != nil {
 		log.Error(ctx, err)
 		return err
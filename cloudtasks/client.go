@@ -49,10 +49,10 @@ func NewClient(
 
 // リクエストをEnqueueする
 func (c *Client) AddTask(ctx context.Context, queue string, path string, params any) error {
-	headers := map[string]string{
-		"Content-Type":  "application/json",
-		"Authorization": c.authToken,
-	}
+	// タスクの実行をリクエストのトレースに含める
+	headers := log.GetTraceHeaders(ctx)
+	headers["Content-Type"] = "application/json"
+	headers["Authorization"] = c.authToken
 	body, err := json.Marshal(params)
 	if err != nil {
 		log.Error(ctx, err)
@@ -19,6 +19,10 @@ const defaultTimeout time.Duration = 7 * time.Second
 type HTTPOption struct {
 	Headers map[string]string
 	Timeout time.Duration
+	// Contextのトレース (traceparent, X-Cloud-Trace-Context) をヘッダーに付与する
+	PropagateTrace bool
+	// Contextのキャンセル、期限をリクエストに反映する
+	BindContext bool
 }
 
 // Getリクエスト(URL)
@@ -371,6 +375,14 @@ func send(ctx context.Context, req *http.Request, opt *HTTPOption) (int, []byte,
 		client.Timeout = defaultTimeout
 	}
 
+	if opt != nil && opt.PropagateTrace {
+		log.InjectTraceHeaders(ctx, req.Header)
+	}
+
+	if opt != nil && opt.BindContext {
+		req = req.WithContext(ctx)
+	}
+
 	res, err := client.Do(req)
 	if err != nil {
 		log.Warning(ctx, err)
@@ -17,3 +17,10 @@ type Action interface {
 		params any,
 	) (any, error)
 }
+
+// params, result の型情報を持つ Action 。 rpc.discover で型情報が出力される
+type ActionWithSchema interface {
+	Action
+	GetEmptyParams() any
+	GetEmptyResult() any
+}
@@ -0,0 +1,49 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/rabee-inc/go-pkg/log"
+)
+
+// 型付きの関数から Action を作成する。 params は P にデコードして f に渡す
+func NewAction[P, R any](f func(ctx context.Context, params *P) (*R, error)) ActionWithSchema {
+	return &action[P, R]{
+		ExecFunc: f,
+	}
+}
+
+type action[P, R any] struct {
+	ExecFunc func(ctx context.Context, params *P) (*R, error)
+}
+
+func (a *action[P, R]) DecodeParams(ctx context.Context, msg *json.RawMessage) (any, error) {
+	var params P
+	if msg == nil {
+		return &params, nil
+	}
+	if err := json.Unmarshal(*msg, &params); err != nil {
+		log.Warning(ctx, err)
+		return nil, err
+	}
+	return &params, nil
+}
+
+func (a *action[P, R]) Exec(ctx context.Context, method string, params any) (any, error) {
+	p, ok := params.(*P)
+	if !ok {
+		err := log.Errorc(ctx, http.StatusBadRequest, "invalid params type: %T", params)
+		return nil, err
+	}
+	return a.ExecFunc(ctx, p)
+}
+
+func (a *action[P, R]) GetEmptyParams() any {
+	return *new(P)
+}
+
+func (a *action[P, R]) GetEmptyResult() any {
+	return *new(R)
+}
@@ -0,0 +1,127 @@
+package jsonrpc2_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/rabee-inc/go-pkg/errcode"
+	"github.com/rabee-inc/go-pkg/jsonrpc2"
+)
+
+func Test_NewAction(t *testing.T) {
+	type args struct {
+		// nil の場合は params なし
+		msg *string
+		// 指定した場合は DecodeParams の結果の代わりに Exec に渡す
+		params any
+	}
+	type want struct {
+		message   string
+		decodeErr bool
+		execCode  int
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	str := func(s string) *string {
+		return &s
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "params を型に変換して実行する",
+			args: args{
+				msg: str(`{"message":"hello"}`),
+			},
+			want: want{
+				message: "hello",
+			},
+		},
+		{
+			name: "params がない場合はゼロ値で実行する",
+			args: args{
+				msg: nil,
+			},
+			want: want{
+				message: "",
+			},
+		},
+		{
+			name: "params の形式が不正",
+			args: args{
+				msg: str(`{"message":1}`),
+			},
+			want: want{
+				decodeErr: true,
+			},
+		},
+		{
+			name: "Exec に異なる型の params が渡された",
+			args: args{
+				params: &sleepParams{},
+			},
+			want: want{
+				execCode: http.StatusBadRequest,
+			},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			action := jsonrpc2.NewAction(func(ctx context.Context, params *echoParams) (*echoResult, error) {
+				return &echoResult{params.Message}, nil
+			})
+
+			params := tc.args.params
+			if params == nil {
+				var msg *json.RawMessage
+				if tc.args.msg != nil {
+					raw := json.RawMessage(*tc.args.msg)
+					msg = &raw
+				}
+				var err error
+				params, err = action.DecodeParams(ctx, msg)
+				if (err != nil) != tc.want.decodeErr {
+					t.Fatalf("got: %v, want: %v", err, tc.want.decodeErr)
+				}
+				if err != nil {
+					return
+				}
+			}
+
+			result, err := action.Exec(ctx, "echo", params)
+			if tc.want.execCode != 0 {
+				code, _ := errcode.Get(err)
+				if code != tc.want.execCode {
+					t.Errorf("got: %v, want: %v", code, tc.want.execCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.(*echoResult).Message != tc.want.message {
+				t.Errorf("got: %v, want: %v", result.(*echoResult).Message, tc.want.message)
+			}
+		})
+	}
+
+	// 型情報
+	action := jsonrpc2.NewAction(func(ctx context.Context, params *echoParams) (*echoResult, error) {
+		return nil, nil
+	})
+	if _, ok := action.GetEmptyParams().(echoParams); !ok {
+		t.Errorf("got: %T, want: %T", action.GetEmptyParams(), echoParams{})
+	}
+	if _, ok := action.GetEmptyResult().(echoResult); !ok {
+		t.Errorf("got: %T, want: %T", action.GetEmptyResult(), echoResult{})
+	}
+}
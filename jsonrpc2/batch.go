@@ -0,0 +1,152 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rabee-inc/go-pkg/errcode"
+	"github.com/rabee-inc/go-pkg/log"
+)
+
+// バッチリクエストの設定
+type BatchOption struct {
+	// 1 回のバッチリクエストに含められるリクエスト数の上限。 0 の場合は無制限
+	MaxSize int
+	// 同時に実行するリクエスト数の上限。 0 の場合は無制限
+	Concurrency int
+	// レスポンスをリクエストと同じ順番で返す。 false の場合は完了した順番で返す
+	PreserveOrder bool
+}
+
+// メソッドごとの設定
+type MethodOption struct {
+	// 実行のタイムアウト。 0 の場合はタイムアウトしない。
+	// タイムアウトした場合は Action の終了を待たずにエラーを返す
+	Timeout time.Duration
+	// バッチリクエストで、このメソッドのリクエストを並列に実行せず、リクエストの順番に 1 つずつ実行する
+	Sequential bool
+}
+
+var errBatchSizeExceeded = errors.New("batch size exceeds the limit")
+
+// バッチリクエストの設定をセットする
+func (h *Handler) SetBatchOption(opt *BatchOption) {
+	if opt == nil {
+		opt = &BatchOption{}
+	}
+	h.batchOption = opt
+}
+
+// メソッドの設定をセットする
+func (h *Handler) SetMethodOption(method string, opt *MethodOption) {
+	if opt == nil {
+		delete(h.methodOptions, method)
+		return
+	}
+	h.methodOptions[method] = opt
+}
+
+func (h *Handler) getMethodOption(method string) *MethodOption {
+	if opt, ok := h.methodOptions[method]; ok {
+		return opt
+	}
+	return &MethodOption{}
+}
+
+func (h *Handler) isBatchSizeExceeded(size int) bool {
+	return h.batchOption.MaxSize > 0 && size > h.batchOption.MaxSize
+}
+
+// バッチリクエストを設定に従って実行する。
+// methods は各リクエストのメソッド名で、 exec で i 番目のリクエストを実行する。返り値はレスポンスを返す順番のインデックス
+func (h *Handler) runBatch(methods []string, exec func(i int)) []int {
+	// 並列に実行する単位ごとにまとめる。順番に実行するリクエストは 1 つの単位にまとめて最初に実行を開始する
+	units := [][]int{}
+	sequential := []int{}
+	for i, method := range methods {
+		if h.getMethodOption(method).Sequential {
+			sequential = append(sequential, i)
+			continue
+		}
+		units = append(units, []int{i})
+	}
+	if len(sequential) > 0 {
+		units = append([][]int{sequential}, units...)
+	}
+
+	concurrency := h.batchOption.Concurrency
+	if concurrency <= 0 || concurrency > len(units) {
+		concurrency = len(units)
+	}
+
+	ch := make(chan []int, len(units))
+	for _, unit := range units {
+		ch <- unit
+	}
+	close(ch)
+
+	mutex := &sync.Mutex{}
+	order := make([]int, 0, len(methods))
+	wg := &sync.WaitGroup{}
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for unit := range ch {
+				for _, i := range unit {
+					exec(i)
+					mutex.Lock()
+					order = append(order, i)
+					mutex.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if h.batchOption.PreserveOrder {
+		for i := range order {
+			order[i] = i
+		}
+	}
+	return order
+}
+
+// メソッドの設定に従って Action を実行する
+func (h *Handler) execActionWithOption(ctx context.Context, method string, action Action, params any) (any, error) {
+	opt := h.getMethodOption(method)
+	if opt.Timeout <= 0 {
+		return action.Exec(ctx, method, params)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opt.Timeout)
+	defer cancel()
+
+	type execResult struct {
+		result any
+		err    error
+	}
+	ch := make(chan *execResult, 1)
+	go func() {
+		defer func() {
+			if rcvr := recover(); rcvr != nil {
+				msg := log.Panic(ctx, rcvr)
+				ch <- &execResult{nil, errors.New(msg)}
+			}
+		}()
+		result, err := action.Exec(ctx, method, params)
+		ch <- &execResult{result, err}
+	}()
+
+	select {
+	case res := <-ch:
+		return res.result, res.err
+	case <-ctx.Done():
+		err := fmt.Errorf("method %s timed out after %s", method, opt.Timeout)
+		return nil, errcode.Set(err, http.StatusGatewayTimeout)
+	}
+}
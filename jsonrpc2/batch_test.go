@@ -0,0 +1,263 @@
+package jsonrpc2_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rabee-inc/go-pkg/jsonrpc2"
+)
+
+type sleepParams struct {
+	Milliseconds int `json:"ms"`
+}
+
+type sleepResult struct {
+	Milliseconds int `json:"ms"`
+}
+
+// 同時に実行されている数の最大値を記録する
+type concurrencyCounter struct {
+	mutex   sync.Mutex
+	current int
+	max     int
+}
+
+func (c *concurrencyCounter) start() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.current++
+	if c.current > c.max {
+		c.max = c.current
+	}
+}
+
+func (c *concurrencyCounter) end() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.current--
+}
+
+func newSleepAction(counter *concurrencyCounter, ignoreContext bool) jsonrpc2.Action {
+	return jsonrpc2.NewAction(func(ctx context.Context, params *sleepParams) (*sleepResult, error) {
+		counter.start()
+		defer counter.end()
+		d := time.Duration(params.Milliseconds) * time.Millisecond
+		if ignoreContext {
+			time.Sleep(d)
+			return &sleepResult{params.Milliseconds}, nil
+		}
+		select {
+		case <-time.After(d):
+			return &sleepResult{params.Milliseconds}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	})
+}
+
+func doBatch(t *testing.T, h *jsonrpc2.Handler, methods []string, sleeps []int) (int, []*jsonrpc2.ClientResponse) {
+	t.Helper()
+	reqs := []*jsonrpc2.ClientRequest{}
+	for i, method := range methods {
+		b, _ := json.Marshal(&sleepParams{sleeps[i]})
+		params := json.RawMessage(b)
+		reqs = append(reqs, &jsonrpc2.ClientRequest{
+			Version: "2.0",
+			ID:      fmt.Sprint(i),
+			Method:  method,
+			Params:  &params,
+		})
+	}
+	body, _ := json.Marshal(reqs)
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.Handle(w, r)
+
+	var responses []*jsonrpc2.ClientResponse
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &responses); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return w.Code, responses
+}
+
+func Test_Handler_Batch(t *testing.T) {
+	type args struct {
+		batchOption   *jsonrpc2.BatchOption
+		methodOptions map[string]*jsonrpc2.MethodOption
+		methods       []string
+		sleeps        []int
+	}
+	type want struct {
+		status         int
+		ids            []string
+		errorIDs       []string
+		maxConcurrency int
+		maxElapsed     time.Duration
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "遅いメソッドがタイムアウトしてもバッチ全体はブロックされない",
+			args: args{
+				batchOption: &jsonrpc2.BatchOption{PreserveOrder: true},
+				methodOptions: map[string]*jsonrpc2.MethodOption{
+					"slow": {Timeout: 50 * time.Millisecond},
+				},
+				methods: []string{"slow", "fast", "fast"},
+				sleeps:  []int{3000, 10, 10},
+			},
+			want: want{
+				status:     http.StatusOK,
+				ids:        []string{"0", "1", "2"},
+				errorIDs:   []string{"0"},
+				maxElapsed: time.Second,
+			},
+		},
+		{
+			name: "並列数の上限",
+			args: args{
+				batchOption: &jsonrpc2.BatchOption{Concurrency: 2, PreserveOrder: true},
+				methods:     []string{"fast", "fast", "fast", "fast", "fast", "fast"},
+				sleeps:      []int{30, 30, 30, 30, 30, 30},
+			},
+			want: want{
+				status:         http.StatusOK,
+				ids:            []string{"0", "1", "2", "3", "4", "5"},
+				maxConcurrency: 2,
+			},
+		},
+		{
+			name: "並列数が 1 でもタイムアウトしたメソッドの後続は実行される",
+			args: args{
+				batchOption: &jsonrpc2.BatchOption{Concurrency: 1, PreserveOrder: true},
+				methodOptions: map[string]*jsonrpc2.MethodOption{
+					"slow": {Timeout: 50 * time.Millisecond},
+				},
+				methods: []string{"slow", "fast", "fast"},
+				sleeps:  []int{3000, 10, 10},
+			},
+			want: want{
+				status:         http.StatusOK,
+				ids:            []string{"0", "1", "2"},
+				errorIDs:       []string{"0"},
+				maxConcurrency: 1,
+				maxElapsed:     time.Second,
+			},
+		},
+		{
+			name: "レスポンスの順番をリクエストと揃える",
+			args: args{
+				batchOption: &jsonrpc2.BatchOption{PreserveOrder: true},
+				methods:     []string{"fast", "fast", "fast"},
+				sleeps:      []int{60, 30, 0},
+			},
+			want: want{
+				status: http.StatusOK,
+				ids:    []string{"0", "1", "2"},
+			},
+		},
+		{
+			// 並列数が 1 の場合、順番に実行するメソッドがまとめて最初に実行されるため完了順が決まる
+			name: "レスポンスを完了順で返す",
+			args: args{
+				batchOption: &jsonrpc2.BatchOption{Concurrency: 1},
+				methodOptions: map[string]*jsonrpc2.MethodOption{
+					"sequential": {Sequential: true},
+				},
+				methods: []string{"fast", "sequential", "fast", "sequential"},
+				sleeps:  []int{0, 0, 0, 0},
+			},
+			want: want{
+				status: http.StatusOK,
+				ids:    []string{"1", "3", "0", "2"},
+			},
+		},
+		{
+			name: "順番に実行するメソッド",
+			args: args{
+				batchOption: &jsonrpc2.BatchOption{PreserveOrder: true},
+				methodOptions: map[string]*jsonrpc2.MethodOption{
+					"fast": {Sequential: true},
+				},
+				methods: []string{"fast", "fast", "fast"},
+				sleeps:  []int{30, 20, 10},
+			},
+			want: want{
+				status:         http.StatusOK,
+				ids:            []string{"0", "1", "2"},
+				maxConcurrency: 1,
+			},
+		},
+		{
+			name: "バッチサイズの上限",
+			args: args{
+				batchOption: &jsonrpc2.BatchOption{MaxSize: 2},
+				methods:     []string{"fast", "fast", "fast"},
+				sleeps:      []int{0, 0, 0},
+			},
+			want: want{
+				status: http.StatusBadRequest,
+			},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			// slow はタイムアウト後も処理を続けるため、 fast とは別に数える
+			counter := &concurrencyCounter{}
+			h := jsonrpc2.NewHandler()
+			h.Register("slow", newSleepAction(&concurrencyCounter{}, true))
+			h.Register("fast", newSleepAction(counter, false))
+			h.Register("sequential", newSleepAction(counter, false))
+			h.SetBatchOption(tc.args.batchOption)
+			for method, opt := range tc.args.methodOptions {
+				h.SetMethodOption(method, opt)
+			}
+
+			start := time.Now()
+			status, responses := doBatch(t, h, tc.args.methods, tc.args.sleeps)
+			elapsed := time.Since(start)
+
+			if status != tc.want.status {
+				t.Fatalf("got: %v, want: %v", status, tc.want.status)
+			}
+			ids := []string{}
+			errorIDs := []string{}
+			for _, res := range responses {
+				ids = append(ids, res.ID)
+				if res.Error != nil {
+					errorIDs = append(errorIDs, res.ID)
+				}
+			}
+			if fmt.Sprint(ids) != fmt.Sprint(tc.want.ids) {
+				t.Errorf("got: %v, want: %v", ids, tc.want.ids)
+			}
+			if fmt.Sprint(errorIDs) != fmt.Sprint(tc.want.errorIDs) {
+				t.Errorf("got: %v, want: %v", errorIDs, tc.want.errorIDs)
+			}
+			if tc.want.maxConcurrency > 0 && counter.max > tc.want.maxConcurrency {
+				t.Errorf("got: %v, want: <= %v", counter.max, tc.want.maxConcurrency)
+			}
+			if tc.want.maxElapsed > 0 && elapsed > tc.want.maxElapsed {
+				t.Errorf("got: %v, want: <= %v", elapsed, tc.want.maxElapsed)
+			}
+		})
+	}
+}
@@ -3,23 +3,27 @@ package jsonrpc2
 import (
 	"context"
 	"encoding/json"
-	"net/http"
+	"time"
 
 	"github.com/rabee-inc/go-pkg/httpclient"
 	"github.com/rabee-inc/go-pkg/log"
 )
 
 type Client struct {
-	URL      string
-	Headers  map[string]string
-	Requests []*ClientRequest
+	URL         string
+	Headers     map[string]string
+	Requests    []*ClientRequest
+	timeout     time.Duration
+	retryOption *RetryOption
+	// リクエストにトレースのヘッダーを付与する
+	propagateTrace bool
 }
 
 func NewClient(url string, headers map[string]string) *Client {
 	return &Client{
-		url,
-		headers,
-		[]*ClientRequest{},
+		URL:      url,
+		Headers:  headers,
+		Requests: []*ClientRequest{},
 	}
 }
 
@@ -54,30 +58,32 @@ func (c *Client) DoSingle(ctx context.Context, method string, params any) (*json
 		Params:  rawParams,
 	}
 	var res ClientResponse
-	status, err := httpclient.PostJSON(ctx, c.URL, req, &res, &httpclient.HTTPOption{Headers: c.Headers})
+	err = c.doWithRetry(ctx, []string{method}, func() (int, error) {
+		res = ClientResponse{}
+		return httpclient.PostJSON(ctx, c.URL, req, &res, c.httpOption(ctx))
+	})
 	if err != nil {
 		log.Error(ctx, err)
 		return nil, nil, err
 	}
-	if status != http.StatusOK {
-		err := log.Errore(ctx, "httpclient.PostJSON status: %d", status)
-		return nil, nil, err
-	}
 	return res.Result, res.Error, nil
 }
 
 // JSONRPC2のバッチリクエストを行う
 func (c *Client) DoBatch(ctx context.Context) ([]*ClientResponse, error) {
+	methods := make([]string, len(c.Requests))
+	for i, req := range c.Requests {
+		methods[i] = req.Method
+	}
 	var res []*ClientResponse
-	status, err := httpclient.PostJSON(ctx, c.URL, c.Requests, &res, nil)
+	err := c.doWithRetry(ctx, methods, func() (int, error) {
+		res = nil
+		return httpclient.PostJSON(ctx, c.URL, c.Requests, &res, c.httpOption(ctx))
+	})
 	if err != nil {
 		log.Error(ctx, err)
 		return nil, err
 	}
-	if status != http.StatusOK {
-		err := log.Errore(ctx, "httpclient.PostJSON status: %d", status)
-		return nil, err
-	}
 	return res, nil
 }
 
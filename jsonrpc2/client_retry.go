@@ -0,0 +1,160 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/rabee-inc/go-pkg/errcode"
+	"github.com/rabee-inc/go-pkg/httpclient"
+	"github.com/rabee-inc/go-pkg/log"
+)
+
+const (
+	defaultRetryInterval    = 100 * time.Millisecond
+	defaultMaxRetryInterval = 5 * time.Second
+)
+
+// 通信エラー時の再試行の設定
+type RetryOption struct {
+	// 最大の再試行回数
+	MaxRetries int
+	// 初回の再試行までの間隔。失敗するごとに 2 倍にする。 0 の場合は 100 ミリ秒
+	Interval time.Duration
+	// 再試行の間隔の上限。 0 の場合は 5 秒
+	MaxInterval time.Duration
+	// 再試行するメソッド。複数回実行しても結果が変わらない (冪等な) メソッドのみ指定する
+	IdempotentMethods []string
+}
+
+// 1 回のリクエストのタイムアウトをセットする。
+// 0 の場合は context の期限 (期限がない場合は httpclient のデフォルト) を使用する
+func (c *Client) SetTimeout(timeout time.Duration) {
+	c.timeout = timeout
+}
+
+// 再試行の設定をセットする。 nil の場合は再試行しない
+func (c *Client) SetRetryOption(opt *RetryOption) {
+	c.retryOption = opt
+}
+
+// リクエストに Context のトレース (traceparent, X-Cloud-Trace-Context) を付与する
+func (c *Client) EnableTracePropagation() {
+	c.propagateTrace = true
+}
+
+// JSONRPC2のシングルリクエストを行い、 result を R にデコードする。
+// エラーレスポンスの場合は code を errcode に設定したエラーを返す
+func Call[R any](ctx context.Context, c *Client, method string, params any) (*R, error) {
+	rawResult, resError, err := c.DoSingle(ctx, method, params)
+	if err != nil {
+		log.Warning(ctx, err)
+		return nil, err
+	}
+	if resError != nil {
+		err := errcode.Set(errors.New(resError.Message), resError.Code)
+		log.Warningf(ctx, "code: %d, message: %s", resError.Code, resError.Message)
+		return nil, err
+	}
+	var result R
+	if rawResult == nil {
+		return &result, nil
+	}
+	if err := json.Unmarshal(*rawResult, &result); err != nil {
+		log.Warning(ctx, err)
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *Client) httpOption(ctx context.Context) *httpclient.HTTPOption {
+	opt := &httpclient.HTTPOption{
+		Headers:        c.Headers,
+		Timeout:        c.timeout,
+		PropagateTrace: c.propagateTrace,
+		BindContext:    true,
+	}
+	// context の期限を優先する
+	if deadline, ok := ctx.Deadline(); ok && opt.Timeout == 0 {
+		opt.Timeout = time.Until(deadline)
+	}
+	return opt
+}
+
+// do を実行し、通信エラーの場合は全てのメソッドが冪等であれば間隔を空けて再試行する
+func (c *Client) doWithRetry(ctx context.Context, methods []string, do func() (int, error)) error {
+	opt := c.retryOption
+	maxRetries := 0
+	if opt != nil && c.isIdempotent(methods) {
+		maxRetries = opt.MaxRetries
+	}
+
+	var interval time.Duration
+	if opt != nil {
+		interval = opt.Interval
+		if interval <= 0 {
+			interval = defaultRetryInterval
+		}
+	}
+
+	for retry := 0; ; retry++ {
+		status, err := do()
+		if status != 0 && status != http.StatusOK {
+			err = errcode.Set(fmt.Errorf("httpclient.PostJSON status: %d", status), status)
+		}
+		if err == nil {
+			return nil
+		}
+		if retry >= maxRetries || !isRetryable(ctx, status) {
+			return err
+		}
+
+		log.Warningf(ctx, "retry jsonrpc2 request after %s: %s", interval, err.Error())
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return err
+		}
+		interval *= 2
+		maxInterval := opt.MaxInterval
+		if maxInterval <= 0 {
+			maxInterval = defaultMaxRetryInterval
+		}
+		if interval > maxInterval {
+			interval = maxInterval
+		}
+	}
+}
+
+func (c *Client) isIdempotent(methods []string) bool {
+	if len(methods) == 0 {
+		return false
+	}
+	for _, method := range methods {
+		if !slices.Contains(c.retryOption.IdempotentMethods, method) {
+			return false
+		}
+	}
+	return true
+}
+
+// レスポンスを受け取れなかった場合と、一時的なエラーのステータスの場合に再試行する
+func isRetryable(ctx context.Context, status int) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	switch status {
+	case 0,
+		http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
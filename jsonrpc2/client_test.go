@@ -0,0 +1,179 @@
+package jsonrpc2_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rabee-inc/go-pkg/errcode"
+	"github.com/rabee-inc/go-pkg/jsonrpc2"
+)
+
+func Test_Call(t *testing.T) {
+	type args struct {
+		method      string
+		retryOption *jsonrpc2.RetryOption
+		// 最初の n 回は 503 を返す
+		unavailable int32
+		timeout     time.Duration
+	}
+	type want struct {
+		message  string
+		code     int
+		err      bool
+		requests int32
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "result を型に変換する",
+			args: args{
+				method: "echo",
+			},
+			want: want{
+				message:  "hello",
+				requests: 1,
+			},
+		},
+		{
+			name: "エラーレスポンスを errcode に変換する",
+			args: args{
+				method: "forbidden",
+			},
+			want: want{
+				code:     http.StatusForbidden,
+				err:      true,
+				requests: 1,
+			},
+		},
+		{
+			name: "冪等なメソッドは再試行する",
+			args: args{
+				method: "echo",
+				retryOption: &jsonrpc2.RetryOption{
+					MaxRetries:        3,
+					Interval:          time.Millisecond,
+					IdempotentMethods: []string{"echo"},
+				},
+				unavailable: 2,
+			},
+			want: want{
+				message:  "hello",
+				requests: 3,
+			},
+		},
+		{
+			name: "再試行の回数の上限",
+			args: args{
+				method: "echo",
+				retryOption: &jsonrpc2.RetryOption{
+					MaxRetries:        1,
+					Interval:          time.Millisecond,
+					IdempotentMethods: []string{"echo"},
+				},
+				unavailable: 5,
+			},
+			want: want{
+				code:     http.StatusServiceUnavailable,
+				err:      true,
+				requests: 2,
+			},
+		},
+		{
+			name: "冪等でないメソッドは再試行しない",
+			args: args{
+				method: "echo",
+				retryOption: &jsonrpc2.RetryOption{
+					MaxRetries: 3,
+					Interval:   time.Millisecond,
+				},
+				unavailable: 1,
+			},
+			want: want{
+				code:     http.StatusServiceUnavailable,
+				err:      true,
+				requests: 1,
+			},
+		},
+		{
+			name: "context の期限でタイムアウトする",
+			args: args{
+				method: "slow",
+				retryOption: &jsonrpc2.RetryOption{
+					MaxRetries:        3,
+					Interval:          time.Millisecond,
+					IdempotentMethods: []string{"slow"},
+				},
+				timeout: 50 * time.Millisecond,
+			},
+			want: want{
+				err:      true,
+				requests: 1,
+			},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newEchoHandler()
+			h.Register("forbidden", jsonrpc2.NewAction(func(ctx context.Context, params *echoParams) (*echoResult, error) {
+				return nil, errcode.Set(errors.New("forbidden"), http.StatusForbidden)
+			}))
+			h.Register("slow", jsonrpc2.NewAction(func(ctx context.Context, params *echoParams) (*echoResult, error) {
+				select {
+				case <-time.After(time.Second):
+				case <-ctx.Done():
+				}
+				return &echoResult{params.Message}, nil
+			}))
+
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if requests.Add(1) <= tc.args.unavailable {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				h.Handle(w, r)
+			}))
+			defer server.Close()
+
+			ctx := context.Background()
+			if tc.args.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tc.args.timeout)
+				defer cancel()
+			}
+
+			c := jsonrpc2.NewClient(server.URL, nil)
+			c.SetRetryOption(tc.args.retryOption)
+			res, err := jsonrpc2.Call[echoResult](ctx, c, tc.args.method, &echoParams{"hello"})
+
+			if (err != nil) != tc.want.err {
+				t.Fatalf("got: %v, want: %v", err, tc.want.err)
+			}
+			if err == nil && res.Message != tc.want.message {
+				t.Errorf("got: %v, want: %v", res.Message, tc.want.message)
+			}
+			if tc.want.code != 0 {
+				code, _ := errcode.Get(err)
+				if code != tc.want.code {
+					t.Errorf("got: %v, want: %v", code, tc.want.code)
+				}
+			}
+			if got := requests.Load(); got != tc.want.requests {
+				t.Errorf("got: %v, want: %v", got, tc.want.requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"golang.org/x/net/websocket"
+)
+
+// JSON-RPC のメッセージを送受信する持続的な接続
+type Conn interface {
+	// メッセージを 1 つ受信する。接続が閉じられた場合は io.EOF を返す
+	Read(ctx context.Context) ([]byte, error)
+	// メッセージを 1 つ送信する
+	Write(ctx context.Context, data []byte) error
+	Close() error
+}
+
+// WebSocket の接続を Conn にする
+func NewWebSocketConn(ws *websocket.Conn) Conn {
+	ws.PayloadType = websocket.TextFrame
+	return &webSocketConn{ws}
+}
+
+type webSocketConn struct {
+	ws *websocket.Conn
+}
+
+func (c *webSocketConn) Read(ctx context.Context) ([]byte, error) {
+	var data []byte
+	if err := websocket.Message.Receive(c.ws, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (c *webSocketConn) Write(ctx context.Context, data []byte) error {
+	deadline, _ := ctx.Deadline()
+	if err := c.ws.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+	return websocket.Message.Send(c.ws, string(data))
+}
+
+func (c *webSocketConn) Close() error {
+	return c.ws.Close()
+}
+
+// メモリ上で接続された 2 つの Conn を作成する。テストで使用する
+func NewPipe() (Conn, Conn) {
+	p := &pipe{
+		done: make(chan struct{}),
+	}
+	a := make(chan []byte)
+	b := make(chan []byte)
+	return &pipeConn{p, a, b}, &pipeConn{p, b, a}
+}
+
+// 両端で共有する接続の状態
+type pipe struct {
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type pipeConn struct {
+	pipe  *pipe
+	read  chan []byte
+	write chan []byte
+}
+
+func (c *pipeConn) Read(ctx context.Context) ([]byte, error) {
+	select {
+	case data := <-c.read:
+		return data, nil
+	case <-c.pipe.done:
+		return nil, io.EOF
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *pipeConn) Write(ctx context.Context, data []byte) error {
+	select {
+	case c.write <- append([]byte{}, data...):
+		return nil
+	case <-c.pipe.done:
+		return errors.New("write on closed pipe")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *pipeConn) Close() error {
+	c.pipe.closeOnce.Do(func() {
+		close(c.pipe.done)
+	})
+	return nil
+}
@@ -0,0 +1,253 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rabee-inc/go-pkg/log"
+	"golang.org/x/net/websocket"
+)
+
+// クライアントが閉じられた
+var ErrClientClosed = errors.New("jsonrpc2: client closed")
+
+const (
+	defaultReconnectInterval    = 1 * time.Second
+	defaultMaxReconnectInterval = 30 * time.Second
+)
+
+// 持続的な接続のクライアントの設定
+type ConnClientOption struct {
+	// サーバーからのリクエスト、通知を処理する。 nil の場合、リクエストにはエラーを返し、通知は無視する
+	Handler *Handler
+	// 再接続の間隔。失敗するごとに 2 倍にする。 0 の場合は 1 秒
+	ReconnectInterval time.Duration
+	// 再接続の間隔の上限。 0 の場合は 30 秒
+	MaxReconnectInterval time.Duration
+	// 接続 (再接続を含む) 時に呼ばれる。
+	// 受信を開始する前に呼ばれるため、 Call でレスポンスを待つ場合は別の goroutine で行う
+	OnConnect func(ctx context.Context, s *Session)
+}
+
+// 持続的な接続で JSON-RPC のリクエストを行うクライアント。切断された場合は自動で再接続する
+type ConnClient struct {
+	dial   func(ctx context.Context) (Conn, error)
+	option *ConnClientOption
+
+	mutex   sync.Mutex
+	session *Session
+	// 接続すると close され、新しいものに置き換えられる
+	connected chan struct{}
+	closed    bool
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// dial で接続するクライアントを作成する。最初の接続に失敗した場合はエラーを返す
+func NewConnClient(ctx context.Context, dial func(ctx context.Context) (Conn, error), opt *ConnClientOption) (*ConnClient, error) {
+	if opt == nil {
+		opt = &ConnClientOption{}
+	}
+	if opt.ReconnectInterval <= 0 {
+		opt.ReconnectInterval = defaultReconnectInterval
+	}
+	if opt.MaxReconnectInterval <= 0 {
+		opt.MaxReconnectInterval = defaultMaxReconnectInterval
+	}
+
+	conn, err := dial(ctx)
+	if err != nil {
+		log.Warning(ctx, err)
+		return nil, err
+	}
+
+	// 接続はクライアントを閉じるまで維持するため、呼び出し元のキャンセルは引き継がない
+	cctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	c := &ConnClient{
+		dial:      dial,
+		option:    opt,
+		connected: make(chan struct{}),
+		ctx:       cctx,
+		cancel:    cancel,
+	}
+	c.connect(conn)
+	c.wg.Add(1)
+	go c.run()
+	return c, nil
+}
+
+// WebSocket で接続するクライアントを作成する
+func NewWebSocketClient(ctx context.Context, url string, headers map[string]string, opt *ConnClientOption) (*ConnClient, error) {
+	dial := func(ctx context.Context) (Conn, error) {
+		config, err := websocket.NewConfig(url, webSocketOrigin(url))
+		if err != nil {
+			return nil, err
+		}
+		config.Header = http.Header{}
+		for key, value := range headers {
+			config.Header.Set(key, value)
+		}
+		ws, err := config.DialContext(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return NewWebSocketConn(ws), nil
+	}
+	return NewConnClient(ctx, dial, opt)
+}
+
+// ws(s)://host/path を http(s)://host にする
+func webSocketOrigin(url string) string {
+	origin := url
+	if i := strings.Index(origin, "://"); i >= 0 {
+		if j := strings.Index(origin[i+3:], "/"); j >= 0 {
+			origin = origin[:i+3+j]
+		}
+	}
+	origin = strings.Replace(origin, "wss://", "https://", 1)
+	return strings.Replace(origin, "ws://", "http://", 1)
+}
+
+// リクエストを送信し、レスポンスを待つ。再接続中の場合は接続するまで待つ
+func (c *ConnClient) Call(ctx context.Context, method string, params any) (*json.RawMessage, *ErrorResponse, error) {
+	for {
+		s, err := c.getSession(ctx)
+		if err != nil {
+			log.Warning(ctx, err)
+			return nil, nil, err
+		}
+		result, rpcErr, err := s.Call(ctx, method, params)
+		// 切断により送信できなかった場合は再接続を待って再送する
+		if errors.Is(err, errSendFailed) {
+			continue
+		}
+		return result, rpcErr, err
+	}
+}
+
+// 通知を送信する。再接続中の場合は接続するまで待つ
+func (c *ConnClient) Notify(ctx context.Context, method string, params any) error {
+	for {
+		s, err := c.getSession(ctx)
+		if err != nil {
+			log.Warning(ctx, err)
+			return err
+		}
+		err = s.Notify(ctx, method, params)
+		if errors.Is(err, errSendFailed) {
+			continue
+		}
+		return err
+	}
+}
+
+// 接続を閉じ、再接続を停止する
+func (c *ConnClient) Close() error {
+	c.mutex.Lock()
+	c.closed = true
+	s := c.session
+	c.mutex.Unlock()
+
+	c.cancel()
+	var err error
+	if s != nil {
+		err = s.Close()
+	}
+	c.wg.Wait()
+	return err
+}
+
+func (c *ConnClient) getSession(ctx context.Context) (*Session, error) {
+	for {
+		c.mutex.Lock()
+		if c.closed {
+			c.mutex.Unlock()
+			return nil, ErrClientClosed
+		}
+		s := c.session
+		connected := c.connected
+		c.mutex.Unlock()
+
+		if s != nil {
+			select {
+			case <-s.Done():
+			default:
+				return s, nil
+			}
+		}
+
+		select {
+		case <-connected:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// クライアントが閉じられている場合は接続を閉じて false を返す
+func (c *ConnClient) connect(conn Conn) bool {
+	s := newSession(conn, c.option.Handler, nil)
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		conn.Close()
+		return false
+	}
+	c.session = s
+	close(c.connected)
+	c.connected = make(chan struct{})
+	c.mutex.Unlock()
+	if c.option.OnConnect != nil {
+		c.option.OnConnect(c.ctx, s)
+	}
+	return true
+}
+
+// 切断されるたびに再接続する
+func (c *ConnClient) run() {
+	defer c.wg.Done()
+	for {
+		c.mutex.Lock()
+		s := c.session
+		c.mutex.Unlock()
+
+		if err := s.serve(c.ctx); err != nil {
+			log.Debugf(c.ctx, "jsonrpc2 connection closed: %s", err.Error())
+		}
+
+		conn, ok := c.reconnect()
+		if !ok || !c.connect(conn) {
+			return
+		}
+	}
+}
+
+// 接続に成功するかクライアントが閉じられるまで、間隔を空けて再接続する
+func (c *ConnClient) reconnect() (Conn, bool) {
+	interval := c.option.ReconnectInterval
+	for {
+		select {
+		case <-time.After(interval):
+		case <-c.ctx.Done():
+			return nil, false
+		}
+
+		conn, err := c.dial(c.ctx)
+		if err == nil {
+			return conn, true
+		}
+		log.Warningf(c.ctx, "jsonrpc2 reconnect failed: %s", err.Error())
+
+		interval *= 2
+		if interval > c.option.MaxReconnectInterval {
+			interval = c.option.MaxReconnectInterval
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package jsonrpc2_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rabee-inc/go-pkg/jsonrpc2"
+	"golang.org/x/net/websocket"
+)
+
+type echoParams struct {
+	Message string `json:"message"`
+}
+
+type echoResult struct {
+	Message string `json:"message"`
+}
+
+// サーバーの Handler に接続する dial と、サーバー側の接続を返す
+func newPipeDial(h *jsonrpc2.Handler) (func(ctx context.Context) (jsonrpc2.Conn, error), func() []jsonrpc2.Conn) {
+	mutex := &sync.Mutex{}
+	serverConns := []jsonrpc2.Conn{}
+	dial := func(ctx context.Context) (jsonrpc2.Conn, error) {
+		client, server := jsonrpc2.NewPipe()
+		mutex.Lock()
+		serverConns = append(serverConns, server)
+		mutex.Unlock()
+		go h.ServeConn(context.Background(), server)
+		return client, nil
+	}
+	getServerConns := func() []jsonrpc2.Conn {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]jsonrpc2.Conn{}, serverConns...)
+	}
+	return dial, getServerConns
+}
+
+func newEchoHandler() *jsonrpc2.Handler {
+	h := jsonrpc2.NewHandler()
+	h.Register("echo", jsonrpc2.NewAction(func(ctx context.Context, params *echoParams) (*echoResult, error) {
+		return &echoResult{params.Message}, nil
+	}))
+	// クライアントに通知してから返す
+	h.Register("notify", jsonrpc2.NewAction(func(ctx context.Context, params *echoParams) (*echoResult, error) {
+		if err := jsonrpc2.GetSession(ctx).Notify(ctx, "pushed", params); err != nil {
+			return nil, err
+		}
+		return &echoResult{params.Message}, nil
+	}))
+	// クライアントにリクエストし、そのレスポンスを返す
+	h.Register("ask", jsonrpc2.NewAction(func(ctx context.Context, params *echoParams) (*echoResult, error) {
+		raw, rpcErr, err := jsonrpc2.GetSession(ctx).Call(ctx, "answer", params)
+		if err != nil {
+			return nil, err
+		}
+		if rpcErr != nil {
+			return nil, errors.New(rpcErr.Message)
+		}
+		var res echoResult
+		if err := json.Unmarshal(*raw, &res); err != nil {
+			return nil, err
+		}
+		return &res, nil
+	}))
+	return h
+}
+
+func callEcho(ctx context.Context, c *jsonrpc2.ConnClient, method string, message string) (string, error) {
+	raw, rpcErr, err := c.Call(ctx, method, &echoParams{message})
+	if err != nil {
+		return "", err
+	}
+	if rpcErr != nil {
+		return "", errors.New(rpcErr.Message)
+	}
+	var res echoResult
+	if err := json.Unmarshal(*raw, &res); err != nil {
+		return "", err
+	}
+	return res.Message, nil
+}
+
+func Test_ConnClient(t *testing.T) {
+	type args struct {
+		method  string
+		message string
+	}
+	type want struct {
+		message string
+		pushed  string
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "クライアントからのリクエスト",
+			args: args{
+				method:  "echo",
+				message: "hello",
+			},
+			want: want{
+				message: "hello",
+			},
+		},
+		{
+			name: "サーバーからの通知",
+			args: args{
+				method:  "notify",
+				message: "hello",
+			},
+			want: want{
+				message: "hello",
+				pushed:  "hello",
+			},
+		},
+		{
+			name: "サーバーからのリクエスト",
+			args: args{
+				method:  "ask",
+				message: "hello",
+			},
+			want: want{
+				message: "answer: hello",
+			},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+
+			pushed := make(chan string, 1)
+			clientHandler := jsonrpc2.NewHandler()
+			clientHandler.Register("pushed", jsonrpc2.NewAction(func(ctx context.Context, params *echoParams) (*struct{}, error) {
+				pushed <- params.Message
+				return &struct{}{}, nil
+			}))
+			clientHandler.Register("answer", jsonrpc2.NewAction(func(ctx context.Context, params *echoParams) (*echoResult, error) {
+				return &echoResult{"answer: " + params.Message}, nil
+			}))
+
+			dial, _ := newPipeDial(newEchoHandler())
+			c, err := jsonrpc2.NewConnClient(ctx, dial, &jsonrpc2.ConnClientOption{Handler: clientHandler})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+
+			message, err := callEcho(ctx, c, tc.args.method, tc.args.message)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if message != tc.want.message {
+				t.Errorf("got: %v, want: %v", message, tc.want.message)
+			}
+			if tc.want.pushed != "" {
+				select {
+				case got := <-pushed:
+					if got != tc.want.pushed {
+						t.Errorf("got: %v, want: %v", got, tc.want.pushed)
+					}
+				case <-ctx.Done():
+					t.Errorf("got: none, want: %v", tc.want.pushed)
+				}
+			}
+		})
+	}
+}
+
+func Test_ConnClient_Reconnect(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	dial, getServerConns := newPipeDial(newEchoHandler())
+	c, err := jsonrpc2.NewConnClient(ctx, dial, &jsonrpc2.ConnClientOption{ReconnectInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if _, err := callEcho(ctx, c, "echo", "before"); err != nil {
+		t.Fatal(err)
+	}
+
+	// サーバー側から切断する
+	getServerConns()[0].Close()
+
+	// 再接続を待って送信される
+	message, err := callEcho(ctx, c, "echo", "after")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message != "after" {
+		t.Errorf("got: %v, want: %v", message, "after")
+	}
+	if got := len(getServerConns()); got != 2 {
+		t.Errorf("got: %v, want: %v", got, 2)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := callEcho(ctx, c, "echo", "closed"); !errors.Is(err, jsonrpc2.ErrClientClosed) {
+		t.Errorf("got: %v, want: %v", err, jsonrpc2.ErrClientClosed)
+	}
+}
+
+func Test_Handler_HandleWebSocket(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	h := newEchoHandler()
+	h.EnableStrictMode()
+	server := httptest.NewServer(http.HandlerFunc(h.HandleWebSocket))
+	defer server.Close()
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	c, err := jsonrpc2.NewWebSocketClient(ctx, url, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	message, err := callEcho(ctx, c, "echo", "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message != "hello" {
+		t.Errorf("got: %v, want: %v", message, "hello")
+	}
+
+	// params が nil の場合は省略して送信する
+	_, rpcErr, err := c.Call(ctx, "echo", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rpcErr != nil {
+		t.Errorf("got: %v, want: %v", rpcErr, nil)
+	}
+
+	_, rpcErr, err = c.Call(ctx, "unknown", &echoParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rpcErr == nil || rpcErr.Code != jsonrpc2.ErrorCodeMethodNotFound {
+		t.Errorf("got: %v, want: %v", rpcErr, jsonrpc2.ErrorCodeMethodNotFound)
+	}
+}
+
+func Test_Handler_HandleWebSocket_CheckOrigin(t *testing.T) {
+	type args struct {
+		// 空文字の場合はサーバーと同じ Origin
+		origin      string
+		checkOrigin func(r *http.Request) bool
+	}
+	type want struct {
+		err bool
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "同じ Origin は許可する",
+			args: args{},
+			want: want{err: false},
+		},
+		{
+			name: "異なる Origin は拒否する",
+			args: args{
+				origin: "http://example.com",
+			},
+			want: want{err: true},
+		},
+		{
+			name: "AllowAllOrigins の場合は異なる Origin も許可する",
+			args: args{
+				origin:      "http://example.com",
+				checkOrigin: jsonrpc2.AllowAllOrigins,
+			},
+			want: want{err: false},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+			defer cancel()
+
+			h := newEchoHandler()
+			h.SetConnOption(&jsonrpc2.ConnOption{CheckOrigin: tc.args.checkOrigin})
+			server := httptest.NewServer(http.HandlerFunc(h.HandleWebSocket))
+			defer server.Close()
+
+			origin := tc.args.origin
+			if origin == "" {
+				origin = server.URL
+			}
+			config, err := websocket.NewConfig("ws"+strings.TrimPrefix(server.URL, "http"), origin)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ws, err := config.DialContext(ctx)
+			if err == nil {
+				ws.Close()
+			}
+			if (err != nil) != tc.want.err {
+				t.Errorf("got: %v, want: %v", err, tc.want.err)
+			}
+		})
+	}
+}
@@ -4,3 +4,22 @@ const (
 	contentType = "application/json"
 	version     = "2.0"
 )
+
+// JSON-RPC 2.0 で定義されているエラーコード (strict モードで使用する)
+// spec: https://www.jsonrpc.org/specification#error_object
+const (
+	ErrorCodeParseError     = -32700
+	ErrorCodeInvalidRequest = -32600
+	ErrorCodeMethodNotFound = -32601
+	ErrorCodeInvalidParams  = -32602
+	ErrorCodeInternalError  = -32603
+
+	// 実装定義のサーバーエラーに使用できる範囲。
+	// strict モードでは errcode に設定した HTTP の 4xx のステータスコードをこの範囲に変換する
+	ErrorCodeServerErrorMin = -32099
+	ErrorCodeServerErrorMax = -32000
+
+	// 仕様で予約されている範囲。アプリケーションのエラーコードはこの範囲外を使用する
+	errorCodeReservedMin = -32768
+	errorCodeReservedMax = -32000
+)
@@ -0,0 +1,67 @@
+package jsonrpc2
+
+import (
+	"context"
+	"sort"
+
+	"github.com/rabee-inc/go-pkg/rapi"
+)
+
+// 登録されているメソッドの一覧を返すメソッド名
+const DiscoverMethod = "rpc.discover"
+
+// 登録されているメソッドの定義
+type MethodDefinition struct {
+	Name string `json:"name"`
+	// ActionWithSchema でない場合は nil
+	ParamsTypeStructure *rapi.TypeStructure `json:"params_type_structure"`
+	// ActionWithSchema でない場合は nil
+	ResultTypeStructure *rapi.TypeStructure `json:"result_type_structure"`
+}
+
+// rpc.discover の結果
+type DiscoverResult struct {
+	Methods []*MethodDefinition            `json:"methods"`
+	Types   map[string]*rapi.TypeStructure `json:"types"`
+}
+
+// 登録されているメソッドと params, result の型定義を出力する
+func (h *Handler) GetMethodDefinitions() ([]*MethodDefinition, map[string]*rapi.TypeStructure) {
+	ts := rapi.NewTypeScanner()
+	ts.DisableStructField()
+	ts.AddStructTagName("json")
+
+	methods := make([]string, 0, len(h.actions))
+	for method := range h.actions {
+		// rpc.discover 自身は含めない
+		if method == DiscoverMethod {
+			continue
+		}
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	methodDefinitions := []*MethodDefinition{}
+	for _, method := range methods {
+		md := &MethodDefinition{
+			Name: method,
+		}
+		if action, ok := h.actions[method].(ActionWithSchema); ok {
+			md.ParamsTypeStructure = ts.Scan(action.GetEmptyParams())
+			md.ResultTypeStructure = ts.Scan(action.GetEmptyResult())
+		}
+		methodDefinitions = append(methodDefinitions, md)
+	}
+	return methodDefinitions, ts.Export()
+}
+
+// rpc.discover メソッドを登録する
+func (h *Handler) RegisterDiscover() {
+	h.Register(DiscoverMethod, NewAction(func(ctx context.Context, params *struct{}) (*DiscoverResult, error) {
+		methods, types := h.GetMethodDefinitions()
+		return &DiscoverResult{
+			Methods: methods,
+			Types:   types,
+		}, nil
+	}))
+}
@@ -0,0 +1,116 @@
+package jsonrpc2_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rabee-inc/go-pkg/jsonrpc2"
+)
+
+// 型情報を持たない Action
+type untypedAction struct{}
+
+func (a *untypedAction) DecodeParams(ctx context.Context, msg *json.RawMessage) (any, error) {
+	return nil, nil
+}
+
+func (a *untypedAction) Exec(ctx context.Context, method string, params any) (any, error) {
+	return nil, nil
+}
+
+func Test_Handler_RegisterDiscover(t *testing.T) {
+	h := jsonrpc2.NewHandler()
+	h.Register("echo", jsonrpc2.NewAction(func(ctx context.Context, params *echoParams) (*echoResult, error) {
+		return &echoResult{params.Message}, nil
+	}))
+	h.Register("untyped", &untypedAction{})
+	h.RegisterDiscover()
+
+	params := json.RawMessage(`{}`)
+	body, _ := json.Marshal([]*jsonrpc2.ClientRequest{{
+		Version: "2.0",
+		ID:      "1",
+		Method:  jsonrpc2.DiscoverMethod,
+		Params:  &params,
+	}})
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.Handle(w, r)
+
+	var responses []*jsonrpc2.ClientResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &responses); err != nil {
+		t.Fatal(err)
+	}
+	if len(responses) != 1 || responses[0].Error != nil || responses[0].Result == nil {
+		t.Fatalf("got: %s, want: result", w.Body.String())
+	}
+	var result jsonrpc2.DiscoverResult
+	if err := json.Unmarshal(*responses[0].Result, &result); err != nil {
+		t.Fatal(err)
+	}
+
+	type want struct {
+		name       string
+		params     string
+		result     string
+		hasSchemas bool
+	}
+	type testCase struct {
+		name string
+		want want
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "型付きの Action は params, result の型情報を出力する",
+			want: want{
+				name:       "echo",
+				params:     "jsonrpc2_test.echoParams",
+				result:     "jsonrpc2_test.echoResult",
+				hasSchemas: true,
+			},
+		},
+		{
+			name: "型情報を持たない Action は名前のみ出力する",
+			want: want{
+				name: "untyped",
+			},
+		},
+	}
+
+	// rpc.discover 自身は含めず、名前順で出力する
+	if len(result.Methods) != len(tcs) {
+		t.Fatalf("got: %v, want: %v", len(result.Methods), len(tcs))
+	}
+
+	// 実行
+	for i, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			md := result.Methods[i]
+			if md.Name != tc.want.name {
+				t.Errorf("got: %v, want: %v", md.Name, tc.want.name)
+			}
+			if (md.ParamsTypeStructure != nil) != tc.want.hasSchemas || (md.ResultTypeStructure != nil) != tc.want.hasSchemas {
+				t.Fatalf("got: %v, %v, want: %v", md.ParamsTypeStructure, md.ResultTypeStructure, tc.want.hasSchemas)
+			}
+			if !tc.want.hasSchemas {
+				return
+			}
+			if md.ParamsTypeStructure.Name != tc.want.params {
+				t.Errorf("got: %v, want: %v", md.ParamsTypeStructure.Name, tc.want.params)
+			}
+			if md.ResultTypeStructure.Name != tc.want.result {
+				t.Errorf("got: %v, want: %v", md.ResultTypeStructure.Name, tc.want.result)
+			}
+			if _, ok := result.Types[tc.want.params]; !ok {
+				t.Errorf("got: %v, want: %v", result.Types, tc.want.params)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package jsonrpc2
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -14,15 +15,33 @@ import (
 )
 
 type Handler struct {
-	actions map[string]Action
+	actions       map[string]Action
+	strict        bool
+	batchOption   *BatchOption
+	methodOptions map[string]*MethodOption
+	interceptors  []Interceptor
+	// メソッドごとの Interceptor
+	methodInterceptors map[string][]Interceptor
+	connOption         *ConnOption
 }
 
 func NewHandler() *Handler {
 	return &Handler{
-		map[string]Action{},
+		actions:       map[string]Action{},
+		batchOption:   &BatchOption{},
+		methodOptions: map[string]*MethodOption{},
+
+		methodInterceptors: map[string][]Interceptor{},
+		connOption:         &ConnOption{},
 	}
 }
 
+// JSON-RPC 2.0 の仕様に準拠したモードにする。
+// エラーコードに仕様のコードを使用し、数値と null の ID の受け付け、通知 (ID のないリクエスト) への応答の省略を行う
+func (h *Handler) EnableStrictMode() {
+	h.strict = true
+}
+
 // JSONRPC2のリクエストを登録する
 func (h *Handler) Register(method string, action Action) {
 	if method == "" || action == nil {
@@ -58,10 +77,20 @@ func (h *Handler) Handle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if h.strict {
+		h.handleStrict(ctx, w, r, data)
+		return
+	}
+
 	err = h.handleSingleRequest(ctx, w, r, data)
 	if err != nil {
 		err = h.handleBatchRequest(ctx, w, r, data)
 	}
+	if errors.Is(err, errBatchSizeExceeded) {
+		log.SetResponseStatus(ctx, http.StatusBadRequest)
+		h.renderError(ctx, w, http.StatusBadRequest, "%s", err.Error())
+		return
+	}
 	if err != nil {
 		log.SetResponseStatus(ctx, http.StatusBadRequest)
 		h.renderError(ctx, w, http.StatusBadRequest, "parse json error: %s", err.Error())
@@ -88,15 +117,22 @@ func (h *Handler) handleBatchRequest(ctx context.Context, w http.ResponseWriter,
 		return err
 	}
 
-	var responses []response
-	ch := make(chan response, len(reqs))
-	for _, req := range reqs {
-		go func(_req request) {
-			ch <- h.handleRequest(ctx, r, _req)
-		}(req)
+	if h.isBatchSizeExceeded(len(reqs)) {
+		return errBatchSizeExceeded
+	}
+
+	methods := make([]string, len(reqs))
+	for i, req := range reqs {
+		methods[i] = req.Method
 	}
-	for i := 0; i < len(reqs); i++ {
-		responses = append(responses, <-ch)
+	results := make([]response, len(reqs))
+	order := h.runBatch(methods, func(i int) {
+		results[i] = h.handleRequest(ctx, r, reqs[i])
+	})
+
+	var responses []response
+	for _, i := range order {
+		responses = append(responses, results[i])
 	}
 
 	encoder := json.NewEncoder(w)
@@ -113,12 +149,11 @@ func (h *Handler) handleRequest(ctx context.Context, r *http.Request, req reques
 		return h.renderErrorJSON(ctx, req.ID, http.StatusBadRequest, "method not found: %s", req.Method)
 	}
 
-	params, err := action.DecodeParams(ctx, req.Params)
-	if err != nil {
-		return h.renderErrorJSON(ctx, req.ID, http.StatusBadRequest, "invalid params: %s", err.Error())
+	result, err := h.execAction(ctx, req.Method, action, req.Params)
+	var ipErr *invalidParamsError
+	if errors.As(err, &ipErr) {
+		return h.renderErrorJSON(ctx, req.ID, http.StatusBadRequest, "invalid params: %s", ipErr.err.Error())
 	}
-
-	result, err := action.Exec(ctx, req.Method, params)
 	if err != nil {
 		code, ok := errcode.Get(err)
 		if !ok {
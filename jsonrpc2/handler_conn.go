@@ -0,0 +1,172 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/rabee-inc/go-pkg/log"
+	"golang.org/x/net/websocket"
+)
+
+// 持続的な接続 (WebSocket など) の設定
+type ConnOption struct {
+	// 接続時に呼ばれる。サーバーから通知を送る場合は Session を保持する。
+	// 受信を開始する前に呼ばれるため、 Call でレスポンスを待つ場合は別の goroutine で行う
+	OnConnect func(ctx context.Context, s *Session)
+	// 切断時に呼ばれる
+	OnDisconnect func(ctx context.Context, s *Session)
+	// WebSocket のハンドシェイク時に Origin などを検証する。
+	// nil の場合は Origin のホストがリクエストのホストと一致する場合 (または Origin がない場合) のみ許可する。
+	// すべての Origin を許可する場合は AllowAllOrigins を指定する
+	CheckOrigin func(r *http.Request) bool
+}
+
+// すべての Origin からの WebSocket の接続を許可する (ConnOption.CheckOrigin に指定する)
+func AllowAllOrigins(r *http.Request) bool {
+	return true
+}
+
+// Origin のホストがリクエストのホストと一致するか。
+// Origin はブラウザからのリクエストにのみ付与されるため、ない場合は許可する
+func isSameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+type contextKey string
+
+const sessionContextKey contextKey = "jsonrpc2:session"
+
+// 持続的な接続の設定をセットする
+func (h *Handler) SetConnOption(opt *ConnOption) {
+	if opt == nil {
+		opt = &ConnOption{}
+	}
+	h.connOption = opt
+}
+
+// リクエストを受信したセッションを取得する。 HTTP でのリクエストの場合は nil
+func GetSession(ctx context.Context) *Session {
+	if s, ok := ctx.Value(sessionContextKey).(*Session); ok {
+		return s
+	}
+	return nil
+}
+
+func setContextSession(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, sessionContextKey, s)
+}
+
+// WebSocket で接続し、登録されている Action を実行する
+func (h *Handler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	server := websocket.Server{
+		Handshake: func(config *websocket.Config, r *http.Request) error {
+			checkOrigin := h.connOption.CheckOrigin
+			if checkOrigin == nil {
+				checkOrigin = isSameOrigin
+			}
+			if !checkOrigin(r) {
+				return fmt.Errorf("invalid origin: %s", r.Header.Get("Origin"))
+			}
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			if err := h.serveConn(ctx, NewWebSocketConn(ws), r); err != nil {
+				log.Debugf(ctx, "websocket closed: %s", err.Error())
+			}
+		},
+	}
+	server.ServeHTTP(w, r)
+}
+
+// Conn で受信したリクエストを処理する。接続が閉じられるまでブロックする
+func (h *Handler) ServeConn(ctx context.Context, conn Conn) error {
+	return h.serveConn(ctx, conn, nil)
+}
+
+func (h *Handler) serveConn(ctx context.Context, conn Conn, r *http.Request) error {
+	s := newSession(conn, h, r)
+	ctx = setContextSession(ctx, s)
+	if h.connOption.OnConnect != nil {
+		h.connOption.OnConnect(ctx, s)
+	}
+	if h.connOption.OnDisconnect != nil {
+		defer h.connOption.OnDisconnect(ctx, s)
+	}
+	return s.serve(ctx)
+}
+
+// 持続的な接続で受信したメッセージを処理してレスポンスを返す。返すレスポンスがない場合は nil 。
+// HTTP と異なり、 ID のないリクエストは通知として扱う
+func (h *Handler) handleMessage(ctx context.Context, r *http.Request, data []byte) any {
+	if h.strict {
+		return h.handleStrictMessage(ctx, r, data)
+	}
+
+	if firstByte(data) == '[' {
+		var reqs []request
+		if err := json.Unmarshal(data, &reqs); err != nil {
+			return h.renderErrorJSON(ctx, "", http.StatusBadRequest, "parse json error: %s", err.Error())
+		}
+		if h.isBatchSizeExceeded(len(reqs)) {
+			return h.renderErrorJSON(ctx, "", http.StatusBadRequest, "%s", errBatchSizeExceeded.Error())
+		}
+		methods := make([]string, len(reqs))
+		for i, req := range reqs {
+			methods[i] = req.Method
+		}
+		results := make([]*response, len(reqs))
+		order := h.runBatch(methods, func(i int) {
+			results[i] = h.handleMessageRequest(ctx, r, reqs[i])
+		})
+		responses := []*response{}
+		for _, i := range order {
+			if results[i] != nil {
+				responses = append(responses, results[i])
+			}
+		}
+		if len(responses) == 0 {
+			return nil
+		}
+		return responses
+	}
+
+	var req request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return h.renderErrorJSON(ctx, "", http.StatusBadRequest, "parse json error: %s", err.Error())
+	}
+	if res := h.handleMessageRequest(ctx, r, req); res != nil {
+		return res
+	}
+	return nil
+}
+
+// 通知の場合は実行のみ行い nil を返す
+func (h *Handler) handleMessageRequest(ctx context.Context, r *http.Request, req request) *response {
+	if req.ID != "" || req.Method == "" {
+		res := h.handleRequest(ctx, r, req)
+		return &res
+	}
+
+	action := h.actions[req.Method]
+	if action == nil {
+		log.Warningf(ctx, "method not found: %s", req.Method)
+		return nil
+	}
+	if _, err := h.execAction(ctx, req.Method, action, req.Params); err != nil {
+		log.Warningf(ctx, "notification %s: %s", req.Method, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/rabee-inc/go-pkg/errcode"
+	"github.com/rabee-inc/go-pkg/log"
+)
+
+// strict モードのリクエスト。 ID は文字列、数値、 null を受け付け、未指定の場合は通知として扱う
+type strictRequest struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+// 通知の場合は id が存在しない (null の場合は通知ではない)
+func (r *strictRequest) isNotification() bool {
+	return r.ID == nil
+}
+
+func (r *strictRequest) validate() error {
+	if r.Version != version {
+		return errors.New("jsonrpc must be exactly \"2.0\"")
+	}
+	if r.Method == "" {
+		return errors.New("method is required")
+	}
+	if r.ID != nil && !isValidID(r.ID) {
+		return errors.New("id must be a string, number or null")
+	}
+	if r.Params != nil {
+		switch firstByte(r.Params) {
+		case '{', '[':
+		default:
+			return errors.New("params must be an object or array")
+		}
+	}
+	return nil
+}
+
+func isValidID(id json.RawMessage) bool {
+	switch c := firstByte(id); {
+	case c == '"', c == 'n', c == '-', '0' <= c && c <= '9':
+		return true
+	default:
+		return false
+	}
+}
+
+func firstByte(b []byte) byte {
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 {
+		return 0
+	}
+	return b[0]
+}
+
+// strict モードのレスポンス。成功時は result が null でも出力する
+type strictResponse struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *ErrorResponse  `json:"error,omitempty"`
+}
+
+func newStrictErrorResponse(id json.RawMessage, code int, message string, data any) *strictResponse {
+	return &strictResponse{
+		Version: version,
+		ID:      id,
+		Error: &ErrorResponse{
+			Code:    code,
+			Message: message,
+			Data:    data,
+		},
+	}
+}
+
+func (h *Handler) handleStrict(ctx context.Context, w http.ResponseWriter, r *http.Request, data []byte) {
+	res := h.handleStrictMessage(ctx, r, data)
+	if res == nil {
+		log.SetResponseStatus(ctx, http.StatusNoContent)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	log.SetResponseStatus(ctx, http.StatusOK)
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		log.Error(ctx, err)
+	}
+}
+
+// リクエストを処理してレスポンスを返す。返すレスポンスがない場合は nil
+func (h *Handler) handleStrictMessage(ctx context.Context, r *http.Request, data []byte) any {
+	var res any
+	switch firstByte(data) {
+	case '[':
+		var rawReqs []json.RawMessage
+		if err := json.Unmarshal(data, &rawReqs); err != nil {
+			log.Warningf(ctx, "parse json error: %s", err.Error())
+			res = newStrictErrorResponse(nil, ErrorCodeParseError, "Parse error", err.Error())
+			break
+		}
+		// 空のバッチは配列ではなく単一のエラーを返す
+		if len(rawReqs) == 0 {
+			log.Warningf(ctx, "empty batch request")
+			res = newStrictErrorResponse(nil, ErrorCodeInvalidRequest, "Invalid Request", "empty batch")
+			break
+		}
+		if h.isBatchSizeExceeded(len(rawReqs)) {
+			log.Warningf(ctx, "%s: %d", errBatchSizeExceeded.Error(), len(rawReqs))
+			res = newStrictErrorResponse(nil, ErrorCodeInvalidRequest, "Invalid Request", errBatchSizeExceeded.Error())
+			break
+		}
+		responses := h.handleStrictBatchRequest(ctx, r, rawReqs)
+		// すべて通知の場合は何も返さない
+		if len(responses) > 0 {
+			res = responses
+		}
+	default:
+		if !json.Valid(data) {
+			log.Warningf(ctx, "parse json error: invalid json")
+			res = newStrictErrorResponse(nil, ErrorCodeParseError, "Parse error", nil)
+			break
+		}
+		if sRes := h.handleStrictRawRequest(ctx, r, data); sRes != nil {
+			res = sRes
+		}
+	}
+	return res
+}
+
+func (h *Handler) handleStrictBatchRequest(ctx context.Context, r *http.Request, rawReqs []json.RawMessage) []*strictResponse {
+	reqs := make([]*strictRequest, len(rawReqs))
+	results := make([]*strictResponse, len(rawReqs))
+	methods := make([]string, len(rawReqs))
+	for i, rawReq := range rawReqs {
+		reqs[i], results[i] = h.parseStrictRequest(ctx, rawReq)
+		if reqs[i] != nil {
+			methods[i] = reqs[i].Method
+		}
+	}
+
+	order := h.runBatch(methods, func(i int) {
+		if reqs[i] == nil {
+			return
+		}
+		results[i] = h.handleStrictRequest(ctx, r, reqs[i])
+	})
+
+	responses := []*strictResponse{}
+	for _, i := range order {
+		// 通知にはレスポンスを返さない
+		if reqs[i] != nil && reqs[i].isNotification() {
+			continue
+		}
+		responses = append(responses, results[i])
+	}
+	return responses
+}
+
+// 通知の場合は nil を返す
+func (h *Handler) handleStrictRawRequest(ctx context.Context, r *http.Request, data json.RawMessage) *strictResponse {
+	req, errRes := h.parseStrictRequest(ctx, data)
+	if errRes != nil {
+		return errRes
+	}
+	res := h.handleStrictRequest(ctx, r, req)
+	if req.isNotification() {
+		return nil
+	}
+	return res
+}
+
+// 不正なリクエストの場合はエラーレスポンスを返す
+func (h *Handler) parseStrictRequest(ctx context.Context, data json.RawMessage) (*strictRequest, *strictResponse) {
+	var req strictRequest
+	if firstByte(data) != '{' {
+		log.Warningf(ctx, "invalid jsonrpc2 request: %s", string(data))
+		return nil, newStrictErrorResponse(nil, ErrorCodeInvalidRequest, "Invalid Request", "request must be an object")
+	}
+	if err := json.Unmarshal(data, &req); err != nil {
+		log.Warningf(ctx, "invalid jsonrpc2 request: %s", err.Error())
+		return nil, newStrictErrorResponse(nil, ErrorCodeInvalidRequest, "Invalid Request", err.Error())
+	}
+	if err := req.validate(); err != nil {
+		log.Warningf(ctx, "invalid jsonrpc2 request: %s", err.Error())
+		id := req.ID
+		if !isValidID(id) {
+			id = nil
+		}
+		return nil, newStrictErrorResponse(id, ErrorCodeInvalidRequest, "Invalid Request", err.Error())
+	}
+	return &req, nil
+}
+
+func (h *Handler) handleStrictRequest(ctx context.Context, r *http.Request, req *strictRequest) *strictResponse {
+	action := h.actions[req.Method]
+	if action == nil {
+		log.Warningf(ctx, "method not found: %s", req.Method)
+		return newStrictErrorResponse(req.ID, ErrorCodeMethodNotFound, "Method not found", req.Method)
+	}
+
+	var rawParams *json.RawMessage
+	if req.Params != nil {
+		rawParams = &req.Params
+	}
+	result, err := h.execAction(ctx, req.Method, action, rawParams)
+	var ipErr *invalidParamsError
+	if errors.As(err, &ipErr) {
+		log.Warningf(ctx, "invalid params: %s", ipErr.err.Error())
+		return newStrictErrorResponse(req.ID, ErrorCodeInvalidParams, "Invalid params", ipErr.err.Error())
+	}
+	if err != nil {
+		code := strictErrorCode(err)
+		if code == ErrorCodeInternalError {
+			// 内部エラーの詳細はクライアントに返さない
+			log.Errorf(ctx, "%s", err.Error())
+			return newStrictErrorResponse(req.ID, code, "Internal error", nil)
+		}
+		log.Warningf(ctx, "%d %s", code, err.Error())
+		return newStrictErrorResponse(req.ID, code, err.Error(), nil)
+	}
+
+	bResult, err := json.Marshal(result)
+	if err != nil {
+		log.Error(ctx, err)
+		return newStrictErrorResponse(req.ID, ErrorCodeInternalError, "Internal error", nil)
+	}
+	return &strictResponse{
+		Version: version,
+		ID:      req.ID,
+		Result:  bResult,
+	}
+}
+
+// errcode のコードをアプリケーションのエラーコードとして使用する。
+// HTTP のステータスコード (100-599) の場合、 4xx は実装定義のサーバーエラーの範囲に変換し
+// (400 -> -32000, 404 -> -32004, ..., 499 -> -32099) 、それ以外は Internal error とする。
+// errcode がない場合、または仕様で予約された範囲の未定義のコードの場合も Internal error とする
+func strictErrorCode(err error) int {
+	code, ok := errcode.Get(err)
+	if !ok {
+		return ErrorCodeInternalError
+	}
+	switch {
+	case code == ErrorCodeParseError,
+		code == ErrorCodeInvalidRequest,
+		code == ErrorCodeMethodNotFound,
+		code == ErrorCodeInvalidParams,
+		code == ErrorCodeInternalError,
+		ErrorCodeServerErrorMin <= code && code <= ErrorCodeServerErrorMax:
+		return code
+	case errorCodeReservedMin <= code && code <= errorCodeReservedMax:
+		return ErrorCodeInternalError
+	case http.StatusBadRequest <= code && code < http.StatusInternalServerError:
+		return ErrorCodeServerErrorMax - (code - http.StatusBadRequest)
+	case http.StatusContinue <= code && code <= 599:
+		return ErrorCodeInternalError
+	default:
+		return code
+	}
+}
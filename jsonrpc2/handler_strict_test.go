@@ -0,0 +1,225 @@
+package jsonrpc2_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rabee-inc/go-pkg/errcode"
+	"github.com/rabee-inc/go-pkg/jsonrpc2"
+)
+
+type failParams struct {
+	Code int `json:"code"`
+}
+
+// レスポンスを比較しやすい文字列に変換する
+func summarizeStrictResponse(t *testing.T, raw json.RawMessage) string {
+	t.Helper()
+	var res struct {
+		ID     json.RawMessage         `json:"id"`
+		Result json.RawMessage         `json:"result"`
+		Error  *jsonrpc2.ErrorResponse `json:"error"`
+	}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Error != nil {
+		return fmt.Sprintf("id=%s code=%d message=%s", res.ID, res.Error.Code, res.Error.Message)
+	}
+	return fmt.Sprintf("id=%s result=%s", res.ID, res.Result)
+}
+
+func Test_Handler_Strict(t *testing.T) {
+	type args struct {
+		body string
+	}
+	type want struct {
+		status    int
+		responses []string
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	h := jsonrpc2.NewHandler()
+	h.EnableStrictMode()
+	h.Register("echo", jsonrpc2.NewAction(func(ctx context.Context, params *echoParams) (*echoResult, error) {
+		return &echoResult{params.Message}, nil
+	}))
+	h.Register("fail", jsonrpc2.NewAction(func(ctx context.Context, params *failParams) (*echoResult, error) {
+		err := errors.New("secret error")
+		if params.Code == 0 {
+			return nil, err
+		}
+		return nil, errcode.Set(err, params.Code)
+	}))
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "文字列の id",
+			args: args{body: `{"jsonrpc":"2.0","id":"a","method":"echo","params":{"message":"hello"}}`},
+			want: want{
+				status:    http.StatusOK,
+				responses: []string{`id="a" result={"message":"hello"}`},
+			},
+		},
+		{
+			name: "数値の id",
+			args: args{body: `{"jsonrpc":"2.0","id":1,"method":"echo","params":{"message":"hello"}}`},
+			want: want{
+				status:    http.StatusOK,
+				responses: []string{`id=1 result={"message":"hello"}`},
+			},
+		},
+		{
+			name: "null の id は通知として扱わない",
+			args: args{body: `{"jsonrpc":"2.0","id":null,"method":"echo","params":{"message":"hello"}}`},
+			want: want{
+				status:    http.StatusOK,
+				responses: []string{`id=null result={"message":"hello"}`},
+			},
+		},
+		{
+			name: "通知にはレスポンスを返さない",
+			args: args{body: `{"jsonrpc":"2.0","method":"echo","params":{"message":"hello"}}`},
+			want: want{
+				status: http.StatusNoContent,
+			},
+		},
+		{
+			name: "Parse error",
+			args: args{body: `{"jsonrpc":"2.0",`},
+			want: want{
+				status:    http.StatusOK,
+				responses: []string{`id=null code=-32700 message=Parse error`},
+			},
+		},
+		{
+			name: "Invalid Request",
+			args: args{body: `{"jsonrpc":"1.0","id":1,"method":"echo"}`},
+			want: want{
+				status:    http.StatusOK,
+				responses: []string{`id=1 code=-32600 message=Invalid Request`},
+			},
+		},
+		{
+			name: "Method not found",
+			args: args{body: `{"jsonrpc":"2.0","id":1,"method":"unknown"}`},
+			want: want{
+				status:    http.StatusOK,
+				responses: []string{`id=1 code=-32601 message=Method not found`},
+			},
+		},
+		{
+			name: "Invalid params",
+			args: args{body: `{"jsonrpc":"2.0","id":1,"method":"echo","params":{"message":1}}`},
+			want: want{
+				status:    http.StatusOK,
+				responses: []string{`id=1 code=-32602 message=Invalid params`},
+			},
+		},
+		{
+			name: "HTTP の 4xx はサーバーエラーの範囲に変換する",
+			args: args{body: `{"jsonrpc":"2.0","id":1,"method":"fail","params":{"code":404}}`},
+			want: want{
+				status:    http.StatusOK,
+				responses: []string{`id=1 code=-32004 message=secret error`},
+			},
+		},
+		{
+			name: "HTTP の 5xx は Internal error としてメッセージを返さない",
+			args: args{body: `{"jsonrpc":"2.0","id":1,"method":"fail","params":{"code":503}}`},
+			want: want{
+				status:    http.StatusOK,
+				responses: []string{`id=1 code=-32603 message=Internal error`},
+			},
+		},
+		{
+			name: "errcode がない場合は Internal error としてメッセージを返さない",
+			args: args{body: `{"jsonrpc":"2.0","id":1,"method":"fail","params":{}}`},
+			want: want{
+				status:    http.StatusOK,
+				responses: []string{`id=1 code=-32603 message=Internal error`},
+			},
+		},
+		{
+			name: "アプリケーションのエラーコードはそのまま返す",
+			args: args{body: `{"jsonrpc":"2.0","id":1,"method":"fail","params":{"code":1001}}`},
+			want: want{
+				status:    http.StatusOK,
+				responses: []string{`id=1 code=1001 message=secret error`},
+			},
+		},
+		{
+			name: "空のバッチ",
+			args: args{body: `[]`},
+			want: want{
+				status:    http.StatusOK,
+				responses: []string{`id=null code=-32600 message=Invalid Request`},
+			},
+		},
+		{
+			name: "すべて通知のバッチ",
+			args: args{body: `[{"jsonrpc":"2.0","method":"echo","params":{"message":"a"}},{"jsonrpc":"2.0","method":"echo","params":{"message":"b"}}]`},
+			want: want{
+				status: http.StatusNoContent,
+			},
+		},
+		{
+			name: "バッチの通知と不正なリクエスト",
+			args: args{body: `[{"jsonrpc":"2.0","id":1,"method":"echo","params":{"message":"a"}},{"jsonrpc":"2.0","method":"echo","params":{"message":"b"}},1]`},
+			want: want{
+				status: http.StatusOK,
+				responses: []string{
+					`id=1 result={"message":"a"}`,
+					`id=null code=-32600 message=Invalid Request`,
+				},
+			},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte(tc.args.body)))
+			r.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			h.Handle(w, r)
+
+			if w.Code != tc.want.status {
+				t.Fatalf("got: %v, want: %v", w.Code, tc.want.status)
+			}
+			if tc.want.status == http.StatusNoContent {
+				if w.Body.Len() != 0 {
+					t.Errorf("got: %v, want: empty", w.Body.String())
+				}
+				return
+			}
+
+			raws := []json.RawMessage{}
+			if bytes.HasPrefix(w.Body.Bytes(), []byte("[")) {
+				if err := json.Unmarshal(w.Body.Bytes(), &raws); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				raws = append(raws, w.Body.Bytes())
+			}
+			responses := []string{}
+			for _, raw := range raws {
+				responses = append(responses, summarizeStrictResponse(t, raw))
+			}
+			if fmt.Sprint(responses) != fmt.Sprint(tc.want.responses) {
+				t.Errorf("got: %v, want: %v", responses, tc.want.responses)
+			}
+		})
+	}
+}
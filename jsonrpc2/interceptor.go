@@ -0,0 +1,80 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/rabee-inc/go-pkg/errcode"
+	"github.com/rabee-inc/go-pkg/log"
+)
+
+// デコード済みの params で Action を実行する関数
+type Invoker func(ctx context.Context, method string, params any) (any, error)
+
+// Action の実行をラップする処理。
+// params はデコード済みの値で、 invoker を呼び出すと後続の Interceptor と Action が実行され、 result と error を受け取れる。
+// params のデコードに失敗した場合は実行されない
+type Interceptor func(ctx context.Context, method string, params any, invoker Invoker) (any, error)
+
+// params のデコードに失敗したエラー
+type invalidParamsError struct {
+	err error
+}
+
+func (e *invalidParamsError) Error() string {
+	return fmt.Sprintf("invalid params: %s", e.err.Error())
+}
+
+func (e *invalidParamsError) Unwrap() error {
+	return e.err
+}
+
+// 全てのメソッドに Interceptor を追加する。先に追加したものほど外側で実行される
+func (h *Handler) Use(interceptors ...Interceptor) {
+	h.interceptors = append(h.interceptors, interceptors...)
+}
+
+// 指定したメソッドに Interceptor を追加する。全てのメソッドの Interceptor より内側で実行される
+func (h *Handler) UseMethod(method string, interceptors ...Interceptor) {
+	h.methodInterceptors[method] = append(h.methodInterceptors[method], interceptors...)
+}
+
+// params をデコードし、 Interceptor を通して Action を実行する。
+// デコードに失敗した場合は Interceptor を実行せずに invalidParamsError を返す
+func (h *Handler) execAction(ctx context.Context, method string, action Action, rawParams *json.RawMessage) (any, error) {
+	params, err := action.DecodeParams(ctx, rawParams)
+	if err != nil {
+		return nil, &invalidParamsError{err}
+	}
+	invoker := func(ctx context.Context, method string, params any) (any, error) {
+		return h.execActionWithOption(ctx, method, action, params)
+	}
+	interceptors := append(append([]Interceptor{}, h.interceptors...), h.methodInterceptors[method]...)
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		invoker = chainInterceptor(interceptors[i], invoker)
+	}
+	return invoker(ctx, method, params)
+}
+
+func chainInterceptor(interceptor Interceptor, next Invoker) Invoker {
+	return func(ctx context.Context, method string, params any) (any, error) {
+		return interceptor(ctx, method, params, next)
+	}
+}
+
+// panic を 500 のエラーに変換する Interceptor 。バッチリクエストの 1 つのリクエストの panic で全体が落ちることを防ぐ
+func RecoverInterceptor() Interceptor {
+	return func(ctx context.Context, method string, params any, invoker Invoker) (result any, err error) {
+		defer func() {
+			if rcvr := recover(); rcvr != nil {
+				// スタックトレースはログにのみ出力する
+				log.Panic(ctx, rcvr)
+				result = nil
+				err = errcode.Set(fmt.Errorf("panic in method %s: %v", method, rcvr), http.StatusInternalServerError)
+			}
+		}()
+		return invoker(ctx, method, params)
+	}
+}
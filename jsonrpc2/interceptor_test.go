@@ -0,0 +1,214 @@
+package jsonrpc2_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/rabee-inc/go-pkg/errcode"
+	"github.com/rabee-inc/go-pkg/jsonrpc2"
+)
+
+func Test_Handler_Interceptor(t *testing.T) {
+	type args struct {
+		methods []string
+		sleeps  []int
+	}
+	type want struct {
+		calls    []string
+		errorIDs []string
+	}
+	type testCase struct {
+		name         string
+		args         args
+		interceptors map[string][]jsonrpc2.Interceptor
+		want         want
+	}
+
+	// 呼び出しを記録する Interceptor
+	var calls []string
+	record := func(name string) jsonrpc2.Interceptor {
+		return func(ctx context.Context, method string, params any, invoker jsonrpc2.Invoker) (any, error) {
+			calls = append(calls, fmt.Sprintf("%s:before:%s:%d", name, method, params.(*sleepParams).Milliseconds))
+			result, err := invoker(ctx, method, params)
+			if err != nil {
+				calls = append(calls, fmt.Sprintf("%s:after:%s:error", name, method))
+			} else {
+				calls = append(calls, fmt.Sprintf("%s:after:%s:%d", name, method, result.(*sleepResult).Milliseconds))
+			}
+			return result, err
+		}
+	}
+	// 実行せずにエラーを返す Interceptor
+	deny := func(ctx context.Context, method string, params any, invoker jsonrpc2.Invoker) (any, error) {
+		return nil, errcode.Set(errors.New("forbidden"), http.StatusForbidden)
+	}
+
+	// デコード済みの params を検証してエラーを返す Interceptor
+	denyOver := func(ctx context.Context, method string, params any, invoker jsonrpc2.Invoker) (any, error) {
+		if params.(*sleepParams).Milliseconds > 1 {
+			return nil, errcode.Set(errors.New("too long"), http.StatusBadRequest)
+		}
+		return invoker(ctx, method, params)
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "全体の Interceptor の内側でメソッドの Interceptor が実行される",
+			args: args{
+				methods: []string{"fast"},
+				sleeps:  []int{1},
+			},
+			interceptors: map[string][]jsonrpc2.Interceptor{
+				"":     {record("global1"), record("global2")},
+				"fast": {record("method")},
+			},
+			want: want{
+				calls: []string{
+					"global1:before:fast:1",
+					"global2:before:fast:1",
+					"method:before:fast:1",
+					"method:after:fast:1",
+					"global2:after:fast:1",
+					"global1:after:fast:1",
+				},
+				errorIDs: []string{},
+			},
+		},
+		{
+			name: "メソッドの Interceptor は他のメソッドでは実行されない",
+			args: args{
+				methods: []string{"fast", "panic"},
+				sleeps:  []int{1, 2},
+			},
+			interceptors: map[string][]jsonrpc2.Interceptor{
+				"":      {jsonrpc2.RecoverInterceptor()},
+				"panic": {record("method")},
+			},
+			want: want{
+				calls: []string{
+					"method:before:panic:2",
+				},
+				errorIDs: []string{"1"},
+			},
+		},
+		{
+			name: "Interceptor でエラーを返すと Action は実行されない",
+			args: args{
+				methods: []string{"fast", "panic"},
+				sleeps:  []int{1, 2},
+			},
+			interceptors: map[string][]jsonrpc2.Interceptor{
+				"":      {record("global")},
+				"panic": {deny},
+			},
+			want: want{
+				calls: []string{
+					"global:before:fast:1",
+					"global:after:fast:1",
+					"global:before:panic:2",
+					"global:after:panic:error",
+				},
+				errorIDs: []string{"1"},
+			},
+		},
+		{
+			name: "デコード済みの params を Interceptor で検証する",
+			args: args{
+				methods: []string{"fast", "fast"},
+				sleeps:  []int{1, 2},
+			},
+			interceptors: map[string][]jsonrpc2.Interceptor{
+				"":     {record("global")},
+				"fast": {denyOver},
+			},
+			want: want{
+				calls: []string{
+					"global:before:fast:1",
+					"global:after:fast:1",
+					"global:before:fast:2",
+					"global:after:fast:error",
+				},
+				errorIDs: []string{"1"},
+			},
+		},
+		{
+			name: "params のデコードに失敗した場合は Interceptor を実行しない",
+			args: args{
+				methods: []string{"invalid", "fast"},
+				sleeps:  []int{1, 2},
+			},
+			interceptors: map[string][]jsonrpc2.Interceptor{
+				"": {record("global")},
+			},
+			want: want{
+				calls: []string{
+					"global:before:fast:2",
+					"global:after:fast:2",
+				},
+				errorIDs: []string{"0"},
+			},
+		},
+		{
+			name: "バッチ内の panic はそのリクエストのみエラーになる",
+			args: args{
+				methods: []string{"panic", "fast", "fast"},
+				sleeps:  []int{0, 1, 2},
+			},
+			interceptors: map[string][]jsonrpc2.Interceptor{
+				"": {jsonrpc2.RecoverInterceptor()},
+			},
+			want: want{
+				calls:    []string{},
+				errorIDs: []string{"0"},
+			},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			calls = []string{}
+			h := jsonrpc2.NewHandler()
+			h.Register("fast", newSleepAction(&concurrencyCounter{}, false))
+			h.Register("panic", jsonrpc2.NewAction(func(ctx context.Context, params *sleepParams) (*sleepResult, error) {
+				panic("panic!!")
+			}))
+			// ms が数値のため params のデコードに失敗する
+			h.Register("invalid", jsonrpc2.NewAction(func(ctx context.Context, params *struct {
+				Milliseconds string `json:"ms"`
+			}) (*sleepResult, error) {
+				return &sleepResult{}, nil
+			}))
+			// 記録の順番を固定する
+			h.SetBatchOption(&jsonrpc2.BatchOption{Concurrency: 1, PreserveOrder: true})
+			for method, interceptors := range tc.interceptors {
+				if method == "" {
+					h.Use(interceptors...)
+				} else {
+					h.UseMethod(method, interceptors...)
+				}
+			}
+
+			status, responses := doBatch(t, h, tc.args.methods, tc.args.sleeps)
+			if status != http.StatusOK {
+				t.Fatalf("got: %v, want: %v", status, http.StatusOK)
+			}
+			errorIDs := []string{}
+			for _, res := range responses {
+				if res.Error != nil {
+					errorIDs = append(errorIDs, res.ID)
+				}
+			}
+			if fmt.Sprint(calls) != fmt.Sprint(tc.want.calls) {
+				t.Errorf("got: %v, want: %v", calls, tc.want.calls)
+			}
+			if fmt.Sprint(errorIDs) != fmt.Sprint(tc.want.errorIDs) {
+				t.Errorf("got: %v, want: %v", errorIDs, tc.want.errorIDs)
+			}
+		})
+	}
+}
@@ -21,6 +21,8 @@ type response struct {
 type ErrorResponse struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
+	// エラーの詳細 (strict モードのみ)
+	Data any `json:"data,omitempty"`
 }
 
 func newResponse(id string, result any) response {
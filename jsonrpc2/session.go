@@ -0,0 +1,242 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rabee-inc/go-pkg/log"
+)
+
+// 接続が閉じられたため、レスポンスを受け取れなかった
+var ErrSessionClosed = errors.New("jsonrpc2: session closed")
+
+// 送信に失敗した。リクエストは相手に届いていないため、再接続後に再送できる
+var errSendFailed = fmt.Errorf("%w: send failed", ErrSessionClosed)
+
+// Conn 上の双方向の JSON-RPC のセッション。
+// 受信したリクエストは handler で処理し、送信したリクエストのレスポンスは ID で対応付ける
+type Session struct {
+	conn    Conn
+	handler *Handler
+	// 接続を確立した HTTP リクエスト。 Conn を直接渡した場合は nil
+	request *http.Request
+
+	writeMutex sync.Mutex
+	mutex      sync.Mutex
+	pending    map[string]chan *ClientResponse
+	seq        atomic.Uint64
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newSession(conn Conn, handler *Handler, r *http.Request) *Session {
+	return &Session{
+		conn:    conn,
+		handler: handler,
+		request: r,
+		pending: map[string]chan *ClientResponse{},
+		done:    make(chan struct{}),
+	}
+}
+
+// 送信するリクエスト、通知
+type sessionRequest struct {
+	Version string           `json:"jsonrpc"`
+	ID      string           `json:"id,omitempty"`
+	Method  string           `json:"method"`
+	Params  *json.RawMessage `json:"params,omitempty"`
+}
+
+// 受信したメッセージの種類の判別に使用する
+type sessionMessage struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+}
+
+// リクエストを送信し、レスポンスを待つ
+func (s *Session) Call(ctx context.Context, method string, params any) (*json.RawMessage, *ErrorResponse, error) {
+	id := strconv.FormatUint(s.seq.Add(1), 10)
+	ch := make(chan *ClientResponse, 1)
+	s.mutex.Lock()
+	s.pending[id] = ch
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		delete(s.pending, id)
+		s.mutex.Unlock()
+	}()
+
+	if err := s.send(ctx, id, method, params); err != nil {
+		log.Warning(ctx, err)
+		return nil, nil, err
+	}
+
+	select {
+	case res := <-ch:
+		return res.Result, res.Error, nil
+	case <-s.done:
+		log.Warning(ctx, ErrSessionClosed)
+		return nil, nil, ErrSessionClosed
+	case <-ctx.Done():
+		log.Warning(ctx, ctx.Err())
+		return nil, nil, ctx.Err()
+	}
+}
+
+// 通知 (レスポンスのないリクエスト) を送信する
+func (s *Session) Notify(ctx context.Context, method string, params any) error {
+	if err := s.send(ctx, "", method, params); err != nil {
+		log.Warning(ctx, err)
+		return err
+	}
+	return nil
+}
+
+// 接続を閉じる
+func (s *Session) Close() error {
+	err := s.conn.Close()
+	s.closeOnce.Do(func() {
+		close(s.done)
+	})
+	return err
+}
+
+// 接続が閉じられると close される
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *Session) send(ctx context.Context, id string, method string, params any) error {
+	req := &sessionRequest{
+		Version: version,
+		ID:      id,
+		Method:  method,
+	}
+	bParams, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	// strict モードでは params に null を指定できないため、 nil の場合は省略する
+	if string(bParams) != "null" {
+		rawParams := json.RawMessage(bParams)
+		req.Params = &rawParams
+	}
+	return s.write(ctx, req)
+}
+
+func (s *Session) write(ctx context.Context, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.writeMutex.Lock()
+	defer s.writeMutex.Unlock()
+	if err := s.conn.Write(ctx, data); err != nil {
+		// 送信できない接続は使えないため閉じる
+		s.Close()
+		return fmt.Errorf("%w: %w", errSendFailed, err)
+	}
+	return nil
+}
+
+// 接続が閉じられるまでメッセージを受信する
+func (s *Session) serve(ctx context.Context) error {
+	ctx = setContextSession(ctx, s)
+	wg := &sync.WaitGroup{}
+	defer func() {
+		// 処理中のリクエストが相手のレスポンスを待ち続けないように、先に接続を閉じる
+		s.Close()
+		wg.Wait()
+	}()
+	for {
+		data, err := s.conn.Read(ctx)
+		if err != nil {
+			return err
+		}
+		// バッチリクエストは配列で送られる
+		if firstByte(data) == '[' {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.handleRequest(ctx, data)
+			}()
+			continue
+		}
+		var msg sessionMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			log.Warningf(ctx, "invalid jsonrpc2 message: %s", err.Error())
+			continue
+		}
+		if msg.Method == "" {
+			s.handleResponse(ctx, data)
+			continue
+		}
+		// リクエストの処理中に相手へのリクエストのレスポンスを受信できるように、別の goroutine で処理する
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.handleRequest(ctx, data)
+		}()
+	}
+}
+
+func (s *Session) handleRequest(ctx context.Context, data []byte) {
+	var res any
+	if s.handler == nil {
+		res = s.methodNotFound(ctx, data)
+	} else {
+		res = s.handler.handleMessage(ctx, s.request, data)
+	}
+	if res == nil {
+		return
+	}
+	if err := s.write(ctx, res); err != nil {
+		log.Warning(ctx, err)
+	}
+}
+
+// handler がない場合、リクエストにはエラーを返し、通知は無視する
+func (s *Session) methodNotFound(ctx context.Context, data []byte) any {
+	var msg sessionMessage
+	if err := json.Unmarshal(data, &msg); err != nil || msg.ID == nil {
+		log.Warningf(ctx, "unhandled jsonrpc2 message: %s", string(data))
+		return nil
+	}
+	log.Warningf(ctx, "method not found: %s", msg.Method)
+	return &strictResponse{
+		Version: version,
+		ID:      msg.ID,
+		Error: &ErrorResponse{
+			Code:    ErrorCodeMethodNotFound,
+			Message: "Method not found",
+			Data:    msg.Method,
+		},
+	}
+}
+
+func (s *Session) handleResponse(ctx context.Context, data []byte) {
+	var res ClientResponse
+	if err := json.Unmarshal(data, &res); err != nil {
+		log.Warningf(ctx, "invalid jsonrpc2 response: %s", err.Error())
+		return
+	}
+	s.mutex.Lock()
+	ch, ok := s.pending[res.ID]
+	s.mutex.Unlock()
+	if !ok {
+		log.Warningf(ctx, "unknown jsonrpc2 response id: %s", res.ID)
+		return
+	}
+	// 同じ ID のレスポンスを重複して受信した場合は読み捨てる
+	select {
+	case ch <- &res:
+	default:
+	}
+}
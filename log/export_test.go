@@ -0,0 +1,12 @@
+package log
+
+import "time"
+
+// テストから非公開のレート制限を使用する
+func NewRateLimiterForTest(opt *LimitOption) func(key string, now time.Time) (bool, int) {
+	r := newRateLimiter(opt)
+	return func(key string, now time.Time) (bool, int) {
+		allowed := r.allow(key, now)
+		return allowed, len(r.windows)
+	}
+}
@@ -0,0 +1,92 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Field ... 構造化ログのフィールド
+type Field struct {
+	Key   string
+	Value any
+}
+
+// F ... フィールドを作成する
+func F(key string, value any) Field {
+	return Field{
+		Key:   key,
+		Value: value,
+	}
+}
+
+const fieldsContextKey contextKey = "log:fields"
+
+// AddFields ... Loggerにフィールドを追加する。以降の全てのアプリケーションログに付与される
+func AddFields(ctx context.Context, fields ...Field) {
+	if logger := GetLogger(ctx); logger != nil {
+		// アプリケーションログの出力と並行して呼ばれる場合があるため、ロックして追加する
+		logger.mutex.Lock()
+		defer logger.mutex.Unlock()
+		logger.Fields = append(logger.Fields, fields...)
+	}
+}
+
+// WithFields ... フィールドを付与したContextを作成する。このContextで出力したアプリケーションログにのみ付与される
+func WithFields(ctx context.Context, fields ...Field) context.Context {
+	dst := append(append([]Field{}, getContextFields(ctx)...), fields...)
+	return context.WithValue(ctx, fieldsContextKey, dst)
+}
+
+func getContextFields(ctx context.Context) []Field {
+	if fields, ok := ctx.Value(fieldsContextKey).([]Field); ok {
+		return fields
+	}
+	return nil
+}
+
+// FieldsToMap ... フィールドをJSONに出力できる形式に変換する。同じキーは後のものを優先する
+func FieldsToMap(fields []Field) map[string]any {
+	if len(fields) == 0 {
+		return nil
+	}
+	dst := map[string]any{}
+	for _, field := range fields {
+		dst[field.Key] = fieldValue(field.Value)
+	}
+	return dst
+}
+
+func fieldValue(value any) any {
+	switch v := value.(type) {
+	case error:
+		return v.Error()
+	default:
+		return v
+	}
+}
+
+// key=value の形式で読みやすく出力する
+func formatFields(fields []Field) string {
+	parts := make([]string, 0, len(fields))
+	for _, field := range fields {
+		// Cloud Logging 用のフィールドは出力しない
+		if strings.HasPrefix(field.Key, "logging.googleapis.com/") {
+			continue
+		}
+		value := fmt.Sprintf("%v", fieldValue(field.Value))
+		if value == "" || strings.ContainsAny(value, " =\"\n\t") {
+			value = strconv.Quote(value)
+		}
+		parts = append(parts, fmt.Sprintf("%s=%s", field.Key, value))
+	}
+	return strings.Join(parts, " ")
+}
+
+func appendFieldsToMessage(msg string, fields []Field) string {
+	if str := formatFields(fields); str != "" {
+		return fmt.Sprintf("%s %s", msg, str)
+	}
+	return msg
+}
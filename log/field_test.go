@@ -0,0 +1,161 @@
+package log_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rabee-inc/go-pkg/log"
+)
+
+// 出力されたメッセージとフィールドを記録する Writer
+type captureWriter struct {
+	messages []string
+	fields   []map[string]any
+	// リクエストログ、ジョブログの子ログ
+	childs []*log.EntryChild
+}
+
+func (w *captureWriter) Request(severity log.Severity, traceID string, applicationLogs []*log.EntryChild, r *http.Request, status int, at time.Time, dr time.Duration) {
+	w.childs = applicationLogs
+}
+
+func (w *captureWriter) Job(severity log.Severity, traceID string, applicationLogs []*log.EntryChild) {
+	w.childs = applicationLogs
+}
+
+func (w *captureWriter) Application(severity log.Severity, traceID string, msg string, file string, line int64, function string, at time.Time) {
+	w.messages = append(w.messages, msg)
+	w.fields = append(w.fields, nil)
+}
+
+// フィールドに対応した captureWriter
+type captureFieldsWriter struct {
+	captureWriter
+}
+
+func (w *captureFieldsWriter) ApplicationWithFields(severity log.Severity, traceID string, msg string, file string, line int64, function string, at time.Time, fields []log.Field) {
+	w.messages = append(w.messages, msg)
+	w.fields = append(w.fields, log.FieldsToMap(fields))
+}
+
+func Test_Fields(t *testing.T) {
+	type args struct {
+		withFieldsWriter bool
+		loggerFields     []log.Field
+		contextFields    []log.Field
+		fields           []log.Field
+	}
+	type want struct {
+		message string
+		fields  map[string]any
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "呼び出しごとのフィールド",
+			args: args{
+				withFieldsWriter: true,
+				fields:           []log.Field{log.F("user_id", "u1"), log.F("count", 3)},
+			},
+			want: want{
+				message: "hello",
+				fields:  map[string]any{"user_id": "u1", "count": 3},
+			},
+		},
+		{
+			name: "Logger と Context のフィールドを付与し、同じキーは呼び出しごとのフィールドを優先する",
+			args: args{
+				withFieldsWriter: true,
+				loggerFields:     []log.Field{log.F("user_id", "u1"), log.F("job_id", "j1")},
+				contextFields:    []log.Field{log.F("doc_path", "users/u1")},
+				fields:           []log.Field{log.F("user_id", "u2"), log.F("err", errors.New("failed"))},
+			},
+			want: want{
+				message: "hello",
+				fields:  map[string]any{"user_id": "u2", "job_id": "j1", "doc_path": "users/u1", "err": "failed"},
+			},
+		},
+		{
+			name: "フィールドに対応していない Writer はメッセージに付与する",
+			args: args{
+				loggerFields: []log.Field{log.F("user_id", "u1")},
+				fields:       []log.Field{log.F("path", "a b")},
+			},
+			want: want{
+				message: `hello user_id=u1 path="a b"`,
+			},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			fw := &captureFieldsWriter{}
+			var writer log.Writer = &fw.captureWriter
+			if tc.args.withFieldsWriter {
+				writer = fw
+			}
+			logger := log.NewLogger(writer, log.SeverityDebug, "trace")
+			ctx := log.SetLogger(context.Background(), logger)
+			log.AddFields(ctx, tc.args.loggerFields...)
+			ctx = log.WithFields(ctx, tc.args.contextFields...)
+
+			log.Infow(ctx, "hello", tc.args.fields...)
+
+			if len(fw.messages) != 1 {
+				t.Fatalf("got: %v, want: %v", len(fw.messages), 1)
+			}
+			if fw.messages[0] != tc.want.message {
+				t.Errorf("got: %v, want: %v", fw.messages[0], tc.want.message)
+			}
+			if fmt.Sprint(fw.fields[0]) != fmt.Sprint(tc.want.fields) {
+				t.Errorf("got: %v, want: %v", fw.fields[0], tc.want.fields)
+			}
+			// リクエストログの子ログにもフィールドが記録される
+			child := logger.ApplicationLogs[0]
+			if tc.args.withFieldsWriter && fmt.Sprint(child.Fields) != fmt.Sprint(tc.want.fields) {
+				t.Errorf("got: %v, want: %v", child.Fields, tc.want.fields)
+			}
+		})
+	}
+}
+
+func Test_AddFields_Concurrent(t *testing.T) {
+	fw := &captureFieldsWriter{}
+	logger := log.NewLogger(fw, log.SeverityDebug, "trace")
+	ctx := log.SetLogger(context.Background(), logger)
+
+	// アプリケーションログの出力と並行してフィールドを追加する (go test -race で検出する)
+	n := 50
+	wg := &sync.WaitGroup{}
+	for i := 0; i < n; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			log.AddFields(ctx, log.F(fmt.Sprintf("key%d", i), i))
+		}()
+		go func() {
+			defer wg.Done()
+			log.Infof(ctx, "hello %d", i)
+		}()
+	}
+	wg.Wait()
+
+	if len(logger.Fields) != n {
+		t.Errorf("got: %v, want: %v", len(logger.Fields), n)
+	}
+	if len(fw.messages) != n {
+		t.Errorf("got: %v, want: %v", len(fw.messages), n)
+	}
+}
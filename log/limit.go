@@ -0,0 +1,176 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+const (
+	defaultRateLimitInterval = time.Second
+	// レート制限で数えるメッセージテンプレートの数の上限。
+	// エラーメッセージなどの動的なメッセージでプロセスのメモリが増え続けないようにする
+	maxRateLimitWindows = 1000
+)
+
+// LimitOption ... アプリケーションログの出力の制限
+type LimitOption struct {
+	// 1つのリクエストログ (ジョブログ) に含める子ログの数の上限。0の場合は無制限
+	MaxEntries int
+	// 1つのリクエストログ (ジョブログ) に含める子ログのメッセージとフィールドの合計バイト数の上限。0の場合は無制限
+	MaxPayloadSize int
+	// Severityごとの出力する割合 (0〜1)。SeverityDebug と SeverityInfo のみ指定でき、未指定の場合は全て出力する
+	SampleRates map[Severity]float64
+	// 同じメッセージテンプレート (フォーマット文字列、またはメッセージ) のログを RateLimitInterval の間に出力する数の上限。0の場合は無制限
+	RateLimit int
+	// 0の場合は1秒
+	RateLimitInterval time.Duration
+}
+
+// 出力しなかったログの数
+type droppedCount struct {
+	limit     int
+	sampling  int
+	rateLimit int
+}
+
+func (d *droppedCount) total() int {
+	return d.limit + d.sampling + d.rateLimit
+}
+
+// メッセージテンプレートごとの出力数を数える。同じMiddlewareのLogger間で共有する
+type rateLimiter struct {
+	mutex    sync.Mutex
+	limit    int
+	interval time.Duration
+	windows  map[string]*rateLimitWindow
+}
+
+type rateLimitWindow struct {
+	start time.Time
+	count int
+}
+
+func newRateLimiter(opt *LimitOption) *rateLimiter {
+	if opt == nil || opt.RateLimit <= 0 {
+		return nil
+	}
+	interval := opt.RateLimitInterval
+	if interval <= 0 {
+		interval = defaultRateLimitInterval
+	}
+	return &rateLimiter{
+		limit:    opt.RateLimit,
+		interval: interval,
+		windows:  map[string]*rateLimitWindow{},
+	}
+}
+
+func (r *rateLimiter) allow(key string, now time.Time) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	window, ok := r.windows[key]
+	if !ok || now.Sub(window.start) >= r.interval {
+		if !ok && len(r.windows) >= maxRateLimitWindows {
+			r.prune(now)
+		}
+		window = &rateLimitWindow{start: now}
+		r.windows[key] = window
+	}
+	window.count++
+	return window.count <= r.limit
+}
+
+// 期間が終わったメッセージテンプレートを削除する。上限を超えたままの場合は最も古いものを削除する
+func (r *rateLimiter) prune(now time.Time) {
+	for key, window := range r.windows {
+		if now.Sub(window.start) >= r.interval {
+			delete(r.windows, key)
+		}
+	}
+	for len(r.windows) >= maxRateLimitWindows {
+		oldestKey := ""
+		var oldest *rateLimitWindow
+		for key, window := range r.windows {
+			if oldest == nil || window.start.Before(oldest.start) {
+				oldestKey, oldest = key, window
+			}
+		}
+		delete(r.windows, oldestKey)
+	}
+}
+
+// SetLimitOption ... アプリケーションログの出力の制限を設定する
+func (l *Logger) SetLimitOption(opt *LimitOption) {
+	l.setLimitOption(opt, newRateLimiter(opt))
+}
+
+func (l *Logger) setLimitOption(opt *LimitOption, limiter *rateLimiter) {
+	l.limitOption = opt
+	l.rateLimiter = limiter
+}
+
+// サンプリングとレート制限により出力するか判定する
+func (l *Logger) allowApplicationLog(severity Severity, template string, at time.Time) bool {
+	if l.limitOption == nil {
+		return true
+	}
+	if severity <= SeverityInfo {
+		if rate, ok := l.limitOption.SampleRates[severity]; ok && rand.Float64() >= rate {
+			l.dropped.sampling++
+			return false
+		}
+	}
+	if l.rateLimiter != nil && !l.rateLimiter.allow(template, at) {
+		l.dropped.rateLimit++
+		return false
+	}
+	return true
+}
+
+// 子ログの数とサイズの上限に達していないか判定する
+func (l *Logger) canAddApplicationLog(msg string, fields []Field) bool {
+	if l.limitOption == nil {
+		return true
+	}
+	if l.limitOption.MaxEntries > 0 && len(l.ApplicationLogs) >= l.limitOption.MaxEntries {
+		l.dropped.limit++
+		return false
+	}
+	if l.limitOption.MaxPayloadSize > 0 {
+		size := len(msg)
+		if len(fields) > 0 {
+			b, _ := json.Marshal(FieldsToMap(fields))
+			size += len(b)
+		}
+		if l.payloadSize+size > l.limitOption.MaxPayloadSize {
+			l.dropped.limit++
+			return false
+		}
+		l.payloadSize += size
+	}
+	return true
+}
+
+// 出力しなかったログがある場合は、その数を末尾に追加した子ログを返す
+func (l *Logger) getApplicationLogs() []*EntryChild {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.dropped.total() == 0 {
+		return l.ApplicationLogs
+	}
+	marker := &EntryChild{
+		Severity: SeverityWarning.String(),
+		Message: fmt.Sprintf(
+			"%d entries dropped (limit: %d, sampling: %d, rate limit: %d)",
+			l.dropped.total(),
+			l.dropped.limit,
+			l.dropped.sampling,
+			l.dropped.rateLimit,
+		),
+		Time: Time(time.Now()),
+	}
+	return append(append([]*EntryChild{}, l.ApplicationLogs...), marker)
+}
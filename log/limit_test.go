@@ -0,0 +1,215 @@
+package log_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rabee-inc/go-pkg/log"
+)
+
+func Test_LimitOption(t *testing.T) {
+	type args struct {
+		option *log.LimitOption
+		job    bool
+	}
+	type want struct {
+		messages int
+		childs   int
+		marker   string
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "制限なし",
+			args: args{
+				option: nil,
+			},
+			want: want{
+				messages: 12,
+				childs:   12,
+			},
+		},
+		{
+			name: "子ログの数の上限",
+			args: args{
+				option: &log.LimitOption{MaxEntries: 5},
+			},
+			want: want{
+				messages: 12,
+				childs:   6,
+				marker:   "7 entries dropped (limit: 7, sampling: 0, rate limit: 0)",
+			},
+		},
+		{
+			name: "子ログのサイズの上限",
+			args: args{
+				// メッセージとスパンIDのフィールドで1件あたり58バイト
+				option: &log.LimitOption{MaxPayloadSize: 250},
+			},
+			want: want{
+				messages: 12,
+				childs:   5,
+				marker:   "8 entries dropped (limit: 8, sampling: 0, rate limit: 0)",
+			},
+		},
+		{
+			name: "Debugログのサンプリング",
+			args: args{
+				option: &log.LimitOption{SampleRates: map[log.Severity]float64{log.SeverityDebug: 0}},
+			},
+			want: want{
+				messages: 2,
+				childs:   3,
+				marker:   "10 entries dropped (limit: 0, sampling: 10, rate limit: 0)",
+			},
+		},
+		{
+			name: "メッセージテンプレートごとのレート制限",
+			args: args{
+				option: &log.LimitOption{RateLimit: 3},
+			},
+			want: want{
+				messages: 5,
+				childs:   6,
+				marker:   "7 entries dropped (limit: 0, sampling: 0, rate limit: 7)",
+			},
+		},
+		{
+			name: "ジョブログ",
+			args: args{
+				option: &log.LimitOption{MaxEntries: 5},
+				job:    true,
+			},
+			want: want{
+				messages: 12,
+				childs:   6,
+				marker:   "7 entries dropped (limit: 7, sampling: 0, rate limit: 0)",
+			},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			w := &captureWriter{}
+			m := log.NewMiddleware(w, "DEBUG")
+			m.SetLimitOption(tc.args.option)
+
+			// Debugログ 10 件と Info, Warning ログを出力する。
+			// Debugログは同じメッセージテンプレートを 2 箇所から出力する
+			run := func(ctx context.Context) {
+				for i := 0; i < 5; i++ {
+					log.Debugf(ctx, "loop %d", i)
+				}
+				for i := 5; i < 10; i++ {
+					log.Debugf(ctx, "loop %d", i)
+				}
+				log.Infof(ctx, "info")
+				log.Warningf(ctx, "warning")
+			}
+			if tc.args.job {
+				ctx := m.SetLogger(context.Background())
+				run(ctx)
+				m.WriteJob(ctx)
+			} else {
+				h := m.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					run(r.Context())
+				}))
+				h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+			}
+
+			if len(w.messages) != tc.want.messages {
+				t.Errorf("got: %v, want: %v", len(w.messages), tc.want.messages)
+			}
+			if len(w.childs) != tc.want.childs {
+				t.Fatalf("got: %v, want: %v", len(w.childs), tc.want.childs)
+			}
+			if tc.want.marker != "" {
+				last := w.childs[len(w.childs)-1]
+				if last.Message != tc.want.marker {
+					t.Errorf("got: %v, want: %v", last.Message, tc.want.marker)
+				}
+			}
+		})
+	}
+}
+
+func Test_RateLimiter_Bounded(t *testing.T) {
+	type args struct {
+		keys int
+		// キーごとに進める時間
+		step time.Duration
+	}
+	type want struct {
+		maxSize int
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "期間内に異なるメッセージが大量に出力されても上限を超えない",
+			args: args{
+				keys: 5000,
+				step: 0,
+			},
+			want: want{
+				maxSize: 1000,
+			},
+		},
+		{
+			name: "期間が終わったメッセージは削除する",
+			args: args{
+				keys: 5000,
+				step: time.Millisecond,
+			},
+			want: want{
+				maxSize: 1000,
+			},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			allow := log.NewRateLimiterForTest(&log.LimitOption{RateLimit: 1})
+			now := time.Now()
+			maxSize := 0
+			for i := 0; i < tc.args.keys; i++ {
+				now = now.Add(tc.args.step)
+				allowed, size := allow(fmt.Sprintf("user %d not found", i), now)
+				if !allowed {
+					t.Fatalf("got: %v, want: %v", allowed, true)
+				}
+				maxSize = max(maxSize, size)
+			}
+			if maxSize > tc.want.maxSize {
+				t.Errorf("got: %v, want: <= %v", maxSize, tc.want.maxSize)
+			}
+
+			// 上限に達した後もレート制限は機能する
+			allowed, _ := allow("repeated", now)
+			if !allowed {
+				t.Errorf("got: %v, want: %v", allowed, true)
+			}
+			allowed, _ = allow("repeated", now)
+			if allowed {
+				t.Errorf("got: %v, want: %v", allowed, false)
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/rabee-inc/go-pkg/errcode"
@@ -20,6 +21,20 @@ type Logger struct {
 	TraceID           string
 	ResponseStatus    int
 	ApplicationLogs   []*EntryChild
+	// 全てのアプリケーションログに付与するフィールド
+	Fields []Field
+	// このリクエスト (ジョブ) のスパンID
+	SpanID string
+	// 呼び出し元のスパンID
+	ParentSpanID string
+	TraceSampled bool
+
+	mutex       sync.Mutex
+	limitOption *LimitOption
+	rateLimiter *rateLimiter
+	dropped     droppedCount
+	// 記録した子ログのメッセージとフィールドの合計バイト数
+	payloadSize int
 }
 
 // IsLogging ... レベル毎のログ出力許可
@@ -36,31 +51,87 @@ func (l *Logger) SetOuttedSeverity(severity Severity) {
 
 // AddApplicationLog ... アプリケーションログ履歴を記録する
 func (l *Logger) AddApplicationLog(severity Severity, file string, line int64, function string, msg string, at time.Time) {
+	l.addApplicationLog(severity, file, line, function, msg, at, nil)
+}
+
+func (l *Logger) addApplicationLog(severity Severity, file string, line int64, function string, msg string, at time.Time, fields []Field) {
 	src := &EntryChild{
 		Severity: severity.String(),
 		Message:  fmt.Sprintf("%s:%d [%s] %s", file, line, function, msg),
 		Time:     Time(at),
+		Fields:   FieldsToMap(fields),
 	}
 	l.ApplicationLogs = append(l.ApplicationLogs, src)
 }
 
+// アプリケーションログを出力して履歴に記録する。 Logger と context のフィールドを fields の前に付与する。
+// template はレート制限に使用するメッセージテンプレート (フォーマット文字列、またはメッセージ)
+func (l *Logger) writeApplication(ctx context.Context, severity Severity, file string, line int64, function string, template string, msg string, at time.Time, fields []Field) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if !l.allowApplicationLog(severity, template, at) {
+		return
+	}
+
+	fields = append(append(append(l.traceFields(), l.Fields...), getContextFields(ctx)...), fields...)
+	if fw, ok := l.Writer.(FieldsWriter); ok && len(fields) > 0 {
+		fw.ApplicationWithFields(severity, l.TraceID, msg, file, line, function, at, fields)
+	} else {
+		// フィールドに対応していない Writer にはメッセージに付与して出力する
+		l.Writer.Application(severity, l.TraceID, appendFieldsToMessage(msg, fields), file, line, function, at)
+	}
+	l.SetOuttedSeverity(severity)
+	if l.canAddApplicationLog(msg, fields) {
+		l.addApplicationLog(severity, file, line, function, msg, at, fields)
+	}
+}
+
+func (l *Logger) traceFields() []Field {
+	fields := []Field{}
+	if l.SpanID != "" {
+		fields = append(fields, F(FieldKeySpanID, l.SpanID))
+	}
+	if l.ParentSpanID != "" {
+		fields = append(fields, F(FieldKeyParentSpanID, l.ParentSpanID))
+	}
+	if l.TraceSampled {
+		fields = append(fields, F(FieldKeyTraceSampled, true))
+	}
+	return fields
+}
+
 // WriteRequest ... リクエストログを出力する
 func (l *Logger) WriteRequest(r *http.Request, at time.Time, dr time.Duration) {
-	if l.IsLogging(l.MaxOuttedSeverity) {
-		l.Writer.Request(
-			l.MaxOuttedSeverity,
-			l.TraceID,
-			l.ApplicationLogs,
-			r,
-			l.ResponseStatus,
-			at,
-			dr)
+	l.writeRequest(r, &RequestDetail{
+		Status:      l.ResponseStatus,
+		RequestSize: r.ContentLength,
+		RemoteIP:    getRemoteIP(r),
+	}, at, dr)
+}
+
+func (l *Logger) writeRequest(r *http.Request, detail *RequestDetail, at time.Time, dr time.Duration) {
+	if !l.IsLogging(l.MaxOuttedSeverity) {
+		return
+	}
+	detail.SpanID = l.SpanID
+	detail.TraceSampled = l.TraceSampled
+	if dw, ok := l.Writer.(RequestDetailWriter); ok {
+		dw.RequestWithDetail(l.MaxOuttedSeverity, l.TraceID, l.getApplicationLogs(), r, detail, at, dr)
+		return
 	}
+	l.Writer.Request(
+		l.MaxOuttedSeverity,
+		l.TraceID,
+		l.getApplicationLogs(),
+		r,
+		detail.Status,
+		at,
+		dr)
 }
 
 // WriteJob ... ジョブログを出力する
 func (l *Logger) WriteJob(ctx context.Context) {
-	l.Writer.Job(l.MaxOuttedSeverity, l.TraceID, l.ApplicationLogs)
+	l.Writer.Job(l.MaxOuttedSeverity, l.TraceID, l.getApplicationLogs())
 }
 
 // NewLogger ... Loggerを作成する
@@ -89,16 +160,7 @@ func Debug(ctx context.Context, err error) {
 		now := timeutil.Now()
 		file, line, function := getFileLine()
 		msg := err.Error()
-		logger.Writer.Application(
-			severity,
-			logger.TraceID,
-			msg,
-			file,
-			line,
-			function,
-			now)
-		logger.SetOuttedSeverity(severity)
-		logger.AddApplicationLog(severity, file, line, function, msg, now)
+		logger.writeApplication(ctx, severity, file, line, function, msg, msg, now, nil)
 	}
 }
 
@@ -110,16 +172,7 @@ func Debugf(ctx context.Context, format string, args ...any) {
 		now := timeutil.Now()
 		file, line, function := getFileLine()
 		msg := fmt.Sprintf(format, args...)
-		logger.Writer.Application(
-			severity,
-			logger.TraceID,
-			msg,
-			file,
-			line,
-			function,
-			now)
-		logger.SetOuttedSeverity(severity)
-		logger.AddApplicationLog(severity, file, line, function, msg, now)
+		logger.writeApplication(ctx, severity, file, line, function, format, msg, now, nil)
 	}
 }
 
@@ -132,16 +185,7 @@ func Debuge(ctx context.Context, format string, args ...any) error {
 		now := timeutil.Now()
 		file, line, function := getFileLine()
 		msg := err.Error()
-		logger.Writer.Application(
-			severity,
-			logger.TraceID,
-			msg,
-			file,
-			line,
-			function,
-			now)
-		logger.SetOuttedSeverity(severity)
-		logger.AddApplicationLog(severity, file, line, function, msg, now)
+		logger.writeApplication(ctx, severity, file, line, function, format, msg, now, nil)
 	}
 	return err
 }
@@ -155,20 +199,22 @@ func Debugc(ctx context.Context, code int, format string, args ...any) error {
 		now := timeutil.Now()
 		file, line, function := getFileLine()
 		msg := err.Error()
-		logger.Writer.Application(
-			severity,
-			logger.TraceID,
-			msg,
-			file,
-			line,
-			function,
-			now)
-		logger.SetOuttedSeverity(severity)
-		logger.AddApplicationLog(severity, file, line, function, msg, now)
+		logger.writeApplication(ctx, severity, file, line, function, format, msg, now, nil)
 	}
 	return errcode.Set(err, code)
 }
 
+// Debugw ... フィールド付きのDebugログを出力する
+func Debugw(ctx context.Context, msg string, fields ...Field) {
+	severity := SeverityDebug
+	logger := GetLogger(ctx)
+	if logger != nil && logger.IsLogging(severity) {
+		now := timeutil.Now()
+		file, line, function := getFileLine()
+		logger.writeApplication(ctx, severity, file, line, function, msg, msg, now, fields)
+	}
+}
+
 // Info ... Infoログの定形を出力する
 func Info(ctx context.Context, err error) {
 	severity := SeverityInfo
@@ -177,16 +223,7 @@ func Info(ctx context.Context, err error) {
 		now := timeutil.Now()
 		file, line, function := getFileLine()
 		msg := err.Error()
-		logger.Writer.Application(
-			severity,
-			logger.TraceID,
-			msg,
-			file,
-			line,
-			function,
-			now)
-		logger.SetOuttedSeverity(severity)
-		logger.AddApplicationLog(severity, file, line, function, msg, now)
+		logger.writeApplication(ctx, severity, file, line, function, msg, msg, now, nil)
 	}
 }
 
@@ -198,16 +235,7 @@ func Infof(ctx context.Context, format string, args ...any) {
 		now := timeutil.Now()
 		file, line, function := getFileLine()
 		msg := fmt.Sprintf(format, args...)
-		logger.Writer.Application(
-			severity,
-			logger.TraceID,
-			msg,
-			file,
-			line,
-			function,
-			now)
-		logger.SetOuttedSeverity(severity)
-		logger.AddApplicationLog(severity, file, line, function, msg, now)
+		logger.writeApplication(ctx, severity, file, line, function, format, msg, now, nil)
 	}
 }
 
@@ -220,16 +248,7 @@ func Infoe(ctx context.Context, format string, args ...any) error {
 		now := timeutil.Now()
 		file, line, function := getFileLine()
 		msg := err.Error()
-		logger.Writer.Application(
-			severity,
-			logger.TraceID,
-			msg,
-			file,
-			line,
-			function,
-			now)
-		logger.SetOuttedSeverity(severity)
-		logger.AddApplicationLog(severity, file, line, function, msg, now)
+		logger.writeApplication(ctx, severity, file, line, function, format, msg, now, nil)
 	}
 	return err
 }
@@ -243,20 +262,22 @@ func Infoc(ctx context.Context, code int, format string, args ...any) error {
 		now := timeutil.Now()
 		file, line, function := getFileLine()
 		msg := err.Error()
-		logger.Writer.Application(
-			severity,
-			logger.TraceID,
-			msg,
-			file,
-			line,
-			function,
-			now)
-		logger.SetOuttedSeverity(severity)
-		logger.AddApplicationLog(severity, file, line, function, msg, now)
+		logger.writeApplication(ctx, severity, file, line, function, format, msg, now, nil)
 	}
 	return errcode.Set(err, code)
 }
 
+// Infow ... フィールド付きのInfoログを出力する
+func Infow(ctx context.Context, msg string, fields ...Field) {
+	severity := SeverityInfo
+	logger := GetLogger(ctx)
+	if logger != nil && logger.IsLogging(severity) {
+		now := timeutil.Now()
+		file, line, function := getFileLine()
+		logger.writeApplication(ctx, severity, file, line, function, msg, msg, now, fields)
+	}
+}
+
 // Warning ... Warningログの定形を出力する
 func Warning(ctx context.Context, err error) {
 	severity := SeverityWarning
@@ -265,16 +286,7 @@ func Warning(ctx context.Context, err error) {
 		now := timeutil.Now()
 		file, line, function := getFileLine()
 		msg := err.Error()
-		logger.Writer.Application(
-			severity,
-			logger.TraceID,
-			msg,
-			file,
-			line,
-			function,
-			now)
-		logger.SetOuttedSeverity(severity)
-		logger.AddApplicationLog(severity, file, line, function, msg, now)
+		logger.writeApplication(ctx, severity, file, line, function, msg, msg, now, nil)
 	}
 }
 
@@ -286,16 +298,7 @@ func Warningf(ctx context.Context, format string, args ...any) {
 		now := timeutil.Now()
 		file, line, function := getFileLine()
 		msg := fmt.Sprintf(format, args...)
-		logger.Writer.Application(
-			severity,
-			logger.TraceID,
-			msg,
-			file,
-			line,
-			function,
-			now)
-		logger.SetOuttedSeverity(severity)
-		logger.AddApplicationLog(severity, file, line, function, msg, now)
+		logger.writeApplication(ctx, severity, file, line, function, format, msg, now, nil)
 	}
 }
 
@@ -308,16 +311,7 @@ func Warninge(ctx context.Context, format string, args ...any) error {
 		now := timeutil.Now()
 		file, line, function := getFileLine()
 		msg := err.Error()
-		logger.Writer.Application(
-			severity,
-			logger.TraceID,
-			msg,
-			file,
-			line,
-			function,
-			now)
-		logger.SetOuttedSeverity(severity)
-		logger.AddApplicationLog(severity, file, line, function, msg, now)
+		logger.writeApplication(ctx, severity, file, line, function, format, msg, now, nil)
 	}
 	return err
 }
@@ -331,20 +325,22 @@ func Warningc(ctx context.Context, code int, format string, args ...any) error {
 		now := timeutil.Now()
 		file, line, function := getFileLine()
 		msg := err.Error()
-		logger.Writer.Application(
-			severity,
-			logger.TraceID,
-			msg,
-			file,
-			line,
-			function,
-			now)
-		logger.SetOuttedSeverity(severity)
-		logger.AddApplicationLog(severity, file, line, function, msg, now)
+		logger.writeApplication(ctx, severity, file, line, function, format, msg, now, nil)
 	}
 	return errcode.Set(err, code)
 }
 
+// Warningw ... フィールド付きのWarningログを出力する
+func Warningw(ctx context.Context, msg string, fields ...Field) {
+	severity := SeverityWarning
+	logger := GetLogger(ctx)
+	if logger != nil && logger.IsLogging(severity) {
+		now := timeutil.Now()
+		file, line, function := getFileLine()
+		logger.writeApplication(ctx, severity, file, line, function, msg, msg, now, fields)
+	}
+}
+
 // Error ... Errorログの定形を出力する
 func Error(ctx context.Context, err error) {
 	severity := SeverityError
@@ -353,16 +349,7 @@ func Error(ctx context.Context, err error) {
 		now := timeutil.Now()
 		file, line, function := getFileLine()
 		msg := err.Error()
-		logger.Writer.Application(
-			severity,
-			logger.TraceID,
-			msg,
-			file,
-			line,
-			function,
-			now)
-		logger.SetOuttedSeverity(severity)
-		logger.AddApplicationLog(severity, file, line, function, msg, now)
+		logger.writeApplication(ctx, severity, file, line, function, msg, msg, now, nil)
 	}
 }
 
@@ -374,16 +361,7 @@ func Errorf(ctx context.Context, format string, args ...any) {
 		now := timeutil.Now()
 		file, line, function := getFileLine()
 		msg := fmt.Sprintf(format, args...)
-		logger.Writer.Application(
-			severity,
-			logger.TraceID,
-			msg,
-			file,
-			line,
-			function,
-			now)
-		logger.SetOuttedSeverity(severity)
-		logger.AddApplicationLog(severity, file, line, function, msg, now)
+		logger.writeApplication(ctx, severity, file, line, function, format, msg, now, nil)
 	}
 }
 
@@ -396,16 +374,7 @@ func Errore(ctx context.Context, format string, args ...any) error {
 	if logger != nil && logger.IsLogging(severity) {
 		now := timeutil.Now()
 		file, line, function := getFileLine()
-		logger.Writer.Application(
-			severity,
-			logger.TraceID,
-			msg,
-			file,
-			line,
-			function,
-			now)
-		logger.SetOuttedSeverity(severity)
-		logger.AddApplicationLog(severity, file, line, function, msg, now)
+		logger.writeApplication(ctx, severity, file, line, function, format, msg, now, nil)
 	}
 	return err
 }
@@ -419,20 +388,22 @@ func Errorc(ctx context.Context, code int, format string, args ...any) error {
 		now := timeutil.Now()
 		file, line, function := getFileLine()
 		msg := err.Error()
-		logger.Writer.Application(
-			severity,
-			logger.TraceID,
-			msg,
-			file,
-			line,
-			function,
-			now)
-		logger.SetOuttedSeverity(severity)
-		logger.AddApplicationLog(severity, file, line, function, msg, now)
+		logger.writeApplication(ctx, severity, file, line, function, format, msg, now, nil)
 	}
 	return errcode.Set(err, code)
 }
 
+// Errorw ... フィールド付きのErrorログを出力する
+func Errorw(ctx context.Context, msg string, fields ...Field) {
+	severity := SeverityError
+	logger := GetLogger(ctx)
+	if logger != nil && logger.IsLogging(severity) {
+		now := timeutil.Now()
+		file, line, function := getFileLine()
+		logger.writeApplication(ctx, severity, file, line, function, msg, msg, now, fields)
+	}
+}
+
 // Critical ... Criticalログの定形を出力する
 func Critical(ctx context.Context, err error) {
 	severity := SeverityCritical
@@ -441,16 +412,7 @@ func Critical(ctx context.Context, err error) {
 		now := timeutil.Now()
 		file, line, function := getFileLine()
 		msg := err.Error()
-		logger.Writer.Application(
-			severity,
-			logger.TraceID,
-			msg,
-			file,
-			line,
-			function,
-			now)
-		logger.SetOuttedSeverity(severity)
-		logger.AddApplicationLog(severity, file, line, function, msg, now)
+		logger.writeApplication(ctx, severity, file, line, function, msg, msg, now, nil)
 	}
 }
 
@@ -462,16 +424,7 @@ func Criticalf(ctx context.Context, format string, args ...any) {
 		now := timeutil.Now()
 		file, line, function := getFileLine()
 		msg := fmt.Sprintf(format, args...)
-		logger.Writer.Application(
-			severity,
-			logger.TraceID,
-			msg,
-			file,
-			line,
-			function,
-			now)
-		logger.SetOuttedSeverity(severity)
-		logger.AddApplicationLog(severity, file, line, function, msg, now)
+		logger.writeApplication(ctx, severity, file, line, function, format, msg, now, nil)
 	}
 }
 
@@ -484,16 +437,7 @@ func Criticale(ctx context.Context, format string, args ...any) error {
 		now := timeutil.Now()
 		file, line, function := getFileLine()
 		msg := err.Error()
-		logger.Writer.Application(
-			severity,
-			logger.TraceID,
-			msg,
-			file,
-			line,
-			function,
-			now)
-		logger.SetOuttedSeverity(severity)
-		logger.AddApplicationLog(severity, file, line, function, msg, now)
+		logger.writeApplication(ctx, severity, file, line, function, format, msg, now, nil)
 	}
 	return err
 }
@@ -507,20 +451,22 @@ func Criticalc(ctx context.Context, code int, format string, args ...any) error
 		now := timeutil.Now()
 		file, line, function := getFileLine()
 		msg := err.Error()
-		logger.Writer.Application(
-			severity,
-			logger.TraceID,
-			msg,
-			file,
-			line,
-			function,
-			now)
-		logger.SetOuttedSeverity(severity)
-		logger.AddApplicationLog(severity, file, line, function, msg, now)
+		logger.writeApplication(ctx, severity, file, line, function, format, msg, now, nil)
 	}
 	return errcode.Set(err, code)
 }
 
+// Criticalw ... フィールド付きのCriticalログを出力する
+func Criticalw(ctx context.Context, msg string, fields ...Field) {
+	severity := SeverityCritical
+	logger := GetLogger(ctx)
+	if logger != nil && logger.IsLogging(severity) {
+		now := timeutil.Now()
+		file, line, function := getFileLine()
+		logger.writeApplication(ctx, severity, file, line, function, msg, msg, now, fields)
+	}
+}
+
 // Panic ... Panicをハンドリングする
 func Panic(ctx context.Context, rcvr any) string {
 	traces := []string{}
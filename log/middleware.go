@@ -4,7 +4,6 @@ import (
 	"context"
 	"net/http"
 
-	"github.com/rabee-inc/go-pkg/stringutil"
 	"github.com/rabee-inc/go-pkg/timeutil"
 )
 
@@ -12,59 +11,121 @@ import (
 type Middleware struct {
 	Writer         Writer
 	MinOutSeverity Severity
+	limitOption    *LimitOption
+	// Loggerの間で共有する
+	rateLimiter  *rateLimiter
+	bodyOption   *BodyOption
+	bodyRedactor *bodyRedactor
 }
 
 func NewMiddleware(writer Writer, minOutSeverity string) *Middleware {
 	mos := NewSeverity(minOutSeverity)
 	return &Middleware{
-		writer,
-		mos,
+		Writer:         writer,
+		MinOutSeverity: mos,
 	}
 }
 
+// SetLimitOption ... リクエストログとジョブログのアプリケーションログの出力の制限を設定する
+func (m *Middleware) SetLimitOption(opt *LimitOption) {
+	m.limitOption = opt
+	m.rateLimiter = newRateLimiter(opt)
+}
+
+// SetBodyOption ... リクエストログにリクエストとレスポンスのボディを記録する
+func (m *Middleware) SetBodyOption(opt *BodyOption) {
+	m.bodyOption = opt
+	m.bodyRedactor = nil
+	if opt != nil {
+		m.bodyRedactor = newBodyRedactor(opt.RedactFields)
+	}
+}
+
+func (m *Middleware) newBodyCapture() *bodyCapture {
+	if m.bodyOption == nil || m.bodyOption.MaxBytes <= 0 {
+		return nil
+	}
+	return &bodyCapture{max: m.bodyOption.MaxBytes}
+}
+
+func (m *Middleware) newLogger() *Logger {
+	logger := NewLogger(m.Writer, m.MinOutSeverity, newTraceID())
+	logger.SpanID = newSpanID()
+	logger.setLimitOption(m.limitOption, m.rateLimiter)
+	return logger
+}
+
 // Handle ... ロガーを初期化する
 func (m *Middleware) Handle(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		startAt := timeutil.Now()
 
-		// ロガーをContextに設定
-		traceID := stringutil.UniqueID()
-		logger := NewLogger(m.Writer, m.MinOutSeverity, traceID)
+		// ロガーをContextに設定。リクエストにトレースのヘッダーがある場合はそのトレースに含める
+		logger := m.newLogger()
+		if tc, ok := GetTraceContextFromRequest(r); ok {
+			logger.TraceID = tc.TraceID
+			logger.ParentSpanID = tc.SpanID
+			logger.TraceSampled = tc.Sampled
+		}
 		ctx := r.Context()
 		ctx = SetLogger(ctx, logger)
 
+		// ステータスコード、サイズ、ボディを記録する
+		reqBody := &requestBody{ReadCloser: r.Body, capture: m.newBodyCapture()}
+		if r.Body != nil && r.Body != http.NoBody {
+			r.Body = reqBody
+		}
+		rw := &responseWriter{ResponseWriter: w, capture: m.newBodyCapture()}
+		writeRequest := func() {
+			// 実行時間を計算
+			endAt := timeutil.Now()
+			dr := endAt.Sub(startAt)
+
+			// リクエストログを出力
+			logger.writeRequest(r, m.newRequestDetail(r, logger, reqBody, rw), endAt, dr)
+		}
+
 		// Panicのハンドリングを設定
 		defer func() {
 			if rcvr := recover(); rcvr != nil {
 				msg := Panic(ctx, rcvr)
-				w.WriteHeader(http.StatusInternalServerError)
-				w.Write([]byte(msg))
-
-				// 実行時間を計算
-				endAt := timeutil.Now()
-				dr := endAt.Sub(startAt)
-
-				// リクエストログを出力
-				logger.WriteRequest(r, endAt, dr)
+				rw.WriteHeader(http.StatusInternalServerError)
+				rw.Write([]byte(msg))
+				writeRequest()
 			}
 		}()
 
 		// 実行
-		next.ServeHTTP(w, r.WithContext(ctx))
-
-		// 実行時間を計算
-		endAt := timeutil.Now()
-		dr := endAt.Sub(startAt)
-
-		// リクエストログを出力
-		logger.WriteRequest(r, endAt, dr)
+		next.ServeHTTP(rw, r.WithContext(ctx))
+		writeRequest()
 	})
 }
 
+func (m *Middleware) newRequestDetail(r *http.Request, logger *Logger, reqBody *requestBody, rw *responseWriter) *RequestDetail {
+	// 何も書き込まれていない場合は SetResponseStatus で設定したステータスコードを使う
+	status := rw.status
+	if status == 0 {
+		status = logger.ResponseStatus
+	}
+	if status == 0 {
+		status = http.StatusOK
+	}
+	requestSize := r.ContentLength
+	if requestSize < 0 || reqBody.size > requestSize {
+		requestSize = reqBody.size
+	}
+	return &RequestDetail{
+		Status:       status,
+		RequestSize:  requestSize,
+		ResponseSize: rw.size,
+		RemoteIP:     getRemoteIP(r),
+		RequestBody:  reqBody.capture.string(m.bodyRedactor),
+		ResponseBody: rw.capture.string(m.bodyRedactor),
+	}
+}
+
 func (m *Middleware) SetLogger(ctx context.Context) context.Context {
-	traceID := stringutil.UniqueID()
-	logger := NewLogger(m.Writer, m.MinOutSeverity, traceID)
-	return SetLogger(ctx, logger)
+	return SetLogger(ctx, m.newLogger())
 }
 
 func (m *Middleware) WriteJob(ctx context.Context) {
@@ -8,8 +8,14 @@ type Entry struct {
 	Time        Time              `json:"time"`
 	Trace       string            `json:"logging.googleapis.com/trace"`
 	TraceID     string            `json:"traceId"`
-	Childs      []*EntryChild     `json:"childs"`
-	Message     string            `json:"message,omitempty"`
+	// リクエストログのみ出力する
+	SpanID       string        `json:"logging.googleapis.com/spanId,omitempty"`
+	TraceSampled bool          `json:"logging.googleapis.com/trace_sampled,omitempty"`
+	Childs       []*EntryChild `json:"childs"`
+	Message      string        `json:"message,omitempty"`
+	// BodyOption を設定した場合のみ出力する
+	RequestBody  string `json:"requestBody,omitempty"`
+	ResponseBody string `json:"responseBody,omitempty"`
 }
 
 // EntryHTTPRequest ... HTTPリクエストの構造ログ定義
@@ -21,6 +27,7 @@ type EntryHTTPRequest struct {
 	Status                         int      `json:"status"`
 	ResponseSize                   int64    `json:"responseSize,string,omitempty"`
 	UserAgent                      string   `json:"userAgent,omitempty"`
+	RemoteIP                       string   `json:"remoteIp,omitempty"`
 	Referer                        string   `json:"referer,omitempty"`
 	Latency                        Duration `json:"latency,omitempty"`
 	CacheLookup                    *bool    `json:"cacheLookup,omitempty"`
@@ -32,9 +39,10 @@ type EntryHTTPRequest struct {
 
 // EntryChild ... 子ログの構造ログ定義
 type EntryChild struct {
-	Severity string `json:"severity"`
-	Message  string `json:"message"`
-	Time     Time   `json:"time"`
+	Severity string         `json:"severity"`
+	Message  string         `json:"message"`
+	Time     Time           `json:"time"`
+	Fields   map[string]any `json:"fields,omitempty"`
 }
 
 // EntrySourceLocation ... SourceLocationの構造ログ定義
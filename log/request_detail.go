@@ -0,0 +1,224 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const redactedValue = "[REDACTED]"
+
+// RequestDetail ... ミドルウェアが記録したリクエストとレスポンスの詳細
+type RequestDetail struct {
+	Status       int
+	RequestSize  int64
+	ResponseSize int64
+	RemoteIP     string
+	// BodyOption を設定した場合のみ記録する
+	RequestBody  string
+	ResponseBody string
+	// リクエストログのスパン
+	SpanID       string
+	TraceSampled bool
+}
+
+// BodyOption ... リクエストログに記録するリクエストとレスポンスのボディの設定
+type BodyOption struct {
+	// 記録するボディの最大バイト数。0の場合は記録しない
+	MaxBytes int
+	// 値を [REDACTED] に置き換えるフィールド名 (JSONのキー、フォームのパラメータ名)。大文字小文字は区別しない
+	RedactFields []string
+}
+
+// ボディの先頭を最大 max バイト記録する
+type bodyCapture struct {
+	max int
+	buf bytes.Buffer
+}
+
+func (c *bodyCapture) write(b []byte) {
+	if c == nil {
+		return
+	}
+	if rest := c.max - c.buf.Len(); rest > 0 {
+		c.buf.Write(b[:min(rest, len(b))])
+	}
+}
+
+func (c *bodyCapture) string(redactor *bodyRedactor) string {
+	if c == nil {
+		return ""
+	}
+	return redactor.redact(c.buf.String())
+}
+
+// 読み込んだバイト数とボディを記録する
+type requestBody struct {
+	io.ReadCloser
+	size    int64
+	capture *bodyCapture
+}
+
+func (b *requestBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += int64(n)
+	b.capture.write(p[:n])
+	return n, err
+}
+
+// ステータスコードと書き込んだバイト数とボディを記録する
+type responseWriter struct {
+	http.ResponseWriter
+	status  int
+	size    int64
+	capture *bodyCapture
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	w.capture.write(b[:n])
+	return n, err
+}
+
+// Flush ... ストリーミングのレスポンスのために http.Flusher を実装する
+func (w *responseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack ... WebSocket のために http.Hijacker を実装する
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("log: ResponseWriter does not implement http.Hijacker")
+	}
+	w.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// Unwrap ... http.ResponseController のために元の ResponseWriter を返す
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// リクエストの送信元のIPアドレスを取得する。ロードバランサーを経由している場合は X-Forwarded-For の先頭を使う
+func getRemoteIP(r *http.Request) string {
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		ip, _, _ := strings.Cut(xff, ",")
+		return strings.TrimSpace(ip)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// JSONのキーとフォームのパラメータの値を置き換える
+type bodyRedactor struct {
+	jsonKey *regexp.Regexp
+	form    *regexp.Regexp
+}
+
+func newBodyRedactor(fields []string) *bodyRedactor {
+	if len(fields) == 0 {
+		return nil
+	}
+	keys := make([]string, len(fields))
+	for i, field := range fields {
+		keys[i] = regexp.QuoteMeta(field)
+	}
+	key := strings.Join(keys, "|")
+	return &bodyRedactor{
+		jsonKey: regexp.MustCompile(fmt.Sprintf(`(?i)"(?:%s)"\s*:\s*`, key)),
+		form:    regexp.MustCompile(fmt.Sprintf(`(?i)((?:^|&)(?:%s)=)[^&]*`, key)),
+	}
+}
+
+// 途中で切れたボディも対象にする
+func (r *bodyRedactor) redact(body string) string {
+	if r == nil {
+		return body
+	}
+	body = r.redactJSON(body)
+	return r.form.ReplaceAllString(body, "${1}"+redactedValue)
+}
+
+// キーに続く JSON の値 (オブジェクト、配列を含む) 全体を置き換える
+func (r *bodyRedactor) redactJSON(body string) string {
+	var b strings.Builder
+	rest := body
+	for {
+		loc := r.jsonKey.FindStringIndex(rest)
+		if loc == nil {
+			b.WriteString(rest)
+			return b.String()
+		}
+		b.WriteString(rest[:loc[1]])
+		b.WriteString(`"` + redactedValue + `"`)
+		rest = rest[loc[1]:]
+		rest = rest[jsonValueLength(rest):]
+	}
+}
+
+// 先頭の JSON の値のバイト数。途中で切れている場合は末尾まで
+func jsonValueLength(s string) int {
+	depth := 0
+	inString := false
+	escaped := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+				if depth == 0 {
+					return i + 1
+				}
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+		case '}', ']':
+			// 親のオブジェクト、配列の終わり
+			if depth == 0 {
+				return i
+			}
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		case ',', ' ', '\t', '\r', '\n':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(s)
+}
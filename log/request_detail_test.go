@@ -0,0 +1,220 @@
+package log_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rabee-inc/go-pkg/log"
+)
+
+// リクエストの詳細に対応した captureWriter
+type captureDetailWriter struct {
+	captureWriter
+	detail *log.RequestDetail
+}
+
+func (w *captureDetailWriter) RequestWithDetail(severity log.Severity, traceID string, applicationLogs []*log.EntryChild, r *http.Request, detail *log.RequestDetail, at time.Time, dr time.Duration) {
+	w.detail = detail
+}
+
+func Test_Middleware_RequestDetail(t *testing.T) {
+	type args struct {
+		option  *log.BodyOption
+		body    string
+		headers map[string]string
+		handler http.HandlerFunc
+	}
+	type want struct {
+		detail  log.RequestDetail
+		flusher bool
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "ステータスコードと書き込んだバイト数",
+			args: args{
+				handler: func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusCreated)
+					w.Write([]byte("created"))
+				},
+			},
+			want: want{
+				detail: log.RequestDetail{
+					Status:       http.StatusCreated,
+					ResponseSize: 7,
+					RemoteIP:     "192.0.2.1",
+				},
+			},
+		},
+		{
+			name: "WriteHeader を呼ばない場合は200",
+			args: args{
+				headers: map[string]string{"X-Forwarded-For": "203.0.113.1, 10.0.0.1"},
+				handler: func(w http.ResponseWriter, r *http.Request) {
+					w.Write([]byte("ok"))
+				},
+			},
+			want: want{
+				detail: log.RequestDetail{
+					Status:       http.StatusOK,
+					ResponseSize: 2,
+					RemoteIP:     "203.0.113.1",
+				},
+			},
+		},
+		{
+			name: "何も書き込まない場合は SetResponseStatus のステータスコード",
+			args: args{
+				handler: func(w http.ResponseWriter, r *http.Request) {
+					log.SetResponseStatus(r.Context(), http.StatusNoContent)
+				},
+			},
+			want: want{
+				detail: log.RequestDetail{
+					Status:   http.StatusNoContent,
+					RemoteIP: "192.0.2.1",
+				},
+			},
+		},
+		{
+			name: "ボディを最大バイト数まで記録し、指定したフィールドを置き換える",
+			args: args{
+				option: &log.BodyOption{MaxBytes: 40, RedactFields: []string{"password", "token"}},
+				body:   `{"name":"taro","Password":"secret","age":20}`,
+				handler: func(w http.ResponseWriter, r *http.Request) {
+					io.ReadAll(r.Body)
+					w.Write([]byte(`{"token":"abcdefghijklmnopqrstuvwxyz"}`))
+				},
+			},
+			want: want{
+				detail: log.RequestDetail{
+					Status:       http.StatusOK,
+					RequestSize:  44,
+					ResponseSize: 38,
+					RemoteIP:     "192.0.2.1",
+					RequestBody:  `{"name":"taro","Password":"[REDACTED]","age"`,
+					ResponseBody: `{"token":"[REDACTED]"}`,
+				},
+			},
+		},
+		{
+			name: "オブジェクト、配列、カンマや空白を含む文字列の値全体を置き換える",
+			args: args{
+				option: &log.BodyOption{MaxBytes: 200, RedactFields: []string{"card", "tokens", "memo"}},
+				body:   `{"card": {"number": "4242", "cvc": [1, 2]}, "tokens":["a, b", {"c": "d"}], "memo":"x, y }z", "name":"taro"}`,
+				handler: func(w http.ResponseWriter, r *http.Request) {
+					io.ReadAll(r.Body)
+				},
+			},
+			want: want{
+				detail: log.RequestDetail{
+					Status:      http.StatusOK,
+					RequestSize: 107,
+					RemoteIP:    "192.0.2.1",
+					RequestBody: `{"card": "[REDACTED]", "tokens":"[REDACTED]", "memo":"[REDACTED]", "name":"taro"}`,
+				},
+			},
+		},
+		{
+			name: "途中で切れた値は末尾まで置き換える",
+			args: args{
+				option: &log.BodyOption{MaxBytes: 30, RedactFields: []string{"card"}},
+				body:   `{"name":"taro","card":{"number":"4242"}}`,
+				handler: func(w http.ResponseWriter, r *http.Request) {
+					io.ReadAll(r.Body)
+				},
+			},
+			want: want{
+				detail: log.RequestDetail{
+					Status:      http.StatusOK,
+					RequestSize: 40,
+					RemoteIP:    "192.0.2.1",
+					RequestBody: `{"name":"taro","card":"[REDACTED]"`,
+				},
+			},
+		},
+		{
+			name: "フォームのパラメータを置き換える",
+			args: args{
+				option: &log.BodyOption{MaxBytes: 100, RedactFields: []string{"password"}},
+				body:   "name=taro&password=secret",
+				handler: func(w http.ResponseWriter, r *http.Request) {
+					r.ParseForm()
+				},
+			},
+			want: want{
+				detail: log.RequestDetail{
+					Status:      http.StatusOK,
+					RequestSize: 25,
+					RemoteIP:    "192.0.2.1",
+					RequestBody: "name=taro&password=[REDACTED]",
+				},
+			},
+		},
+		{
+			name: "http.Flusher を維持する",
+			args: args{
+				handler: func(w http.ResponseWriter, r *http.Request) {
+					w.Write([]byte("data"))
+					w.(http.Flusher).Flush()
+				},
+			},
+			want: want{
+				detail: log.RequestDetail{
+					Status:       http.StatusOK,
+					ResponseSize: 4,
+					RemoteIP:     "192.0.2.1",
+				},
+				flusher: true,
+			},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			w := &captureDetailWriter{}
+			m := log.NewMiddleware(w, "DEBUG")
+			m.SetBodyOption(tc.args.option)
+
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.args.body))
+			if tc.args.option != nil && strings.Contains(tc.args.body, "=") {
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			for key, value := range tc.args.headers {
+				r.Header.Set(key, value)
+			}
+			rec := httptest.NewRecorder()
+			m.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				log.Infof(r.Context(), "handle")
+				tc.args.handler(w, r)
+			})).ServeHTTP(rec, r)
+
+			if w.detail == nil {
+				t.Fatalf("got: %v, want: %v", w.detail, tc.want.detail)
+			}
+			// スパンIDはミドルウェアが生成する
+			got := *w.detail
+			if len(got.SpanID) != 16 {
+				t.Errorf("got: %v, want: %v", got.SpanID, "16 hex digits")
+			}
+			got.SpanID = ""
+			if got != tc.want.detail {
+				t.Errorf("got: %+v, want: %+v", got, tc.want.detail)
+			}
+			if rec.Flushed != tc.want.flusher {
+				t.Errorf("got: %v, want: %v", rec.Flushed, tc.want.flusher)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// SlogHandler ... Contextに設定されたLoggerにslogのログを出力するHandler
+type SlogHandler struct {
+	fallback slog.Handler
+	// WithGroup で指定されたグループ (フィールドのキーの接頭辞)
+	prefix string
+	fields []Field
+}
+
+// NewSlogHandler ... SlogHandlerを作成する。ContextにLoggerがない場合はfallbackに出力する (nilの場合は出力しない)
+func NewSlogHandler(fallback slog.Handler) *SlogHandler {
+	return &SlogHandler{
+		fallback: fallback,
+	}
+}
+
+// Enabled ... レベル毎のログ出力許可
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if logger := GetLogger(ctx); logger != nil {
+		return logger.IsLogging(SeverityFromSlogLevel(level))
+	}
+	if h.fallback != nil {
+		return h.fallback.Enabled(ctx, level)
+	}
+	return false
+}
+
+// Handle ... ログを出力する
+func (h *SlogHandler) Handle(ctx context.Context, record slog.Record) error {
+	logger := GetLogger(ctx)
+	if logger == nil {
+		if h.fallback != nil {
+			return h.fallback.Handle(ctx, record)
+		}
+		return nil
+	}
+
+	severity := SeverityFromSlogLevel(record.Level)
+	if !logger.IsLogging(severity) {
+		return nil
+	}
+	fields := append([]Field{}, h.fields...)
+	record.Attrs(func(attr slog.Attr) bool {
+		fields = appendSlogAttr(fields, h.prefix, attr)
+		return true
+	})
+	file, line, function := getSlogSource(record.PC)
+	at := record.Time
+	if at.IsZero() {
+		at = time.Now()
+	}
+	logger.writeApplication(ctx, severity, file, line, function, record.Message, record.Message, at, fields)
+	return nil
+}
+
+// WithAttrs ... 属性を付与したHandlerを作成する
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	dst := h.clone()
+	for _, attr := range attrs {
+		dst.fields = appendSlogAttr(dst.fields, h.prefix, attr)
+	}
+	if h.fallback != nil {
+		dst.fallback = h.fallback.WithAttrs(attrs)
+	}
+	return dst
+}
+
+// WithGroup ... グループを指定したHandlerを作成する
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	dst := h.clone()
+	dst.prefix = h.prefix + name + "."
+	if h.fallback != nil {
+		dst.fallback = h.fallback.WithGroup(name)
+	}
+	return dst
+}
+
+func (h *SlogHandler) clone() *SlogHandler {
+	return &SlogHandler{
+		fallback: h.fallback,
+		prefix:   h.prefix,
+		fields:   append([]Field{}, h.fields...),
+	}
+}
+
+// グループは "group.key" のキーに展開する
+func appendSlogAttr(fields []Field, prefix string, attr slog.Attr) []Field {
+	value := attr.Value.Resolve()
+	if value.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if attr.Key != "" {
+			groupPrefix = prefix + attr.Key + "."
+		}
+		for _, groupAttr := range value.Group() {
+			fields = appendSlogAttr(fields, groupPrefix, groupAttr)
+		}
+		return fields
+	}
+	if attr.Key == "" {
+		return fields
+	}
+	return append(fields, F(prefix+attr.Key, value.Any()))
+}
+
+func getSlogSource(pc uintptr) (string, int64, string) {
+	if pc == 0 {
+		return "", 0, ""
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	parts := strings.Split(frame.File, "/")
+	file := frame.File
+	if length := len(parts); length >= 2 {
+		file = fmt.Sprintf("%s/%s", parts[length-2], parts[length-1])
+	}
+	fParts := strings.Split(frame.Function, ".")
+	return file, int64(frame.Line), fParts[len(fParts)-1]
+}
+
+// SeverityFromSlogLevel ... slogのレベルをSeverityに変換する
+func SeverityFromSlogLevel(level slog.Level) Severity {
+	switch {
+	case level < slog.LevelInfo:
+		return SeverityDebug
+	case level < slog.LevelWarn:
+		return SeverityInfo
+	case level < slog.LevelError:
+		return SeverityWarning
+	case level < slog.LevelError+4:
+		return SeverityError
+	default:
+		return SeverityCritical
+	}
+}
+
+// SlogLevelFromSeverity ... Severityをslogのレベルに変換する
+func SlogLevelFromSeverity(severity Severity) slog.Level {
+	switch {
+	case severity < SeverityInfo:
+		return slog.LevelDebug
+	case severity < SeverityWarning:
+		return slog.LevelInfo
+	case severity < SeverityError:
+		return slog.LevelWarn
+	case severity < SeverityCritical:
+		return slog.LevelError
+	default:
+		return slog.LevelError + 4
+	}
+}
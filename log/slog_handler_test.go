@@ -0,0 +1,114 @@
+package log_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/rabee-inc/go-pkg/log"
+)
+
+func Test_SlogHandler(t *testing.T) {
+	type args struct {
+		level slog.Level
+		attrs []any
+	}
+	type want struct {
+		output   bool
+		severity string
+		fields   map[string]any
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "属性をフィールドにする",
+			args: args{
+				level: slog.LevelWarn,
+				attrs: []any{"user_id", "u1", slog.Group("req", "id", 1)},
+			},
+			want: want{
+				output:   true,
+				severity: "WARNING",
+				fields:   map[string]any{"lib": "x", "user_id": "u1", "req.id": 1},
+			},
+		},
+		{
+			name: "slog.LevelError より大きいレベルは Critical にする",
+			args: args{
+				level: slog.LevelError + 4,
+			},
+			want: want{
+				output:   true,
+				severity: "CRITICAL",
+				fields:   map[string]any{"lib": "x"},
+			},
+		},
+		{
+			name: "Logger の最小レベル未満は出力しない",
+			args: args{
+				level: slog.LevelDebug,
+			},
+			want: want{
+				output: false,
+			},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			w := &captureFieldsWriter{}
+			logger := log.NewLogger(w, log.SeverityInfo, "trace")
+			ctx := log.SetLogger(context.Background(), logger)
+
+			l := slog.New(log.NewSlogHandler(nil)).With("lib", "x")
+			l.Log(ctx, tc.args.level, "hello", tc.args.attrs...)
+
+			if got := len(w.messages) == 1; got != tc.want.output {
+				t.Fatalf("got: %v, want: %v", got, tc.want.output)
+			}
+			if !tc.want.output {
+				return
+			}
+			if logger.ApplicationLogs[0].Severity != tc.want.severity {
+				t.Errorf("got: %v, want: %v", logger.ApplicationLogs[0].Severity, tc.want.severity)
+			}
+			if fmt.Sprint(w.fields[0]) != fmt.Sprint(tc.want.fields) {
+				t.Errorf("got: %v, want: %v", w.fields[0], tc.want.fields)
+			}
+			// 呼び出し元のファイルが記録される
+			if !strings.Contains(logger.ApplicationLogs[0].Message, "log/slog_handler_test.go") {
+				t.Errorf("got: %v, want: %v", logger.ApplicationLogs[0].Message, "log/slog_handler_test.go")
+			}
+		})
+	}
+}
+
+func Test_WriterSlog(t *testing.T) {
+	buf := &bytes.Buffer{}
+	handler := slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelInfo})
+	logger := log.NewLogger(log.NewWriterSlog(handler), log.SeverityDebug, "trace")
+	ctx := log.SetLogger(context.Background(), logger)
+
+	log.Debugf(ctx, "debug")
+	log.Warningw(ctx, "hello", log.F("user_id", "u1"))
+
+	got := buf.String()
+	for _, want := range []string{"level=WARN", "msg=hello", "trace_id=trace", "user_id=u1", "source.function=Test_WriterSlog"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("got: %v, want: %v", got, want)
+		}
+	}
+	if strings.Contains(got, "msg=debug") {
+		t.Errorf("got: %v, want: %v", got, "no debug log")
+	}
+}
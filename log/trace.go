@@ -0,0 +1,177 @@
+package log
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// HeaderTraceParent ... W3C Trace Context のヘッダー
+	// spec: https://www.w3.org/TR/trace-context/#traceparent-header
+	HeaderTraceParent = "traceparent"
+	// HeaderCloudTraceContext ... Google Cloud のトレースのヘッダー
+	// spec: https://cloud.google.com/trace/docs/trace-context#legacy-http-header
+	HeaderCloudTraceContext = "X-Cloud-Trace-Context"
+
+	// FieldKeySpanID ... Cloud Logging でスパンIDとして扱われるフィールドのキー
+	FieldKeySpanID = "logging.googleapis.com/spanId"
+	// FieldKeyTraceSampled ... Cloud Logging でトレースのサンプリング有無として扱われるフィールドのキー
+	FieldKeyTraceSampled = "logging.googleapis.com/trace_sampled"
+	// FieldKeyParentSpanID ... 呼び出し元のスパンIDのフィールドのキー
+	FieldKeyParentSpanID = "parent_span_id"
+)
+
+// TraceContext ... 分散トレースのコンテキスト
+type TraceContext struct {
+	// 32桁の16進数
+	TraceID string
+	// 16桁の16進数
+	SpanID  string
+	Sampled bool
+}
+
+// ParseTraceParent ... traceparent ヘッダーをパースする
+func ParseTraceParent(value string) (*TraceContext, bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 {
+		return nil, false
+	}
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	if !isHex(version, 2) || version == "ff" || (version == "00" && len(parts) != 4) {
+		return nil, false
+	}
+	if !isHex(traceID, 32) || isZero(traceID) || !isHex(spanID, 16) || isZero(spanID) || !isHex(flags, 2) {
+		return nil, false
+	}
+	bFlags, _ := hex.DecodeString(flags)
+	return &TraceContext{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Sampled: bFlags[0]&1 == 1,
+	}, true
+}
+
+// ParseCloudTraceContext ... X-Cloud-Trace-Context ヘッダー (TRACE_ID/SPAN_ID;o=OPTIONS) をパースする
+func ParseCloudTraceContext(value string) (*TraceContext, bool) {
+	value = strings.TrimSpace(value)
+	options := ""
+	if i := strings.Index(value, ";"); i >= 0 {
+		value, options = value[:i], value[i+1:]
+	}
+	traceID, spanID, _ := strings.Cut(value, "/")
+	traceID = strings.ToLower(traceID)
+	if !isHex(traceID, 32) || isZero(traceID) {
+		return nil, false
+	}
+	tc := &TraceContext{
+		TraceID: traceID,
+		Sampled: options == "o=1",
+	}
+	// スパンIDは10進数
+	if spanID != "" {
+		id, err := strconv.ParseUint(spanID, 10, 64)
+		if err != nil || id == 0 {
+			return nil, false
+		}
+		tc.SpanID = fmt.Sprintf("%016x", id)
+	}
+	return tc, true
+}
+
+// GetTraceContextFromRequest ... リクエストのヘッダーからトレースのコンテキストを取得する。traceparent を優先する
+func GetTraceContextFromRequest(r *http.Request) (*TraceContext, bool) {
+	if tc, ok := ParseTraceParent(r.Header.Get(HeaderTraceParent)); ok {
+		return tc, true
+	}
+	return ParseCloudTraceContext(r.Header.Get(HeaderCloudTraceContext))
+}
+
+// TraceParent ... traceparent ヘッダーの値を作成する
+func (tc *TraceContext) TraceParent() string {
+	flags := "00"
+	if tc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", tc.TraceID, tc.SpanID, flags)
+}
+
+// CloudTraceContext ... X-Cloud-Trace-Context ヘッダーの値を作成する
+func (tc *TraceContext) CloudTraceContext() string {
+	options := "0"
+	if tc.Sampled {
+		options = "1"
+	}
+	spanID, _ := strconv.ParseUint(tc.SpanID, 16, 64)
+	return fmt.Sprintf("%s/%d;o=%s", tc.TraceID, spanID, options)
+}
+
+// GetTraceContext ... 現在のリクエスト (スパン) のトレースのコンテキストを取得する
+func GetTraceContext(ctx context.Context) (*TraceContext, bool) {
+	logger := GetLogger(ctx)
+	if logger == nil || !isHex(logger.TraceID, 32) || !isHex(logger.SpanID, 16) {
+		return nil, false
+	}
+	return &TraceContext{
+		TraceID: logger.TraceID,
+		SpanID:  logger.SpanID,
+		Sampled: logger.TraceSampled,
+	}, true
+}
+
+// GetTraceHeaders ... 送信するリクエストに付与するトレースのヘッダーを取得する。トレースがない場合は空
+func GetTraceHeaders(ctx context.Context) map[string]string {
+	tc, ok := GetTraceContext(ctx)
+	if !ok {
+		return map[string]string{}
+	}
+	return map[string]string{
+		HeaderTraceParent:       tc.TraceParent(),
+		HeaderCloudTraceContext: tc.CloudTraceContext(),
+	}
+}
+
+// InjectTraceHeaders ... 送信するリクエストのヘッダーにトレースを付与する。既に設定されているヘッダーは上書きしない
+func InjectTraceHeaders(ctx context.Context, header http.Header) {
+	for key, value := range GetTraceHeaders(ctx) {
+		if header.Get(key) == "" {
+			header.Set(key, value)
+		}
+	}
+}
+
+func newTraceID() string {
+	return randomHex(16)
+}
+
+func newSpanID() string {
+	return randomHex(8)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func isHex(s string, length int) bool {
+	if len(s) != length {
+		return false
+	}
+	for _, c := range s {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func isZero(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
@@ -0,0 +1,126 @@
+package log_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rabee-inc/go-pkg/log"
+)
+
+func Test_Middleware_Trace(t *testing.T) {
+	type args struct {
+		headers map[string]string
+	}
+	type want struct {
+		traceID      string
+		parentSpanID string
+		sampled      bool
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "traceparent",
+			args: args{
+				headers: map[string]string{
+					"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+				},
+			},
+			want: want{
+				traceID:      "4bf92f3577b34da6a3ce929d0e0e4736",
+				parentSpanID: "00f067aa0ba902b7",
+				sampled:      true,
+			},
+		},
+		{
+			name: "X-Cloud-Trace-Context",
+			args: args{
+				headers: map[string]string{
+					"X-Cloud-Trace-Context": "105445AA7843BC8BF206B12000100000/1;o=0",
+				},
+			},
+			want: want{
+				traceID:      "105445aa7843bc8bf206b12000100000",
+				parentSpanID: "0000000000000001",
+				sampled:      false,
+			},
+		},
+		{
+			name: "traceparent を優先する",
+			args: args{
+				headers: map[string]string{
+					"traceparent":           "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+					"X-Cloud-Trace-Context": "105445aa7843bc8bf206b12000100000/1;o=1",
+				},
+			},
+			want: want{
+				traceID:      "4bf92f3577b34da6a3ce929d0e0e4736",
+				parentSpanID: "00f067aa0ba902b7",
+				sampled:      false,
+			},
+		},
+		{
+			name: "不正なヘッダーは無視して新しいトレースを開始する",
+			args: args{
+				headers: map[string]string{
+					"traceparent": "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+				},
+			},
+			want: want{},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			var logger *log.Logger
+			header := http.Header{}
+			fw := &captureFieldsWriter{}
+			m := log.NewMiddleware(fw, "DEBUG")
+			h := m.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				logger = log.GetLogger(r.Context())
+				log.InjectTraceHeaders(r.Context(), header)
+				log.Infof(r.Context(), "hello")
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for key, value := range tc.args.headers {
+				r.Header.Set(key, value)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if tc.want.traceID != "" && logger.TraceID != tc.want.traceID {
+				t.Errorf("got: %v, want: %v", logger.TraceID, tc.want.traceID)
+			}
+			if len(logger.TraceID) != 32 {
+				t.Errorf("got: %v, want: %v", logger.TraceID, "32 hex digits")
+			}
+			if logger.ParentSpanID != tc.want.parentSpanID {
+				t.Errorf("got: %v, want: %v", logger.ParentSpanID, tc.want.parentSpanID)
+			}
+			// アプリケーションログに呼び出し元のスパンIDを出力する
+			if got, _ := fw.fields[0][log.FieldKeyParentSpanID].(string); got != tc.want.parentSpanID {
+				t.Errorf("got: %v, want: %v", got, tc.want.parentSpanID)
+			}
+			if logger.TraceSampled != tc.want.sampled {
+				t.Errorf("got: %v, want: %v", logger.TraceSampled, tc.want.sampled)
+			}
+
+			// 送信するリクエストには自身のスパンIDを付与する
+			wantTraceParent := "00-" + logger.TraceID + "-" + logger.SpanID + "-"
+			if got := header.Get("traceparent"); !strings.HasPrefix(got, wantTraceParent) {
+				t.Errorf("got: %v, want: %v", got, wantTraceParent)
+			}
+			outgoing, ok := log.ParseCloudTraceContext(header.Get("X-Cloud-Trace-Context"))
+			if !ok || outgoing.TraceID != logger.TraceID || outgoing.SpanID != logger.SpanID || outgoing.Sampled != tc.want.sampled {
+				t.Errorf("got: %v, want: %v", header.Get("X-Cloud-Trace-Context"), logger.TraceID)
+			}
+		})
+	}
+}
@@ -33,3 +33,34 @@ type Writer interface {
 		at time.Time,
 	)
 }
+
+// FieldsWriter ... 構造化フィールドを出力できるWriter
+type FieldsWriter interface {
+	Writer
+
+	ApplicationWithFields(
+		severity Severity,
+		traceID string,
+		msg string,
+		file string,
+		line int64,
+		function string,
+		at time.Time,
+		fields []Field,
+	)
+}
+
+// RequestDetailWriter ... ミドルウェアが記録したリクエストとレスポンスの詳細を出力できるWriter
+type RequestDetailWriter interface {
+	Writer
+
+	RequestWithDetail(
+		severity Severity,
+		traceID string,
+		applicationLogs []*EntryChild,
+		r *http.Request,
+		detail *RequestDetail,
+		at time.Time,
+		dr time.Duration,
+	)
+}
@@ -0,0 +1,120 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type writerSlog struct {
+	Handler slog.Handler
+}
+
+// NewWriterSlog ... slogのHandlerに出力するWriterを作成する
+func NewWriterSlog(handler slog.Handler) Writer {
+	return &writerSlog{handler}
+}
+
+func (w *writerSlog) Request(
+	severity Severity,
+	traceID string,
+	applicationLogs []*EntryChild,
+	r *http.Request,
+	status int,
+	at time.Time,
+	dr time.Duration,
+) {
+	w.RequestWithDetail(severity, traceID, applicationLogs, r, &RequestDetail{Status: status}, at, dr)
+}
+
+func (w *writerSlog) RequestWithDetail(
+	severity Severity,
+	traceID string,
+	applicationLogs []*EntryChild,
+	r *http.Request,
+	detail *RequestDetail,
+	at time.Time,
+	dr time.Duration,
+) {
+	u := *r.URL
+	u.Fragment = ""
+	attrs := []slog.Attr{
+		slog.String("trace_id", traceID),
+		slog.String("method", r.Method),
+		slog.String("url", u.RequestURI()),
+		slog.Int("status", detail.Status),
+		slog.Duration("latency", dr),
+	}
+	if detail.RemoteIP != "" {
+		attrs = append(attrs, slog.String("remote_ip", detail.RemoteIP))
+	}
+	if detail.ResponseSize > 0 {
+		attrs = append(attrs, slog.Int64("response_size", detail.ResponseSize))
+	}
+	if detail.RequestBody != "" {
+		attrs = append(attrs, slog.String("request_body", detail.RequestBody))
+	}
+	if detail.ResponseBody != "" {
+		attrs = append(attrs, slog.String("response_body", detail.ResponseBody))
+	}
+	w.handle(severity, at, fmt.Sprintf("%s %s", r.Method, u.RequestURI()), attrs...)
+}
+
+func (w *writerSlog) Job(
+	severity Severity,
+	traceID string,
+	applicationLogs []*EntryChild,
+) {
+	w.handle(severity, time.Now(), "end job",
+		slog.String("trace_id", traceID),
+	)
+}
+
+func (w *writerSlog) Application(
+	severity Severity,
+	traceID string,
+	msg string,
+	file string,
+	line int64,
+	function string,
+	at time.Time,
+) {
+	w.ApplicationWithFields(severity, traceID, msg, file, line, function, at, nil)
+}
+
+func (w *writerSlog) ApplicationWithFields(
+	severity Severity,
+	traceID string,
+	msg string,
+	file string,
+	line int64,
+	function string,
+	at time.Time,
+	fields []Field,
+) {
+	attrs := []slog.Attr{
+		slog.String("trace_id", traceID),
+		slog.Group(slog.SourceKey,
+			slog.String("file", file),
+			slog.Int64("line", line),
+			slog.String("function", function),
+		),
+	}
+	for _, field := range fields {
+		attrs = append(attrs, slog.Any(field.Key, fieldValue(field.Value)))
+	}
+	w.handle(severity, at, msg, attrs...)
+}
+
+func (w *writerSlog) handle(severity Severity, at time.Time, msg string, attrs ...slog.Attr) {
+	ctx := context.Background()
+	level := SlogLevelFromSeverity(severity)
+	if !w.Handler.Enabled(ctx, level) {
+		return
+	}
+	record := slog.NewRecord(at, level, msg, 0)
+	record.AddAttrs(attrs...)
+	_ = w.Handler.Handle(ctx, record)
+}
@@ -25,6 +25,22 @@ func (w *writerStackdriver) Request(
 	status int,
 	at time.Time,
 	dr time.Duration,
+) {
+	detail := &RequestDetail{
+		Status:      status,
+		RequestSize: r.ContentLength,
+	}
+	w.RequestWithDetail(severity, traceID, applicationLogs, r, detail, at, dr)
+}
+
+func (w *writerStackdriver) RequestWithDetail(
+	severity Severity,
+	traceID string,
+	applicationLogs []*EntryChild,
+	r *http.Request,
+	detail *RequestDetail,
+	at time.Time,
+	dr time.Duration,
 ) {
 	u := *r.URL
 	u.Fragment = ""
@@ -37,18 +53,22 @@ func (w *writerStackdriver) Request(
 	falseV := false
 
 	e := &Entry{
-		Severity: severity.String(),
-		Time:     Time(at),
-		Trace:    fmt.Sprintf("projects/%s/traces/%s", w.ProjectID, traceID),
-		TraceID:  traceID,
-		Childs:   applicationLogs,
-		Message:  "",
+		Severity:     severity.String(),
+		Time:         Time(at),
+		Trace:        fmt.Sprintf("projects/%s/traces/%s", w.ProjectID, traceID),
+		TraceID:      traceID,
+		Childs:       applicationLogs,
+		Message:      "",
+		SpanID:       detail.SpanID,
+		TraceSampled: detail.TraceSampled,
 		HTTPRequest: &EntryHTTPRequest{
 			RequestMethod:                  r.Method,
 			RequestURL:                     uri,
-			RequestSize:                    r.ContentLength,
-			Status:                         status,
+			RequestSize:                    detail.RequestSize,
+			Status:                         detail.Status,
+			ResponseSize:                   detail.ResponseSize,
 			UserAgent:                      r.UserAgent(),
+			RemoteIP:                       detail.RemoteIP,
 			Referer:                        r.Referer(),
 			Latency:                        Duration(dr),
 			CacheLookup:                    &falseV,
@@ -57,6 +77,8 @@ func (w *writerStackdriver) Request(
 			CacheFillBytes:                 nil,
 			Protocol:                       r.Proto,
 		},
+		RequestBody:  detail.RequestBody,
+		ResponseBody: detail.ResponseBody,
 	}
 	b, err := json.Marshal(e)
 	if err != nil {
@@ -94,6 +116,19 @@ func (w *writerStackdriver) Application(
 	line int64,
 	function string,
 	at time.Time,
+) {
+	w.ApplicationWithFields(severity, traceID, msg, file, line, function, at, nil)
+}
+
+func (w *writerStackdriver) ApplicationWithFields(
+	severity Severity,
+	traceID string,
+	msg string,
+	file string,
+	line int64,
+	function string,
+	at time.Time,
+	fields []Field,
 ) {
 	e := &Entry{
 		Severity: severity.String(),
@@ -101,9 +136,29 @@ func (w *writerStackdriver) Application(
 		Trace:    fmt.Sprintf("projects/%s/traces/%s", w.ProjectID, traceID),
 		Message:  fmt.Sprintf("%s:%d [%s] %s", file, line, function, msg),
 	}
-	b, err := json.Marshal(e)
+	b, err := marshalEntryWithFields(e, fields)
 	if err != nil {
 		panic(err)
 	}
 	fmt.Fprintf(os.Stdout, string(b)+"\n")
 }
+
+// フィールドを jsonPayload のトップレベルに展開する。 Entry のキーと重複するフィールドは出力しない
+func marshalEntryWithFields(e *Entry, fields []Field) ([]byte, error) {
+	b, err := json.Marshal(e)
+	if err != nil || len(fields) == 0 {
+		return b, err
+	}
+	payload := map[string]any{}
+	for key, value := range FieldsToMap(fields) {
+		payload[key] = value
+	}
+	entry := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return nil, err
+	}
+	for key, value := range entry {
+		payload[key] = value
+	}
+	return json.Marshal(payload)
+}
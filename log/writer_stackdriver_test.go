@@ -0,0 +1,90 @@
+package log_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/rabee-inc/go-pkg/log"
+)
+
+func Test_WriterStackdriver_Request(t *testing.T) {
+	type args struct {
+		traceparent string
+	}
+	type want struct {
+		sampled bool
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "サンプリングする",
+			args: args{
+				traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			},
+			want: want{
+				sampled: true,
+			},
+		},
+		{
+			name: "サンプリングしない",
+			args: args{
+				traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			},
+			want: want{
+				sampled: false,
+			},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			// 標準エラー出力に書き込まれたログを読み取る
+			pr, pw, err := os.Pipe()
+			if err != nil {
+				t.Fatal(err)
+			}
+			stderr := os.Stderr
+			os.Stderr = pw
+			defer func() {
+				os.Stderr = stderr
+			}()
+
+			var logger *log.Logger
+			m := log.NewMiddleware(log.NewWriterStackdriver("project"), "DEBUG")
+			h := m.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				logger = log.GetLogger(r.Context())
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("traceparent", tc.args.traceparent)
+			h.ServeHTTP(httptest.NewRecorder(), r)
+			pw.Close()
+			os.Stderr = stderr
+
+			b, err := io.ReadAll(pr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			entry := map[string]any{}
+			if err := json.Unmarshal(b, &entry); err != nil {
+				t.Fatal(err)
+			}
+			if got := entry[log.FieldKeySpanID]; got != logger.SpanID {
+				t.Errorf("got: %v, want: %v", got, logger.SpanID)
+			}
+			if got, _ := entry[log.FieldKeyTraceSampled].(bool); got != tc.want.sampled {
+				t.Errorf("got: %v, want: %v", got, tc.want.sampled)
+			}
+		})
+	}
+}
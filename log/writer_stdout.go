@@ -24,10 +24,21 @@ func (w *writerStdout) Request(
 	status int,
 	at time.Time,
 	dr time.Duration) {
+	w.RequestWithDetail(severity, traceID, applicationLogs, r, &RequestDetail{Status: status}, at, dr)
+}
+
+func (w *writerStdout) RequestWithDetail(
+	severity Severity,
+	traceID string,
+	applicationLogs []*EntryChild,
+	r *http.Request,
+	detail *RequestDetail,
+	at time.Time,
+	dr time.Duration) {
 	u := *r.URL
 	u.Fragment = ""
 	date := at.Format(w.TimeFormat)
-	fmt.Printf("%s \"%s %s\" %d %dms\n", date, r.Method, u.RequestURI(), status, dr/1000000)
+	fmt.Printf("%s \"%s %s\" %d %dB %dms\n", date, r.Method, u.RequestURI(), detail.Status, detail.ResponseSize, dr/1000000)
 }
 
 func (w *writerStdout) Job(
@@ -46,6 +57,18 @@ func (w *writerStdout) Application(
 	line int64,
 	function string,
 	at time.Time) {
+	w.ApplicationWithFields(severity, traceID, msg, file, line, function, at, nil)
+}
+
+func (w *writerStdout) ApplicationWithFields(
+	severity Severity,
+	traceID string,
+	msg string,
+	file string,
+	line int64,
+	function string,
+	at time.Time,
+	fields []Field) {
 	date := at.Format(w.TimeFormat)
-	fmt.Printf("%s [%s] %s:%d [%s] %s\n", date, severity.String(), file, line, function, msg)
+	fmt.Printf("%s [%s] %s:%d [%s] %s\n", date, severity.String(), file, line, function, appendFieldsToMessage(msg, fields))
 }
@@ -2,7 +2,6 @@ package push
 
 import (
 	"context"
-	"encoding/json"
 
 	"github.com/rabee-inc/go-pkg/jsonrpc2"
 	"github.com/rabee-inc/go-pkg/log"
@@ -129,19 +128,9 @@ func (c *Client) GetReserve(ctx context.Context, reserveID string) (*Reserve, er
 		ReserveID: reserveID,
 	}
 	cli := jsonrpc2.NewClient(c.endpoint, c.headers)
-	resResult, resError, err := cli.DoSingle(ctx, "get_reserve", params)
-	if err != nil {
-		log.Error(ctx, err)
-		return nil, err
-	}
-	if resError != nil {
-		err = log.Errore(ctx, "code: %d, message: %s", resError.Code, resError.Message)
-		return nil, err
-	}
-	ret := &struct {
+	ret, err := jsonrpc2.Call[struct {
 		Reserve *Reserve `json:"reserve"`
-	}{}
-	err = json.Unmarshal([]byte(*resResult), ret)
+	}](ctx, cli, "get_reserve", params)
 	if err != nil {
 		log.Error(ctx, err)
 		return nil, err
@@ -161,20 +150,10 @@ func (c *Client) ListReserve(ctx context.Context, limit int, cursor string) ([]*
 		Cursor: cursor,
 	}
 	cli := jsonrpc2.NewClient(c.endpoint, c.headers)
-	resResult, resError, err := cli.DoSingle(ctx, "list_reserve", params)
-	if err != nil {
-		log.Error(ctx, err)
-		return nil, "", err
-	}
-	if resError != nil {
-		err = log.Errore(ctx, "code: %d, message: %s", resError.Code, resError.Message)
-		return nil, "", err
-	}
-	ret := &struct {
+	ret, err := jsonrpc2.Call[struct {
 		Reserves   []*Reserve `json:"reserves"`
 		NextCursor string     `json:"next_cursor"`
-	}{}
-	err = json.Unmarshal([]byte(*resResult), ret)
+	}](ctx, cli, "list_reserve", params)
 	if err != nil {
 		log.Error(ctx, err)
 		return nil, "", err
@@ -198,19 +177,9 @@ func (c *Client) CreateReserve(ctx context.Context, userIDs []string, msg *Messa
 		Unmanaged:  unmanaged,
 	}
 	cli := jsonrpc2.NewClient(c.endpoint, c.headers)
-	resResult, resError, err := cli.DoSingle(ctx, "create_reserve", params)
-	if err != nil {
-		log.Error(ctx, err)
-		return nil, err
-	}
-	if resError != nil {
-		err = log.Errore(ctx, "code: %d, message: %s", resError.Code, resError.Message)
-		return nil, err
-	}
-	ret := &struct {
+	ret, err := jsonrpc2.Call[struct {
 		Reserve *Reserve `json:"reserve"`
-	}{}
-	err = json.Unmarshal([]byte(*resResult), ret)
+	}](ctx, cli, "create_reserve", params)
 	if err != nil {
 		log.Error(ctx, err)
 		return nil, err
@@ -236,19 +205,9 @@ func (c *Client) UpdateReserve(ctx context.Context, reserveID string, userIDs []
 		Status:     status,
 	}
 	cli := jsonrpc2.NewClient(c.endpoint, c.headers)
-	resResult, resError, err := cli.DoSingle(ctx, "update_reserve", params)
-	if err != nil {
-		log.Error(ctx, err)
-		return nil, err
-	}
-	if resError != nil {
-		err = log.Errore(ctx, "code: %d, message: %s", resError.Code, resError.Message)
-		return nil, err
-	}
-	ret := &struct {
+	ret, err := jsonrpc2.Call[struct {
 		Reserve *Reserve `json:"reserve"`
-	}{}
-	err = json.Unmarshal([]byte(*resResult), ret)
+	}](ctx, cli, "update_reserve", params)
 	if err != nil {
 		log.Error(ctx, err)
 		return nil, err
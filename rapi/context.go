@@ -0,0 +1,37 @@
+package rapi
+
+import (
+	"context"
+	"net/http"
+)
+
+type contextKey string
+
+const (
+	statusCodeContextKey contextKey = "rapi:status_code"
+	versionContextKey    contextKey = "rapi:version"
+)
+
+// エンドポイントに設定された成功時のステータスコードを取得する。未設定の場合は 200
+func GetStatusCode(ctx context.Context) int {
+	if status, ok := ctx.Value(statusCodeContextKey).(int); ok {
+		return status
+	}
+	return http.StatusOK
+}
+
+func setContextStatusCode(ctx context.Context, status int) context.Context {
+	return context.WithValue(ctx, statusCodeContextKey, status)
+}
+
+// リクエストされた API のバージョンを取得する。バージョンのないエンドポイントの場合は空文字
+func GetVersion(ctx context.Context) string {
+	if version, ok := ctx.Value(versionContextKey).(string); ok {
+		return version
+	}
+	return ""
+}
+
+func setContextVersion(ctx context.Context, version string) context.Context {
+	return context.WithValue(ctx, versionContextKey, version)
+}
@@ -0,0 +1,349 @@
+package rapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// GetRouterDefinition の出力。 JSON で保存して、後から CompareContract で互換性を比較する
+type ContractSnapshot struct {
+	RouterDefinitions []*RouterDefinition       `json:"router_definitions"`
+	Types             map[string]*TypeStructure `json:"types"`
+}
+
+func NewContractSnapshot(r Router) *ContractSnapshot {
+	routerDefinitions, types := r.GetRouterDefinition()
+	return &ContractSnapshot{
+		RouterDefinitions: routerDefinitions,
+		Types:             types,
+	}
+}
+
+func (s *ContractSnapshot) JSON() ([]byte, error) {
+	return json.MarshalIndent(s, "", "  ")
+}
+
+// 変更の種類
+type ContractChangeKind string
+
+const (
+	ContractChangeKindEndpointAdded     ContractChangeKind = "endpoint_added"
+	ContractChangeKindEndpointRemoved   ContractChangeKind = "endpoint_removed"
+	ContractChangeKindFieldAdded        ContractChangeKind = "field_added"
+	ContractChangeKindFieldRemoved      ContractChangeKind = "field_removed"
+	ContractChangeKindFieldTypeChanged  ContractChangeKind = "field_type_changed"
+	ContractChangeKindFieldMoved        ContractChangeKind = "field_moved"
+	ContractChangeKindFieldRequired     ContractChangeKind = "field_required"
+	ContractChangeKindFieldOptional     ContractChangeKind = "field_optional"
+	ContractChangeKindAuthChanged       ContractChangeKind = "auth_changed"
+	ContractChangeKindStatusCodeChanged ContractChangeKind = "status_code_changed"
+	ContractChangeKindStreamKindChanged ContractChangeKind = "stream_kind_changed"
+)
+
+// 一つの変更
+type ContractChange struct {
+	Kind     ContractChangeKind `json:"kind"`
+	Breaking bool               `json:"breaking"`
+	Method   string             `json:"method"`
+	Path     string             `json:"path"`
+	// 変更箇所 (例: "input.name", "output.items[].id") 。エンドポイント自体の変更の場合は空文字
+	Location string `json:"location,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (c *ContractChange) String() string {
+	if c.Location == "" {
+		return fmt.Sprintf("%s %s: %s", c.Method, c.Path, c.Message)
+	}
+	return fmt.Sprintf("%s %s: %s: %s", c.Method, c.Path, c.Location, c.Message)
+}
+
+// 互換性の比較結果
+type ContractReport struct {
+	// クライアントを壊す変更
+	Breaking []*ContractChange `json:"breaking"`
+	// 既存のクライアントに影響しない追加の変更
+	Additive []*ContractChange `json:"additive"`
+}
+
+func (r *ContractReport) HasBreaking() bool {
+	return len(r.Breaking) > 0
+}
+
+func (r *ContractReport) String() string {
+	var sb strings.Builder
+	sb.WriteString("breaking changes:\n")
+	for _, c := range r.Breaking {
+		fmt.Fprintf(&sb, "  - %s\n", c)
+	}
+	sb.WriteString("additive changes:\n")
+	for _, c := range r.Additive {
+		fmt.Fprintf(&sb, "  - %s\n", c)
+	}
+	return sb.String()
+}
+
+// 2 つの GetRouterDefinition の出力を比較して、互換性のない変更と追加の変更を返す。
+// input はクライアントが送信する側、 output はクライアントが受信する側として判定する
+// (例: input のフィールドが必須になるのは破壊的変更、 output のフィールドが省略されうるようになるのは破壊的変更)
+func CompareContract(before, after *ContractSnapshot) *ContractReport {
+	c := &contractComparer{
+		before: before,
+		after:  after,
+		report: &ContractReport{
+			Breaking: []*ContractChange{},
+			Additive: []*ContractChange{},
+		},
+	}
+	c.compare()
+	return c.report
+}
+
+type contractComparer struct {
+	before *ContractSnapshot
+	after  *ContractSnapshot
+	report *ContractReport
+	// 比較中の型 (再帰型の無限ループを防ぐ)
+	visiting map[string]bool
+}
+
+type contractDirection int
+
+const (
+	contractDirectionInput contractDirection = iota
+	contractDirectionOutput
+)
+
+var regexpContractPathParam = regexp.MustCompile(`\{[^}]*\}`)
+
+// パスパラメーター名の変更はクライアントに影響しないため、名前を除いたパスで比較する
+func contractEndpointKey(rd *RouterDefinition) string {
+	path, _ := convertOpenAPIPath(rd.FullPathName)
+	return rd.Method + " " + regexpContractPathParam.ReplaceAllString(path, "{}")
+}
+
+func (c *contractComparer) compare() {
+	beforeMap := map[string]*RouterDefinition{}
+	for _, rd := range c.before.RouterDefinitions {
+		beforeMap[contractEndpointKey(rd)] = rd
+	}
+	afterMap := map[string]*RouterDefinition{}
+	for _, rd := range c.after.RouterDefinitions {
+		afterMap[contractEndpointKey(rd)] = rd
+	}
+
+	keys := []string{}
+	for key := range beforeMap {
+		keys = append(keys, key)
+	}
+	for key := range afterMap {
+		if _, ok := beforeMap[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		beforeRD, beforeOK := beforeMap[key]
+		afterRD, afterOK := afterMap[key]
+		switch {
+		case !afterOK:
+			c.add(ContractChangeKindEndpointRemoved, true, beforeRD, "", "endpoint removed")
+		case !beforeOK:
+			c.add(ContractChangeKindEndpointAdded, false, afterRD, "", "endpoint added")
+		default:
+			c.compareEndpoint(beforeRD, afterRD)
+		}
+	}
+}
+
+func (c *contractComparer) compareEndpoint(before, after *RouterDefinition) {
+	if before.WithAuth != after.WithAuth {
+		if after.WithAuth {
+			c.add(ContractChangeKindAuthChanged, true, after, "", "authentication became required")
+		} else {
+			c.add(ContractChangeKindAuthChanged, false, after, "", "authentication became unnecessary")
+		}
+	}
+	if contractStatusCode(before) != contractStatusCode(after) {
+		c.add(ContractChangeKindStatusCodeChanged, true, after, "", fmt.Sprintf("status code changed from %d to %d", contractStatusCode(before), contractStatusCode(after)))
+	}
+	if before.StreamKind != after.StreamKind {
+		c.add(ContractChangeKindStreamKindChanged, true, after, "", fmt.Sprintf("stream kind changed from %q to %q", before.StreamKind, after.StreamKind))
+	}
+
+	c.visiting = map[string]bool{}
+	c.compareType(after, contractDirectionInput, "input", before.InputTypeStructure, after.InputTypeStructure)
+	c.visiting = map[string]bool{}
+	c.compareType(after, contractDirectionOutput, "output", before.OutputTypeStructure, after.OutputTypeStructure)
+}
+
+func contractStatusCode(rd *RouterDefinition) int {
+	if rd.StatusCode == 0 {
+		return http.StatusOK
+	}
+	return rd.StatusCode
+}
+
+func (c *contractComparer) compareType(rd *RouterDefinition, direction contractDirection, location string, before, after *TypeStructure) {
+	if before == nil || after == nil {
+		if before != after {
+			c.add(ContractChangeKindFieldTypeChanged, true, rd, location, "type changed")
+		}
+		return
+	}
+	// any は何でも受け付ける (返す) ため、 input が any になる場合と output が any から変わる場合は互換性を保つ
+	if before.Kind != after.Kind {
+		switch {
+		case direction == contractDirectionInput && after.Kind == TypeKindAny:
+			c.add(ContractChangeKindFieldTypeChanged, false, rd, location, fmt.Sprintf("type changed from %s to %s", before.Kind, after.Kind))
+		case direction == contractDirectionOutput && before.Kind == TypeKindAny:
+			c.add(ContractChangeKindFieldTypeChanged, false, rd, location, fmt.Sprintf("type changed from %s to %s", before.Kind, after.Kind))
+		default:
+			c.add(ContractChangeKindFieldTypeChanged, true, rd, location, fmt.Sprintf("type changed from %s to %s", before.Kind, after.Kind))
+		}
+		return
+	}
+	// 同じ種類でも名前のある別の型 (struct, enum など) に変わる場合は互換性がない
+	if beforeName, afterName := contractTypeName(before), contractTypeName(after); beforeName != afterName {
+		c.add(ContractChangeKindFieldTypeChanged, true, rd, location, fmt.Sprintf("type changed from %s to %s", contractTypeLabel(before), contractTypeLabel(after)))
+	}
+
+	switch after.Kind {
+	case TypeKindArray:
+		c.compareType(rd, direction, location+"[]", before.ElemType, after.ElemType)
+	case TypeKindMap:
+		c.compareType(rd, direction, location+"{key}", before.KeyType, after.KeyType)
+		c.compareType(rd, direction, location+"{}", before.ElemType, after.ElemType)
+	case TypeKindStruct:
+		visitingKey := fmt.Sprintf("%d:%s:%s", direction, before.Name, after.Name)
+		if c.visiting[visitingKey] {
+			return
+		}
+		c.visiting[visitingKey] = true
+		defer delete(c.visiting, visitingKey)
+		c.compareFields(rd, direction, location, c.resolveFields(c.before, before), c.resolveFields(c.after, after))
+	}
+}
+
+// 名前のある型の名前。組み込み型、無名 struct 、 slice, map (要素の型で比較する) の場合は空文字
+func contractTypeName(ts *TypeStructure) string {
+	if ts.Kind == TypeKindArray || ts.Kind == TypeKindMap {
+		return ""
+	}
+	if strings.HasPrefix(ts.Name, "__unnamed__.") || !strings.Contains(ts.GoTypeName, ".") {
+		return ""
+	}
+	return ts.GoTypeName
+}
+
+func contractTypeLabel(ts *TypeStructure) string {
+	if name := contractTypeName(ts); name != "" {
+		return name
+	}
+	return ts.Kind
+}
+
+// DisableStructField で scan した場合、 struct のフィールドは Types から取得する
+func (c *contractComparer) resolveFields(s *ContractSnapshot, ts *TypeStructure) map[string]*TypeStructure {
+	if len(ts.Fields) == 0 {
+		if v, ok := s.Types[ts.Name]; ok {
+			return v.Fields
+		}
+	}
+	return ts.Fields
+}
+
+func (c *contractComparer) compareFields(rd *RouterDefinition, direction contractDirection, location string, before, after map[string]*TypeStructure) {
+	before = excludeContractURLFields(before)
+	after = excludeContractURLFields(after)
+	keys := []string{}
+	for key := range before {
+		keys = append(keys, key)
+	}
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		beforeField, beforeOK := before[key]
+		afterField, afterOK := after[key]
+		fieldLocation := location + "." + key
+		switch {
+		case !afterOK:
+			c.add(ContractChangeKindFieldRemoved, true, rd, fieldLocation, "field removed")
+		case !beforeOK:
+			// input に validate の required のフィールドが追加されると既存のクライアントのリクエストが失敗する。
+			// omitempty がないだけのフィールドは省略してもリクエストできるため破壊的変更としない
+			breaking := direction == contractDirectionInput && hasValidateRule(afterField.Validate, "required")
+			c.add(ContractChangeKindFieldAdded, breaking, rd, fieldLocation, "field added")
+		default:
+			if beforeField.TagName != afterField.TagName {
+				c.add(ContractChangeKindFieldMoved, true, rd, fieldLocation, fmt.Sprintf("field moved from %s to %s", contractTagName(beforeField), contractTagName(afterField)))
+			}
+			beforeRequired := isContractRequiredField(beforeField, direction)
+			afterRequired := isContractRequiredField(afterField, direction)
+			switch {
+			case !beforeRequired && afterRequired:
+				c.add(ContractChangeKindFieldRequired, direction == contractDirectionInput, rd, fieldLocation, "field became required")
+			case beforeRequired && !afterRequired:
+				c.add(ContractChangeKindFieldOptional, direction == contractDirectionOutput, rd, fieldLocation, "field became optional")
+			}
+			c.compareType(rd, direction, fieldLocation, beforeField, afterField)
+		}
+	}
+}
+
+// url のフィールドはパスとして比較済みのため除外する
+func excludeContractURLFields(fields map[string]*TypeStructure) map[string]*TypeStructure {
+	dst := map[string]*TypeStructure{}
+	for key, field := range fields {
+		if field.TagName == "url" {
+			continue
+		}
+		dst[key] = field
+	}
+	return dst
+}
+
+// input の場合はリクエストに必須か、 output の場合はレスポンスに常に含まれるかを返す
+func isContractRequiredField(field *TypeStructure, direction contractDirection) bool {
+	if direction == contractDirectionOutput {
+		return !field.OmitEmpty
+	}
+	if field.TagName == "form" {
+		return hasValidateRule(field.Validate, "required")
+	}
+	return !field.OmitEmpty || hasValidateRule(field.Validate, "required")
+}
+
+func contractTagName(field *TypeStructure) string {
+	if field.TagName == "" {
+		return "(none)"
+	}
+	return field.TagName
+}
+
+func (c *contractComparer) add(kind ContractChangeKind, breaking bool, rd *RouterDefinition, location string, message string) {
+	path, _ := convertOpenAPIPath(rd.FullPathName)
+	change := &ContractChange{
+		Kind:     kind,
+		Breaking: breaking,
+		Method:   rd.Method,
+		Path:     path,
+		Location: location,
+		Message:  message,
+	}
+	if breaking {
+		c.report.Breaking = append(c.report.Breaking, change)
+	} else {
+		c.report.Additive = append(c.report.Additive, change)
+	}
+}
@@ -0,0 +1,291 @@
+package rapi_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/rabee-inc/go-pkg/rapi"
+)
+
+type contractInput struct {
+	ID   string `url:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+type contractInputNoOmitEmpty struct {
+	ID   string `url:"id"`
+	Name string `json:"name"`
+}
+
+type contractInputRequired struct {
+	ID   string `url:"id"`
+	Name string `json:"name,omitempty" validate:"required"`
+}
+
+type contractInputAdded struct {
+	ID    string `url:"id"`
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email" validate:"required"`
+}
+
+type contractInputOptionalAdded struct {
+	ID       string `url:"id"`
+	Name     string `json:"name,omitempty"`
+	Nickname string `json:"nickname"`
+}
+
+type contractProfile struct {
+	Bio string `json:"bio"`
+}
+
+type contractProfileV2 struct {
+	Bio string `json:"bio"`
+}
+
+type contractOutput struct {
+	ID       string             `json:"id"`
+	Age      int                `json:"age"`
+	Profile  contractProfile    `json:"profile"`
+	Profiles []*contractProfile `json:"profiles"`
+}
+
+type contractOutputRemoved struct {
+	Age      int                `json:"age"`
+	Profile  contractProfile    `json:"profile"`
+	Profiles []*contractProfile `json:"profiles"`
+}
+
+type contractOutputOmitEmpty struct {
+	ID       string             `json:"id,omitempty"`
+	Age      int                `json:"age"`
+	Profile  contractProfile    `json:"profile"`
+	Profiles []*contractProfile `json:"profiles"`
+}
+
+type contractOutputRetyped struct {
+	ID       string               `json:"id"`
+	Age      string               `json:"age"`
+	Profile  contractProfileV2    `json:"profile"`
+	Profiles []*contractProfileV2 `json:"profiles"`
+}
+
+func newContractHandler[I, O any]() rapi.HandlerMethod[I] {
+	return rapi.NewHandlerMethod(func(ctx context.Context, param *I) (*O, error) {
+		return nil, nil
+	})
+}
+
+func newContractSnapshot(register func(r rapi.Router)) *rapi.ContractSnapshot {
+	r := rapi.NewRouter()
+	register(r)
+	return rapi.NewContractSnapshot(r)
+}
+
+func Test_CompareContract(t *testing.T) {
+	base := func(r rapi.Router) {
+		r.Put("/users/{id}", newContractHandler[contractInput, contractOutput]())
+	}
+
+	type args struct {
+		before func(r rapi.Router)
+		after  func(r rapi.Router)
+	}
+	type want struct {
+		changes []string
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "変更なし",
+			args: args{
+				before: base,
+				after:  base,
+			},
+			want: want{
+				changes: []string{},
+			},
+		},
+		{
+			name: "エンドポイントの削除と追加",
+			args: args{
+				before: base,
+				after: func(r rapi.Router) {
+					r.Post("/users/{user_id}", newContractHandler[contractInput, contractOutput]())
+				},
+			},
+			want: want{
+				changes: []string{
+					"breaking endpoint_removed PUT /users/{id} ",
+					"additive endpoint_added POST /users/{user_id} ",
+				},
+			},
+		},
+		{
+			name: "パスパラメーター名の変更は互換性がある",
+			args: args{
+				before: base,
+				after: func(r rapi.Router) {
+					r.Put("/users/{user_id}", newContractHandler[contractInput, contractOutput]())
+				},
+			},
+			want: want{
+				changes: []string{},
+			},
+		},
+		{
+			name: "フィールドの削除",
+			args: args{
+				before: base,
+				after: func(r rapi.Router) {
+					r.Put("/users/{id}", newContractHandler[contractInput, contractOutputRemoved]())
+				},
+			},
+			want: want{
+				changes: []string{
+					"breaking field_type_changed PUT /users/{id} output",
+					"breaking field_removed PUT /users/{id} output.id",
+				},
+			},
+		},
+		{
+			name: "フィールドの型の変更",
+			args: args{
+				before: base,
+				after: func(r rapi.Router) {
+					r.Put("/users/{id}", newContractHandler[contractInput, contractOutputRetyped]())
+				},
+			},
+			want: want{
+				changes: []string{
+					"breaking field_type_changed PUT /users/{id} output",
+					"breaking field_type_changed PUT /users/{id} output.age",
+					"breaking field_type_changed PUT /users/{id} output.profile",
+					"breaking field_type_changed PUT /users/{id} output.profiles[]",
+				},
+			},
+		},
+		{
+			name: "inputのomitemptyがなくなる",
+			args: args{
+				before: base,
+				after: func(r rapi.Router) {
+					r.Put("/users/{id}", newContractHandler[contractInputNoOmitEmpty, contractOutput]())
+				},
+			},
+			want: want{
+				changes: []string{
+					"breaking field_type_changed PUT /users/{id} input",
+					"breaking field_required PUT /users/{id} input.name",
+				},
+			},
+		},
+		{
+			name: "outputにomitemptyが付く",
+			args: args{
+				before: base,
+				after: func(r rapi.Router) {
+					r.Put("/users/{id}", newContractHandler[contractInput, contractOutputOmitEmpty]())
+				},
+			},
+			want: want{
+				changes: []string{
+					"breaking field_type_changed PUT /users/{id} output",
+					"breaking field_optional PUT /users/{id} output.id",
+				},
+			},
+		},
+		{
+			name: "必須になったフィールドと必須のフィールドの追加",
+			args: args{
+				before: func(r rapi.Router) {
+					r.Put("/users/{id}", newContractHandler[contractInput, contractOutput]())
+					r.Post("/users/{id}", newContractHandler[contractInput, contractOutput]())
+				},
+				after: func(r rapi.Router) {
+					r.Put("/users/{id}", newContractHandler[contractInputRequired, contractOutput]())
+					r.Post("/users/{id}", newContractHandler[contractInputAdded, contractOutput]())
+				},
+			},
+			want: want{
+				changes: []string{
+					"breaking field_type_changed POST /users/{id} input",
+					"breaking field_added POST /users/{id} input.email",
+					"breaking field_type_changed PUT /users/{id} input",
+					"breaking field_required PUT /users/{id} input.name",
+				},
+			},
+		},
+		{
+			name: "omitempty がないだけのフィールドの追加",
+			args: args{
+				before: func(r rapi.Router) {
+					r.Post("/users/{id}", newContractHandler[contractInput, contractOutput]())
+				},
+				after: func(r rapi.Router) {
+					r.Post("/users/{id}", newContractHandler[contractInputOptionalAdded, contractOutput]())
+				},
+			},
+			want: want{
+				changes: []string{
+					"breaking field_type_changed POST /users/{id} input",
+					"additive field_added POST /users/{id} input.nickname",
+				},
+			},
+		},
+		{
+			name: "認証が必要になる",
+			args: args{
+				before: base,
+				after: func(r rapi.Router) {
+					r.Auth().Put("/users/{id}", newContractHandler[contractInput, contractOutput]())
+				},
+			},
+			want: want{
+				changes: []string{
+					"breaking auth_changed PUT /users/{id} ",
+				},
+			},
+		},
+		{
+			name: "認証が不要になる",
+			args: args{
+				before: func(r rapi.Router) {
+					r.Auth().Put("/users/{id}", newContractHandler[contractInput, contractOutput]())
+				},
+				after: base,
+			},
+			want: want{
+				changes: []string{
+					"additive auth_changed PUT /users/{id} ",
+				},
+			},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			report := rapi.CompareContract(newContractSnapshot(tc.args.before), newContractSnapshot(tc.args.after))
+			changes := []string{}
+			for _, c := range report.Breaking {
+				changes = append(changes, fmt.Sprintf("breaking %s %s %s %s", c.Kind, c.Method, c.Path, c.Location))
+			}
+			for _, c := range report.Additive {
+				changes = append(changes, fmt.Sprintf("additive %s %s %s %s", c.Kind, c.Method, c.Path, c.Location))
+			}
+			if fmt.Sprint(changes) != fmt.Sprint(tc.want.changes) {
+				t.Errorf("got: %v, want: %v", changes, tc.want.changes)
+			}
+			if report.HasBreaking() != (len(report.Breaking) > 0) {
+				t.Errorf("got: %v, want: %v", report.HasBreaking(), len(report.Breaking) > 0)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package rapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/rabee-inc/go-pkg/errcode"
+	"github.com/rabee-inc/go-pkg/log"
+	"github.com/rabee-inc/go-pkg/parameter"
+	"github.com/rabee-inc/go-pkg/renderer"
+	"github.com/rabee-inc/go-pkg/validation"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// HandlerMethod で個別に設定されていない場合に使用する処理。
+// Router.SetDefault* で設定した処理のみ使用し、未設定の場合は何もしない
+type handlerDefaults struct {
+	InputFunc       func(ctx context.Context, r *http.Request, param any) error
+	ValidateFunc    func(ctx context.Context, param any) error
+	HandleErrorFunc func(ctx context.Context, w http.ResponseWriter, r *http.Request, err error)
+	RenderFunc      func(ctx context.Context, w http.ResponseWriter, r *http.Request, output any)
+}
+
+func newHandlerDefaults() *handlerDefaults {
+	return &handlerDefaults{}
+}
+
+// Router に登録された際に、 Router のデフォルト処理を受け取る RouterElement
+type handlerDefaultsReceiver interface {
+	setHandlerDefaults(defaults *handlerDefaults)
+}
+
+var defaultValidator = newDefaultValidator()
+
+func newDefaultValidator() *validator.Validate {
+	v := validator.New()
+	// エラーメッセージにはリクエストで指定するフィールド名を使用する
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tagName := range []string{"url", "json", "form"} {
+			name := strings.Split(field.Tag.Get(tagName), ",")[0]
+			if name != "" && name != "-" {
+				return name
+			}
+		}
+		return field.Name
+	})
+	return v
+}
+
+// JSON ボディ、 form (クエリ) 、 url パラメーターの順にリクエストパラメーターを受け取る。
+// Router.SetDefaultInputFunc または HandlerMethod.SetInputFunc に渡して使用する
+func DefaultInputFunc(ctx context.Context, r *http.Request, param any) error {
+	if isJSONBodyRequest(r) {
+		if err := json.NewDecoder(r.Body).Decode(param); err != nil && !errors.Is(err, io.EOF) {
+			log.Warning(ctx, err)
+			return errcode.Set(err, http.StatusBadRequest)
+		}
+	}
+
+	// form, url は struct のみ対応
+	if reflect.Indirect(reflect.ValueOf(param)).Kind() != reflect.Struct {
+		return nil
+	}
+	if err := parameter.GetForms(ctx, r, param); err != nil {
+		log.Warning(ctx, err)
+		if _, ok := errcode.Get(err); !ok {
+			err = errcode.Set(err, http.StatusBadRequest)
+		}
+		return err
+	}
+	FillURLParam(r, param)
+	return nil
+}
+
+func isJSONBodyRequest(r *http.Request) bool {
+	if r.Body == nil || r.Body == http.NoBody {
+		return false
+	}
+	ct := r.Header.Get("Content-Type")
+	if ct == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json"
+}
+
+// validate タグによるバリデーションを行う
+func DefaultValidateFunc(ctx context.Context, param any) error {
+	if reflect.Indirect(reflect.ValueOf(param)).Kind() != reflect.Struct {
+		return nil
+	}
+	if err := defaultValidator.Struct(param); err != nil {
+		err = validation.ConvertErrorMessageByDefault(err, nil)
+		log.Warning(ctx, err)
+		return err
+	}
+	return nil
+}
+
+// エラーをレンダリングする
+func DefaultHandleErrorFunc(ctx context.Context, w http.ResponseWriter, r *http.Request, err error) {
+	renderer.HandleError(ctx, w, err)
+}
+
+// レスポンスを JSON でレンダリングする。ステータスコードは SetStatusCode で設定された値を使用する
+func DefaultRenderFunc(ctx context.Context, w http.ResponseWriter, r *http.Request, output any) {
+	status := GetStatusCode(ctx)
+	if status == http.StatusNoContent {
+		w.WriteHeader(status)
+		log.SetResponseStatus(ctx, status)
+		return
+	}
+	renderer.JSON(ctx, w, status, output)
+}
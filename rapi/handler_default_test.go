@@ -0,0 +1,159 @@
+package rapi_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rabee-inc/go-pkg/rapi"
+	"github.com/rabee-inc/go-pkg/renderer"
+)
+
+type defaultInputItem struct {
+	ItemID string `url:"item_id" json:"-"`
+	Limit  int    `form:"limit"`
+	Name   string `json:"name"`
+}
+
+func Test_DefaultInputFunc(t *testing.T) {
+	type args struct {
+		withDefaults bool
+		method       string
+		url          string
+		contentType  string
+		body         string
+	}
+	type want struct {
+		status int
+		output string
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "ボディ、フォーム、URLパラメーター",
+			args: args{
+				withDefaults: true,
+				method:       http.MethodPost,
+				url:          "/items/i1?limit=3",
+				contentType:  "application/json; charset=utf-8",
+				body:         `{"name":"foo"}`,
+			},
+			want: want{
+				status: http.StatusOK,
+				output: "i1:3:foo",
+			},
+		},
+		{
+			name: "ボディなし",
+			args: args{
+				withDefaults: true,
+				method:       http.MethodGet,
+				url:          "/items/i2?limit=5",
+			},
+			want: want{
+				status: http.StatusOK,
+				output: "i2:5:",
+			},
+		},
+		{
+			name: "JSON以外のボディは読み込まない",
+			args: args{
+				withDefaults: true,
+				method:       http.MethodPost,
+				url:          "/items/i1",
+				contentType:  "text/plain",
+				body:         `{"name":"foo"}`,
+			},
+			want: want{
+				status: http.StatusOK,
+				output: "i1:0:",
+			},
+		},
+		{
+			name: "不正なボディは400",
+			args: args{
+				withDefaults: true,
+				method:       http.MethodPost,
+				url:          "/items/i1",
+				contentType:  "application/json",
+				body:         `{"name":`,
+			},
+			want: want{
+				status: http.StatusBadRequest,
+			},
+		},
+		{
+			name: "不正なフォームは400",
+			args: args{
+				withDefaults: true,
+				method:       http.MethodGet,
+				url:          "/items/i1?limit=abc",
+			},
+			want: want{
+				status: http.StatusBadRequest,
+			},
+		},
+		{
+			name: "デフォルトを設定しない場合は受け取らない",
+			args: args{
+				method:      http.MethodPost,
+				url:         "/items/i1?limit=3",
+				contentType: "application/json",
+				body:        `{"name":"foo"}`,
+			},
+			want: want{
+				status: http.StatusOK,
+				output: ":0:",
+			},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			r := rapi.NewRouter()
+			if tc.args.withDefaults {
+				r.SetDefaultInputFunc(rapi.DefaultInputFunc)
+				r.SetDefaultHandleErrorFunc(rapi.DefaultHandleErrorFunc)
+			}
+			h := rapi.NewHandlerMethod(func(ctx context.Context, param *defaultInputItem) (*string, error) {
+				output := fmt.Sprintf("%s:%d:%s", param.ItemID, param.Limit, param.Name)
+				return &output, nil
+			})
+			h.SetRenderFunc(func(ctx context.Context, w http.ResponseWriter, r *http.Request, output any) {
+				renderer.Text(ctx, w, http.StatusOK, *output.(*string))
+			})
+			if tc.args.method == http.MethodGet {
+				r.Get("/items/{item_id}", h)
+			} else {
+				r.Post("/items/{item_id}", h)
+			}
+
+			req := httptest.NewRequest(tc.args.method, tc.args.url, strings.NewReader(tc.args.body))
+			if tc.args.body == "" {
+				req.Body = http.NoBody
+			}
+			if tc.args.contentType != "" {
+				req.Header.Set("Content-Type", tc.args.contentType)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tc.want.status {
+				t.Errorf("got: %v, want: %v", rec.Code, tc.want.status)
+			}
+			if tc.want.status == http.StatusOK && rec.Body.String() != tc.want.output {
+				t.Errorf("got: %v, want: %v", rec.Body.String(), tc.want.output)
+			}
+		})
+	}
+}
@@ -0,0 +1,650 @@
+package rapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	openAPIVersion             = "3.1.0"
+	openAPISchemaRefPrefix     = "#/components/schemas/"
+	openAPIErrorSchemaName     = "renderer.ResponseError"
+	openAPIDefaultSecurityName = "bearerAuth"
+)
+
+var regexpInvalidOpenAPIComponentName = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+type OpenAPIOption struct {
+	Title       string
+	Description string
+	Version     string
+	Servers     []*OpenAPIServer
+	// WithAuth なエンドポイントに要求する SecurityScheme の名前。未指定の場合は bearerAuth
+	SecuritySchemeName string
+	// 未指定の場合は HTTP Bearer 認証
+	SecurityScheme *OpenAPISecurityScheme
+	// ScanUnion した型を enum として出力する場合に指定する (TypeScanner.ExportUnion の出力)
+	Unions map[string]*UnionStructure
+}
+
+type openAPIGenerator struct {
+	opt          *OpenAPIOption
+	types        map[string]*TypeStructure
+	operationIDs map[string]int
+}
+
+// GetRouterDefinition の出力から OpenAPI 3.1 のドキュメントを生成する
+func GenerateOpenAPI(routerDefinitions []*RouterDefinition, types map[string]*TypeStructure, opt *OpenAPIOption) *OpenAPIDocument {
+	if opt == nil {
+		opt = &OpenAPIOption{}
+	}
+	dst := &OpenAPIOption{
+		Title:              opt.Title,
+		Description:        opt.Description,
+		Version:            opt.Version,
+		Servers:            opt.Servers,
+		SecuritySchemeName: opt.SecuritySchemeName,
+		SecurityScheme:     opt.SecurityScheme,
+		Unions:             opt.Unions,
+	}
+	if dst.SecuritySchemeName == "" {
+		dst.SecuritySchemeName = openAPIDefaultSecurityName
+	}
+	if dst.SecurityScheme == nil {
+		dst.SecurityScheme = &OpenAPISecurityScheme{
+			Type:   "http",
+			Scheme: "bearer",
+		}
+	}
+	opt = dst
+
+	g := &openAPIGenerator{
+		opt:          opt,
+		types:        types,
+		operationIDs: map[string]int{},
+	}
+
+	doc := &OpenAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info: &OpenAPIInfo{
+			Title:       opt.Title,
+			Description: opt.Description,
+			Version:     opt.Version,
+		},
+		Servers: opt.Servers,
+		Paths:   map[string]OpenAPIPathItem{},
+		Components: &OpenAPIComponents{
+			Schemas:         map[string]*OpenAPISchema{},
+			SecuritySchemes: map[string]*OpenAPISecurityScheme{},
+		},
+	}
+
+	// struct の型は全て components に登録し、 $ref で参照する
+	for name, ts := range types {
+		if ts.Kind != TypeKindStruct {
+			continue
+		}
+		doc.Components.Schemas[openAPIComponentName(name)] = g.generateObjectSchema(ts)
+	}
+	doc.Components.Schemas[openAPIErrorSchemaName] = &OpenAPISchema{
+		Type: "object",
+		Properties: map[string]*OpenAPISchema{
+			"status":  {Type: "integer"},
+			"message": {Type: "string"},
+		},
+		Required: []string{"message", "status"},
+	}
+
+	withAuth := false
+	for _, rd := range routerDefinitions {
+		path, pathParams := convertOpenAPIPath(rd.FullPathName)
+		pathItem, ok := doc.Paths[path]
+		if !ok {
+			pathItem = OpenAPIPathItem{}
+			doc.Paths[path] = pathItem
+		}
+		pathItem[strings.ToLower(rd.Method)] = g.generateOperation(rd, path, pathParams)
+		withAuth = withAuth || rd.WithAuth
+	}
+	if withAuth {
+		doc.Components.SecuritySchemes[opt.SecuritySchemeName] = opt.SecurityScheme
+	}
+
+	return doc
+}
+
+// JSON で出力する
+func (d *OpenAPIDocument) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// YAML で出力する
+func (d *OpenAPIDocument) YAML() ([]byte, error) {
+	// json タグのフィールド名・順序をそのまま使うため、 JSON を経由して変換する
+	b, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(b, &node); err != nil {
+		return nil, err
+	}
+	resetYAMLNodeStyle(&node)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func resetYAMLNodeStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetYAMLNodeStyle(child)
+	}
+}
+
+func (g *openAPIGenerator) generateOperation(rd *RouterDefinition, path string, pathParams []*openAPIPathParam) *OpenAPIOperation {
+	op := &OpenAPIOperation{
+		OperationID: g.generateOperationID(rd.Method, path),
+		Summary:     rd.Summary,
+		Description: rd.Description,
+		Tags:        rd.Tags,
+		Deprecated:  rd.Deprecated,
+		Responses:   map[string]*OpenAPIResponse{},
+	}
+
+	var fields map[string]*TypeStructure
+	input := rd.InputTypeStructure
+	if input != nil && input.Kind == TypeKindStruct {
+		fields = g.resolveFields(input)
+	}
+
+	// パスパラメーター
+	for _, pathParam := range pathParams {
+		schema := &OpenAPISchema{Type: "string"}
+		if field, ok := fields[pathParam.name]; ok && field.TagName == "url" {
+			schema = g.generateFieldSchema(field)
+		}
+		if pathParam.pattern != "" && schema.Pattern == "" {
+			schema.Pattern = "^" + pathParam.pattern + "$"
+		}
+		op.Parameters = append(op.Parameters, &OpenAPIParameter{
+			Name:     pathParam.name,
+			In:       "path",
+			Required: true,
+			Schema:   schema,
+		})
+	}
+
+	// クエリパラメーター
+	// ボディのないメソッドの json フィールドはリクエストから受け取らないため出力しない
+	hasBody := hasRequestBody(rd.Method)
+	hasBodyField := false
+	for _, key := range sortedFieldKeys(fields) {
+		field := fields[key]
+		switch {
+		case field.TagName == "form":
+			name := key
+			// slice は parameter.GetForms の仕様に合わせて key[] で受け取る
+			if field.Kind == TypeKindArray {
+				name += "[]"
+			}
+			op.Parameters = append(op.Parameters, &OpenAPIParameter{
+				Name:     name,
+				In:       "query",
+				Required: hasValidateRule(field.Validate, "required"),
+				Schema:   g.generateFieldSchema(field),
+			})
+		case field.TagName == "json" && hasBody:
+			hasBodyField = true
+		}
+	}
+
+	// リクエストボディ
+	if hasBodyField {
+		op.RequestBody = &OpenAPIRequestBody{
+			Required: true,
+			Content: map[string]*OpenAPIMediaType{
+				"application/json": {Schema: g.generateSchema(input)},
+			},
+		}
+	}
+
+	// レスポンス
+	status := rd.StatusCode
+	if status == 0 {
+		status = http.StatusOK
+	}
+	res := &OpenAPIResponse{Description: http.StatusText(status)}
+	if rd.OutputTypeStructure != nil && status != http.StatusNoContent {
+		res.Content = map[string]*OpenAPIMediaType{
+			openAPIResponseContentType(rd.StreamKind): {Schema: g.generateSchema(rd.OutputTypeStructure)},
+		}
+	}
+	op.Responses[strconv.Itoa(status)] = res
+	errorSchema := &OpenAPISchema{Ref: openAPISchemaRefPrefix + openAPIErrorSchemaName}
+	for _, errRes := range rd.ErrorResponses {
+		description := errRes.Description
+		if description == "" {
+			description = http.StatusText(errRes.StatusCode)
+		}
+		schema := errorSchema
+		if errRes.TypeStructure != nil {
+			schema = g.generateSchema(errRes.TypeStructure)
+		}
+		op.Responses[strconv.Itoa(errRes.StatusCode)] = &OpenAPIResponse{
+			Description: description,
+			Content: map[string]*OpenAPIMediaType{
+				"application/json": {Schema: schema},
+			},
+		}
+	}
+	op.Responses["default"] = &OpenAPIResponse{
+		Description: "Error",
+		Content: map[string]*OpenAPIMediaType{
+			"application/json": {Schema: errorSchema},
+		},
+	}
+
+	if rd.WithAuth {
+		op.Security = []OpenAPISecurityRequirement{
+			{g.opt.SecuritySchemeName: []string{}},
+		}
+	}
+	return op
+}
+
+// パスとメソッドから一意な operationId を生成する
+func (g *openAPIGenerator) generateOperationID(method string, path string) string {
+	id := generateOperationName(method, path)
+	g.operationIDs[id]++
+	if cnt := g.operationIDs[id]; cnt > 1 {
+		id += strconv.Itoa(cnt)
+	}
+	return id
+}
+
+// パスとメソッドから操作名を生成する (例: GET /users/{userID} -> getUsersByUserID)
+func generateOperationName(method string, path string) string {
+	name := strings.ToLower(method)
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			name += "By" + toUpperCamel(segment[1:len(segment)-1])
+			continue
+		}
+		name += toUpperCamel(segment)
+	}
+	return name
+}
+
+// 型情報の fields を取得する。 field 側の型情報は fields が削除されているため types から引き直す
+func (g *openAPIGenerator) resolveFields(ts *TypeStructure) map[string]*TypeStructure {
+	if len(ts.Fields) == 0 {
+		if v, ok := g.types[ts.Name]; ok {
+			return v.Fields
+		}
+	}
+	return ts.Fields
+}
+
+// struct の JSON 表現となる object の schema を生成する
+func (g *openAPIGenerator) generateObjectSchema(ts *TypeStructure) *OpenAPISchema {
+	schema := &OpenAPISchema{
+		Type:       "object",
+		Properties: map[string]*OpenAPISchema{},
+	}
+	for _, key := range sortedFieldKeys(ts.Fields) {
+		field := ts.Fields[key]
+		// url, form で受け取るフィールドはボディに含めない
+		if field.TagName == "url" || field.TagName == "form" {
+			continue
+		}
+		schema.Properties[key] = g.generateFieldSchema(field)
+		if !field.OmitEmpty || hasValidateRule(field.Validate, "required") {
+			schema.Required = append(schema.Required, key)
+		}
+	}
+	return schema
+}
+
+// struct の field の schema を生成する (validate タグを制約として反映する)
+func (g *openAPIGenerator) generateFieldSchema(field *TypeStructure) *OpenAPISchema {
+	schema := g.generateSchema(field)
+	rules, diveRules := parseValidateTag(field.Validate)
+	applyValidateRules(schema, field.Kind, rules)
+	if len(diveRules) > 0 && field.ElemType != nil {
+		var elemSchema *OpenAPISchema
+		switch {
+		case schema.Items != nil:
+			elemSchema = schema.Items
+		case schema.AdditionalProperties != nil:
+			elemSchema = schema.AdditionalProperties
+		}
+		if elemSchema != nil {
+			applyValidateRules(elemSchema, field.ElemType.Kind, diveRules)
+		}
+	}
+	return schema
+}
+
+func (g *openAPIGenerator) generateSchema(ts *TypeStructure) *OpenAPISchema {
+	if ts == nil {
+		return &OpenAPISchema{}
+	}
+
+	if us, ok := g.opt.Unions[ts.GoTypeName]; ok {
+		schema := &OpenAPISchema{Type: openAPIPrimitiveType(us.Kind)}
+		schema.Enum = append(schema.Enum, us.Values...)
+		return schema
+	}
+
+	switch ts.Kind {
+	case TypeKindArray:
+		// []byte は encoding/json で base64 の文字列になる
+		if ts.ElemType != nil && ts.ElemType.GoTypeName == "uint8" && !regexpGoArrayType.MatchString(ts.GoTypeName) {
+			return &OpenAPISchema{Type: "string", ContentEncoding: "base64"}
+		}
+		return &OpenAPISchema{
+			Type:  "array",
+			Items: g.generateSchema(ts.ElemType),
+		}
+	case TypeKindMap:
+		return &OpenAPISchema{
+			Type:                 "object",
+			AdditionalProperties: g.generateSchema(ts.ElemType),
+		}
+	case TypeKindStruct:
+		return &OpenAPISchema{Ref: openAPISchemaRefPrefix + openAPIComponentName(ts.Name)}
+	case TypeKindDateTime:
+		return &OpenAPISchema{Type: "string", Format: "date-time"}
+	default:
+		return &OpenAPISchema{Type: openAPIPrimitiveType(ts.Kind)}
+	}
+}
+
+var regexpGoArrayType = regexp.MustCompile(`^\[\d+\]`)
+
+func openAPIPrimitiveType(kind string) string {
+	switch kind {
+	case TypeKindString:
+		return "string"
+	case TypeKindInt:
+		return "integer"
+	case TypeKindFloat:
+		return "number"
+	case TypeKindBool:
+		return "boolean"
+	default:
+		return ""
+	}
+}
+
+// components のキーに使用できない文字を置換する
+func openAPIComponentName(name string) string {
+	return regexpInvalidOpenAPIComponentName.ReplaceAllString(name, "_")
+}
+
+func hasRequestBody(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete, http.MethodOptions, http.MethodTrace:
+		return false
+	default:
+		return true
+	}
+}
+
+func sortedFieldKeys(fields map[string]*TypeStructure) []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func toUpperCamel(s string) string {
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	dst := ""
+	for _, word := range words {
+		rs := []rune(word)
+		rs[0] = unicode.ToUpper(rs[0])
+		dst += string(rs)
+	}
+	return dst
+}
+
+type openAPIPathParam struct {
+	name    string
+	pattern string
+}
+
+// chi のルーティングパターンを OpenAPI のパス形式に変換する (例: //users/{id:[0-9]+}/ -> /users/{id})
+func convertOpenAPIPath(fullPath string) (string, []*openAPIPathParam) {
+	params := []*openAPIPathParam{}
+	var sb strings.Builder
+	for i := 0; i < len(fullPath); i++ {
+		c := fullPath[i]
+		if c != '{' {
+			// 連続するスラッシュはまとめる
+			if c == '/' && sb.Len() > 0 && strings.HasSuffix(sb.String(), "/") {
+				continue
+			}
+			sb.WriteByte(c)
+			continue
+		}
+
+		// 正規表現中の {} を考慮して対応する閉じ括弧を探す
+		depth := 0
+		end := -1
+		for j := i; j < len(fullPath); j++ {
+			switch fullPath[j] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+			}
+			if depth == 0 {
+				end = j
+				break
+			}
+		}
+		if end < 0 {
+			sb.WriteString(fullPath[i:])
+			break
+		}
+		param := &openAPIPathParam{}
+		param.name, param.pattern, _ = strings.Cut(fullPath[i+1:end], ":")
+		params = append(params, param)
+		sb.WriteString(fmt.Sprintf("{%s}", param.name))
+		i = end
+	}
+
+	path := sb.String()
+	if path == "" {
+		path = "/"
+	}
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return path, params
+}
+
+type validateRule struct {
+	name  string
+	param string
+}
+
+// validate タグをパースする。 dive 以降は要素に対するルールとして分けて返す
+func parseValidateTag(tag string) ([]*validateRule, []*validateRule) {
+	rules := []*validateRule{}
+	diveRules := []*validateRule{}
+	dst := &rules
+	for _, v := range strings.Split(tag, ",") {
+		if v == "" {
+			continue
+		}
+		if v == "dive" {
+			if dst == &diveRules {
+				// 2 階層以上の dive は対象外
+				break
+			}
+			dst = &diveRules
+			continue
+		}
+		// OR 条件は表現できないため無視する
+		if strings.Contains(v, "|") {
+			continue
+		}
+		name, param, _ := strings.Cut(v, "=")
+		*dst = append(*dst, &validateRule{name, param})
+	}
+	return rules, diveRules
+}
+
+func hasValidateRule(tag string, name string) bool {
+	rules, _ := parseValidateTag(tag)
+	for _, rule := range rules {
+		if rule.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validate のルールを JSON Schema の制約に変換して schema に反映する
+func applyValidateRules(schema *OpenAPISchema, kind string, rules []*validateRule) {
+	for _, rule := range rules {
+		num, numErr := strconv.ParseFloat(rule.param, 64)
+		isNum := numErr == nil
+		switch rule.name {
+		case "min", "max", "len", "eq", "gt", "gte", "lt", "lte":
+			if !isNum {
+				continue
+			}
+			applyValidateRangeRule(schema, kind, rule.name, num)
+		case "oneof":
+			for _, v := range strings.Fields(rule.param) {
+				if kind == TypeKindInt || kind == TypeKindFloat {
+					if n, err := strconv.ParseFloat(v, 64); err == nil {
+						schema.Enum = append(schema.Enum, n)
+					}
+					continue
+				}
+				schema.Enum = append(schema.Enum, v)
+			}
+		case "email":
+			schema.Format = "email"
+		case "url", "uri":
+			schema.Format = "uri"
+		case "uuid", "uuid4":
+			schema.Format = "uuid"
+		case "ipv4":
+			schema.Format = "ipv4"
+		case "ipv6":
+			schema.Format = "ipv6"
+		case "hostname":
+			schema.Format = "hostname"
+		case "numeric":
+			addSchemaPattern(schema, `^[-+]?[0-9]+(?:\.[0-9]+)?$`)
+		case "alpha":
+			addSchemaPattern(schema, `^[a-zA-Z]+$`)
+		case "alphanum":
+			addSchemaPattern(schema, `^[a-zA-Z0-9]+$`)
+		case "hexcolor":
+			addSchemaPattern(schema, `^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+		case "startswith":
+			addSchemaPattern(schema, "^"+regexp.QuoteMeta(rule.param))
+		case "endswith":
+			addSchemaPattern(schema, regexp.QuoteMeta(rule.param)+"$")
+		case "contains":
+			addSchemaPattern(schema, regexp.QuoteMeta(rule.param))
+		}
+	}
+}
+
+func applyValidateRangeRule(schema *OpenAPISchema, kind string, name string, num float64) {
+	switch kind {
+	case TypeKindInt, TypeKindFloat:
+		switch name {
+		case "min", "gte":
+			schema.Minimum = &num
+		case "max", "lte":
+			schema.Maximum = &num
+		case "gt":
+			schema.ExclusiveMinimum = &num
+		case "lt":
+			schema.ExclusiveMaximum = &num
+		case "len", "eq":
+			schema.Enum = []any{num}
+		}
+		return
+	}
+
+	// string, array, map は長さに対する制約
+	var minDst, maxDst **int
+	switch kind {
+	case TypeKindString:
+		minDst, maxDst = &schema.MinLength, &schema.MaxLength
+	case TypeKindArray:
+		minDst, maxDst = &schema.MinItems, &schema.MaxItems
+	case TypeKindMap:
+		minDst, maxDst = &schema.MinProperties, &schema.MaxProperties
+	default:
+		return
+	}
+	n := int(num)
+	switch name {
+	case "min", "gte":
+		*minDst = &n
+	case "max", "lte":
+		*maxDst = &n
+	case "gt":
+		n++
+		*minDst = &n
+	case "lt":
+		n--
+		*maxDst = &n
+	case "len", "eq":
+		*minDst = &n
+		*maxDst = &n
+	}
+}
+
+// pattern は 1 つしか指定できないため、 2 つ目以降は allOf に追加する
+func addSchemaPattern(schema *OpenAPISchema, pattern string) {
+	if schema.Pattern == "" {
+		schema.Pattern = pattern
+		return
+	}
+	schema.AllOf = append(schema.AllOf, &OpenAPISchema{Pattern: pattern})
+}
+
+// ストリーミングの場合、スキーマは 1 件分のデータを表す
+func openAPIResponseContentType(kind StreamKind) string {
+	switch kind {
+	case StreamKindSSE:
+		return "text/event-stream"
+	case StreamKindNDJSON:
+		return "application/x-ndjson"
+	default:
+		return "application/json"
+	}
+}
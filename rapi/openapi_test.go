@@ -0,0 +1,282 @@
+package rapi_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"testing"
+
+	"github.com/rabee-inc/go-pkg/rapi"
+)
+
+type openAPIUserInput struct {
+	UserID string   `url:"user_id" json:"-"`
+	Fields []string `form:"fields"`
+	Limit  int      `form:"limit" validate:"required,min=1"`
+	Name   string   `json:"name" validate:"required"`
+}
+
+type openAPIUser struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+type openAPIValidationError struct {
+	Field string `json:"field"`
+}
+
+func newOpenAPIHandler() rapi.HandlerMethod[openAPIUserInput] {
+	return rapi.NewHandlerMethod(func(ctx context.Context, param *openAPIUserInput) (*openAPIUser, error) {
+		return &openAPIUser{}, nil
+	})
+}
+
+func Test_GenerateOpenAPI(t *testing.T) {
+	type args struct {
+		register func(r rapi.Router)
+		method   string
+		path     string
+	}
+	type want struct {
+		parameters      []string
+		requestBody     string
+		responses       []string
+		security        bool
+		securitySchemes []string
+		schemas         map[string]string
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "GETはパスとクエリパラメーターのみ",
+			args: args{
+				register: func(r rapi.Router) {
+					r.Get("/users/{user_id:[a-z0-9]+}", newOpenAPIHandler())
+				},
+				method: "get",
+				path:   "/users/{user_id}",
+			},
+			want: want{
+				parameters: []string{
+					"path:user_id:true:string:^[a-z0-9]+$",
+					"query:fields[]:false:array:",
+					"query:limit:true:integer:",
+				},
+				responses:       []string{"200:#/components/schemas/rapi_test.openAPIUser", "default:#/components/schemas/renderer.ResponseError"},
+				securitySchemes: []string{},
+			},
+		},
+		{
+			name: "POSTはjsonフィールドをボディで受け取る",
+			args: args{
+				register: func(r rapi.Router) {
+					h := newOpenAPIHandler()
+					h.SetStatusCode(http.StatusCreated)
+					r.Post("/users/{user_id}", h)
+				},
+				method: "post",
+				path:   "/users/{user_id}",
+			},
+			want: want{
+				parameters: []string{
+					"path:user_id:true:string:",
+					"query:fields[]:false:array:",
+					"query:limit:true:integer:",
+				},
+				requestBody:     "#/components/schemas/rapi_test.openAPIUserInput",
+				responses:       []string{"201:#/components/schemas/rapi_test.openAPIUser", "default:#/components/schemas/renderer.ResponseError"},
+				securitySchemes: []string{},
+				schemas: map[string]string{
+					"rapi_test.openAPIUserInput": "[name]:[name]",
+					"rapi_test.openAPIUser":      "[id name]:[id]",
+				},
+			},
+		},
+		{
+			name: "認証",
+			args: args{
+				register: func(r rapi.Router) {
+					r.Route("/users", func(r rapi.Router) {
+						r.Auth().Delete("/{user_id}", newOpenAPIHandler())
+					})
+				},
+				method: "delete",
+				path:   "/users/{user_id}",
+			},
+			want: want{
+				parameters: []string{
+					"path:user_id:true:string:",
+					"query:fields[]:false:array:",
+					"query:limit:true:integer:",
+				},
+				responses:       []string{"200:#/components/schemas/rapi_test.openAPIUser", "default:#/components/schemas/renderer.ResponseError"},
+				security:        true,
+				securitySchemes: []string{"bearerAuth"},
+			},
+		},
+		{
+			name: "エラーレスポンス",
+			args: args{
+				register: func(r rapi.Router) {
+					h := newOpenAPIHandler()
+					h.AddErrorResponse(http.StatusNotFound, "", nil)
+					h.AddErrorResponse(http.StatusBadRequest, "invalid", &openAPIValidationError{})
+					r.Get("/users/{user_id}", h)
+				},
+				method: "get",
+				path:   "/users/{user_id}",
+			},
+			want: want{
+				parameters: []string{
+					"path:user_id:true:string:",
+					"query:fields[]:false:array:",
+					"query:limit:true:integer:",
+				},
+				responses: []string{
+					"200:#/components/schemas/rapi_test.openAPIUser",
+					"400:#/components/schemas/rapi_test.openAPIValidationError",
+					"404:#/components/schemas/renderer.ResponseError",
+					"default:#/components/schemas/renderer.ResponseError",
+				},
+				securitySchemes: []string{},
+				schemas: map[string]string{
+					"renderer.ResponseError": "[message status]:[message status]",
+				},
+			},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			r := rapi.NewRouter()
+			tc.args.register(r)
+			routerDefinitions, types := r.GetRouterDefinition()
+			doc := rapi.GenerateOpenAPI(routerDefinitions, types, nil)
+
+			op := doc.Paths[tc.args.path][tc.args.method]
+			if op == nil {
+				t.Fatalf("got: %v, want: %s %s", doc.Paths, tc.args.method, tc.args.path)
+			}
+
+			parameters := []string{}
+			for _, p := range op.Parameters {
+				parameters = append(parameters, fmt.Sprintf("%s:%s:%v:%s:%s", p.In, p.Name, p.Required, p.Schema.Type, p.Schema.Pattern))
+			}
+			if fmt.Sprint(parameters) != fmt.Sprint(tc.want.parameters) {
+				t.Errorf("got: %v, want: %v", parameters, tc.want.parameters)
+			}
+
+			requestBody := ""
+			if op.RequestBody != nil {
+				requestBody = op.RequestBody.Content["application/json"].Schema.Ref
+			}
+			if requestBody != tc.want.requestBody {
+				t.Errorf("got: %v, want: %v", requestBody, tc.want.requestBody)
+			}
+
+			responses := []string{}
+			for status, res := range op.Responses {
+				responses = append(responses, fmt.Sprintf("%s:%s", status, res.Content["application/json"].Schema.Ref))
+			}
+			sort.Strings(responses)
+			if fmt.Sprint(responses) != fmt.Sprint(tc.want.responses) {
+				t.Errorf("got: %v, want: %v", responses, tc.want.responses)
+			}
+
+			if security := len(op.Security) > 0; security != tc.want.security {
+				t.Errorf("got: %v, want: %v", security, tc.want.security)
+			}
+			securitySchemes := []string{}
+			for name := range doc.Components.SecuritySchemes {
+				securitySchemes = append(securitySchemes, name)
+			}
+			if fmt.Sprint(securitySchemes) != fmt.Sprint(tc.want.securitySchemes) {
+				t.Errorf("got: %v, want: %v", securitySchemes, tc.want.securitySchemes)
+			}
+
+			for name, want := range tc.want.schemas {
+				schema := doc.Components.Schemas[name]
+				if schema == nil {
+					t.Errorf("got: nil, want: %s", name)
+					continue
+				}
+				properties := []string{}
+				for key := range schema.Properties {
+					properties = append(properties, key)
+				}
+				sort.Strings(properties)
+				if got := fmt.Sprintf("%v:%v", properties, schema.Required); got != want {
+					t.Errorf("got: %v, want: %v", got, want)
+				}
+			}
+		})
+	}
+}
+
+func Test_GenerateOpenAPI_Option(t *testing.T) {
+	type args struct {
+		opt *rapi.OpenAPIOption
+	}
+	type want struct {
+		opt             rapi.OpenAPIOption
+		securitySchemes []string
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "デフォルト値は渡したオプションに書き込まない",
+			args: args{
+				opt: &rapi.OpenAPIOption{Title: "api"},
+			},
+			want: want{
+				opt:             rapi.OpenAPIOption{Title: "api"},
+				securitySchemes: []string{"bearerAuth"},
+			},
+		},
+		{
+			name: "SecuritySchemeName を指定",
+			args: args{
+				opt: &rapi.OpenAPIOption{SecuritySchemeName: "apiKey"},
+			},
+			want: want{
+				opt:             rapi.OpenAPIOption{SecuritySchemeName: "apiKey"},
+				securitySchemes: []string{"apiKey"},
+			},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			r := rapi.NewRouter()
+			r.Auth().Get("/users/{user_id}", newOpenAPIHandler())
+			routerDefinitions, types := r.GetRouterDefinition()
+			doc := rapi.GenerateOpenAPI(routerDefinitions, types, tc.args.opt)
+
+			if fmt.Sprintf("%+v", *tc.args.opt) != fmt.Sprintf("%+v", tc.want.opt) {
+				t.Errorf("got: %+v, want: %+v", *tc.args.opt, tc.want.opt)
+			}
+			securitySchemes := []string{}
+			for name := range doc.Components.SecuritySchemes {
+				securitySchemes = append(securitySchemes, name)
+			}
+			if fmt.Sprint(securitySchemes) != fmt.Sprint(tc.want.securitySchemes) {
+				t.Errorf("got: %v, want: %v", securitySchemes, tc.want.securitySchemes)
+			}
+		})
+	}
+}
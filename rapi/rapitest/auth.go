@@ -0,0 +1,36 @@
+package rapitest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/rabee-inc/go-pkg/firebaseauth"
+)
+
+const authHeaderPrefix = "Bearer user="
+
+// Router.SetAuthMiddleware にセットする、 Client.WithUserID で指定したユーザーIDで認証する Middleware
+func AuthMiddleware() func(http.Handler) http.Handler {
+	return firebaseauth.NewMiddleware(&fakeAuthService{}, false).Handle
+}
+
+// Router.SetOptAuthMiddleware にセットする、 Client.WithUserID で指定したユーザーIDで認証する Middleware
+func OptAuthMiddleware() func(http.Handler) http.Handler {
+	return firebaseauth.NewMiddleware(&fakeAuthService{}, true).Handle
+}
+
+// Authorization ヘッダーのユーザーIDをそのまま認証結果とする firebaseauth.Service
+type fakeAuthService struct{}
+
+func (s *fakeAuthService) Authentication(ctx context.Context, ah string) (string, map[string]any, error) {
+	if !strings.HasPrefix(ah, authHeaderPrefix) || len(ah) == len(authHeaderPrefix) {
+		return "", nil, errors.New("invalid authorization header")
+	}
+	return ah[len(authHeaderPrefix):], map[string]any{}, nil
+}
+
+func (s *fakeAuthService) SetCustomClaims(ctx context.Context, userID string, claims map[string]any) error {
+	return nil
+}
@@ -0,0 +1,244 @@
+package rapitest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/rabee-inc/go-pkg/rapi"
+	"github.com/rabee-inc/go-pkg/renderer"
+	"github.com/rabee-inc/go-pkg/util"
+)
+
+// Router のエンドポイントをネットワークを介さずに呼び出すクライアント
+type Client struct {
+	handler http.Handler
+	header  http.Header
+}
+
+func NewClient(router rapi.Router) *Client {
+	return &Client{
+		handler: router,
+		header:  http.Header{},
+	}
+}
+
+// 指定したユーザーIDで認証した状態でリクエストするクライアントを返す。
+// Router には AuthMiddleware, OptAuthMiddleware をセットしておくこと
+func (c *Client) WithUserID(userID string) *Client {
+	return c.WithHeader("Authorization", authHeaderPrefix+userID)
+}
+
+// 未認証の状態でリクエストするクライアントを返す
+func (c *Client) WithoutAuth() *Client {
+	dst := c.clone()
+	dst.header.Del("Authorization")
+	return dst
+}
+
+// ヘッダーを追加したクライアントを返す
+func (c *Client) WithHeader(key, value string) *Client {
+	dst := c.clone()
+	dst.header.Set(key, value)
+	return dst
+}
+
+func (c *Client) clone() *Client {
+	return &Client{
+		handler: c.handler,
+		header:  c.header.Clone(),
+	}
+}
+
+// レスポンス
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// リクエストを実行してレスポンスをそのまま返す。
+// path は "/users/{user_id}" のようなパターンでもよく、 input の url タグのフィールドで置き換える。
+// input の form タグのフィールドはクエリに、ボディを持つメソッドの場合は input を JSON にしてボディにセットする
+func (c *Client) Do(ctx context.Context, method, path string, input any) (*Response, error) {
+	req, err := c.newRequest(ctx, method, path, input)
+	if err != nil {
+		return nil, err
+	}
+	rec := httptest.NewRecorder()
+	c.handler.ServeHTTP(rec, req)
+	res := rec.Result()
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Body:       body,
+	}, nil
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, input any) (*http.Request, error) {
+	var body io.Reader
+	query := url.Values{}
+	if input != nil && reflect.Indirect(reflect.ValueOf(input)).Kind() == reflect.Struct {
+		urlParams := map[string]string{}
+		_ = util.EachTaggedFields(input, "url", func(tagValue string, reflectParam reflect.Value, fieldNum int) error {
+			urlParams[tagValue] = fmt.Sprint(reflectParam.Field(fieldNum).Interface())
+			return nil
+		})
+		path = fillPathParams(path, urlParams)
+
+		_ = util.EachTaggedFields(input, "form", func(tagValue string, reflectParam reflect.Value, fieldNum int) error {
+			appendQuery(query, tagValue, reflectParam.Field(fieldNum))
+			return nil
+		})
+	}
+	if input != nil && hasRequestBody(method) {
+		b, err := json.Marshal(input)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = c.header.Clone()
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// パスパターンの {name} または {name:regexp} を値で置き換える
+func fillPathParams(pattern string, params map[string]string) string {
+	var sb strings.Builder
+	for {
+		start := strings.Index(pattern, "{")
+		if start < 0 {
+			sb.WriteString(pattern)
+			return sb.String()
+		}
+		// 正規表現に含まれる {} を考慮して対応する閉じ括弧を探す
+		end := -1
+		depth := 0
+		for i := start; i < len(pattern); i++ {
+			switch pattern[i] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+			}
+			if depth == 0 {
+				end = i
+				break
+			}
+		}
+		if end < 0 {
+			sb.WriteString(pattern)
+			return sb.String()
+		}
+		name := strings.SplitN(pattern[start+1:end], ":", 2)[0]
+		sb.WriteString(pattern[:start])
+		sb.WriteString(url.PathEscape(params[name]))
+		pattern = pattern[end+1:]
+	}
+}
+
+// parameter.GetForms の形式でクエリに追加する。ゼロ値は追加しない
+func appendQuery(query url.Values, key string, v reflect.Value) {
+	if v.IsZero() {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			query.Add(key+"[]", fmt.Sprint(v.Index(i).Interface()))
+		}
+	case reflect.Ptr:
+		appendQuery(query, key, v.Elem())
+	default:
+		query.Add(key, fmt.Sprint(v.Interface()))
+	}
+}
+
+func hasRequestBody(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete, http.MethodOptions, http.MethodTrace:
+		return false
+	default:
+		return true
+	}
+}
+
+// エラーレスポンス
+type Error struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+func (e *Error) Error() string {
+	res := &renderer.ResponseError{}
+	if err := json.Unmarshal(e.Body, res); err == nil && res.Message != "" {
+		return fmt.Sprintf("%d %s", e.StatusCode, res.Message)
+	}
+	return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// エラーレスポンスのボディを dst にデコードする
+func (e *Error) Decode(dst any) error {
+	return json.Unmarshal(e.Body, dst)
+}
+
+// renderer.ResponseError としてデコードする
+func (e *Error) ResponseError() *renderer.ResponseError {
+	res := &renderer.ResponseError{}
+	if err := e.Decode(res); err != nil {
+		return renderer.NewResponseError(e.StatusCode, string(e.Body))
+	}
+	return res
+}
+
+// リクエストを実行して、成功時はレスポンスボディを O にデコードして返す。
+// ステータスコードが 400 以上の場合は *Error を返す
+func Call[I, O any](ctx context.Context, c *Client, method, path string, input *I) (*O, error) {
+	var in any
+	if input != nil {
+		in = input
+	}
+	res, err := c.Do(ctx, method, path, in)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode >= http.StatusBadRequest {
+		return nil, &Error{
+			StatusCode: res.StatusCode,
+			Header:     res.Header,
+			Body:       res.Body,
+		}
+	}
+	output := new(O)
+	if len(res.Body) == 0 {
+		return output, nil
+	}
+	if err := json.Unmarshal(res.Body, output); err != nil {
+		return nil, err
+	}
+	return output, nil
+}
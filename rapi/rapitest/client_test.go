@@ -0,0 +1,198 @@
+package rapitest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/rabee-inc/go-pkg/errcode"
+	"github.com/rabee-inc/go-pkg/firebaseauth"
+	"github.com/rabee-inc/go-pkg/rapi"
+	"github.com/rabee-inc/go-pkg/rapi/rapitest"
+)
+
+type GetUserInput struct {
+	UserID string   `url:"user_id"`
+	Fields []string `form:"fields"`
+}
+
+type UpdateUserInput struct {
+	UserID string `url:"user_id" json:"-"`
+	Name   string `json:"name" validate:"required"`
+}
+
+type User struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Fields   []string `json:"fields,omitempty"`
+	ViewerID string   `json:"viewer_id"`
+}
+
+func newRouter() rapi.Router {
+	r := rapi.NewRouterWithDefaults()
+	r.SetAuthMiddleware(rapitest.AuthMiddleware())
+	r.SetOptAuthMiddleware(rapitest.OptAuthMiddleware())
+	r.Route("/v1", func(r rapi.Router) {
+		r.OptAuth().Get("/users/{user_id}", rapi.NewHandlerMethod(func(ctx context.Context, param *GetUserInput) (*User, error) {
+			if param.UserID == "unknown" {
+				return nil, errcode.Set(errors.New("user not found"), http.StatusNotFound)
+			}
+			return &User{
+				ID:       param.UserID,
+				Fields:   param.Fields,
+				ViewerID: firebaseauth.GetUserID(ctx),
+			}, nil
+		}))
+		r.Auth().Put("/users/{user_id:[a-z0-9]+}", rapi.NewHandlerMethod(func(ctx context.Context, param *UpdateUserInput) (*User, error) {
+			return &User{
+				ID:       param.UserID,
+				Name:     param.Name,
+				ViewerID: firebaseauth.GetUserID(ctx),
+			}, nil
+		}))
+	})
+	return r
+}
+
+func Test_Call(t *testing.T) {
+	type args struct {
+		userID string
+		method string
+		path   string
+		input  any
+	}
+	type want struct {
+		output *User
+		status int
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "未認証でのOptAuth",
+			args: args{
+				method: http.MethodGet,
+				path:   "/v1/users/{user_id}",
+				input:  &GetUserInput{UserID: "user1", Fields: []string{"a", "b"}},
+			},
+			want: want{
+				output: &User{ID: "user1", Fields: []string{"a", "b"}},
+			},
+		},
+		{
+			name: "認証済みでのOptAuth",
+			args: args{
+				userID: "viewer1",
+				method: http.MethodGet,
+				path:   "/v1/users/{user_id}",
+				input:  &GetUserInput{UserID: "user1"},
+			},
+			want: want{
+				output: &User{ID: "user1", ViewerID: "viewer1"},
+			},
+		},
+		{
+			name: "エラーレスポンス",
+			args: args{
+				method: http.MethodGet,
+				path:   "/v1/users/unknown",
+				input:  &GetUserInput{},
+			},
+			want: want{
+				status: http.StatusNotFound,
+			},
+		},
+		{
+			name: "認証済みでのAuth",
+			args: args{
+				userID: "viewer1",
+				method: http.MethodPut,
+				path:   "/v1/users/{user_id:[a-z0-9]+}",
+				input:  &UpdateUserInput{UserID: "user1", Name: "name"},
+			},
+			want: want{
+				output: &User{ID: "user1", Name: "name", ViewerID: "viewer1"},
+			},
+		},
+		{
+			name: "未認証でのAuth",
+			args: args{
+				method: http.MethodPut,
+				path:   "/v1/users/{user_id:[a-z0-9]+}",
+				input:  &UpdateUserInput{UserID: "user1", Name: "name"},
+			},
+			want: want{
+				status: http.StatusUnauthorized,
+			},
+		},
+		{
+			name: "バリデーションエラー",
+			args: args{
+				userID: "viewer1",
+				method: http.MethodPut,
+				path:   "/v1/users/{user_id:[a-z0-9]+}",
+				input:  &UpdateUserInput{UserID: "user1"},
+			},
+			want: want{
+				status: http.StatusBadRequest,
+			},
+		},
+	}
+
+	// 実行
+	c := rapitest.NewClient(newRouter())
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			client := c
+			if tc.args.userID != "" {
+				client = c.WithUserID(tc.args.userID)
+			}
+
+			var output *User
+			var err error
+			switch input := tc.args.input.(type) {
+			case *GetUserInput:
+				output, err = rapitest.Call[GetUserInput, User](ctx, client, tc.args.method, tc.args.path, input)
+			case *UpdateUserInput:
+				output, err = rapitest.Call[UpdateUserInput, User](ctx, client, tc.args.method, tc.args.path, input)
+			}
+
+			status := 0
+			var resErr *rapitest.Error
+			if errors.As(err, &resErr) {
+				status = resErr.StatusCode
+				if resErr.ResponseError().Status != tc.want.status {
+					t.Errorf("got: %v, want: %v", resErr.ResponseError().Status, tc.want.status)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if status != tc.want.status {
+				t.Errorf("got: %v, want: %v", status, tc.want.status)
+			}
+			if !reflect.DeepEqual(output, tc.want.output) {
+				t.Errorf("got: %v, want: %v", output, tc.want.output)
+			}
+		})
+	}
+}
+
+func Test_Client_WithoutAuth(t *testing.T) {
+	ctx := context.Background()
+	c := rapitest.NewClient(newRouter()).WithUserID("viewer1").WithoutAuth()
+	output, err := rapitest.Call[GetUserInput, User](ctx, c, http.MethodGet, "/v1/users/{user_id}", &GetUserInput{UserID: "user1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output.ViewerID != "" {
+		t.Errorf("got: %v, want: %v", output.ViewerID, "")
+	}
+}
@@ -1,7 +1,9 @@
 package rapi
 
 import (
+	"context"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi"
 )
@@ -10,8 +12,22 @@ type Router interface {
 	http.Handler
 	GetChiRouter() chi.Router
 	Route(pattern string, fn func(r Router)) Router
+	// "/{version}" 以下にバージョンのグループを作成する。 opt で非推奨、廃止の日時を設定する (nil 可)
+	Version(version string, opt *VersionOption, fn func(r Router)) Router
+	// パスにバージョンが含まれないリクエストを、 header で指定されたバージョン (未指定の場合は defaultVersion) にルーティングする。
+	// バージョンのないエンドポイントに一致するリクエストはそのままルーティングする
+	SetVersionNegotiation(header string, defaultVersion string)
 	SetAuthMiddleware(middlewares ...func(http.Handler) http.Handler)
 	SetOptAuthMiddleware(middlewares ...func(http.Handler) http.Handler)
+	// HandlerMethod で個別に設定されていない場合に使用するリクエストパラメーター受け取り処理をセット。
+	// SetDefault* に nil を渡すと panic する。全て標準の処理にする場合は NewRouterWithDefaults を使用する
+	SetDefaultInputFunc(func(ctx context.Context, r *http.Request, param any) error)
+	// HandlerMethod で個別に設定されていない場合に使用するバリデーション処理をセット
+	SetDefaultValidateFunc(func(ctx context.Context, param any) error)
+	// HandlerMethod で個別に設定されていない場合に使用するエラーをレンダリングする処理をセット
+	SetDefaultHandleErrorFunc(func(ctx context.Context, w http.ResponseWriter, r *http.Request, err error))
+	// HandlerMethod で個別に設定されていない場合に使用するレスポンスをレンダリングする処理をセット
+	SetDefaultRenderFunc(func(ctx context.Context, w http.ResponseWriter, r *http.Request, output any))
 	Use(middlewares ...func(http.Handler) http.Handler)
 	With(middlewares ...func(http.Handler) http.Handler) Router
 	Auth() Router
@@ -25,6 +41,8 @@ type Router interface {
 	Post(pattern string, re RouterElement)
 	Put(pattern string, re RouterElement)
 	Trace(pattern string, re RouterElement)
+	// GetRouterDefinition で value の型を mapping の型情報として扱うように登録する
+	AddTypeMapping(value any, mapping *TypeStructure)
 	// router のエンドポイントと input, output の型定義を出力する
 	GetRouterDefinition() ([]*RouterDefinition, map[string]*TypeStructure)
 }
@@ -36,4 +54,24 @@ type RouterDefinition struct {
 	CurrentPathName     string         `json:"current_path_name"`
 	Method              string         `json:"method"`
 	WithAuth            bool           `json:"with_auth"`
+	Summary             string         `json:"summary,omitempty"`
+	Description         string         `json:"description,omitempty"`
+	Tags                []string       `json:"tags,omitempty"`
+	Deprecated          bool           `json:"deprecated,omitempty"`
+	// 成功時のステータスコード
+	StatusCode     int                        `json:"status_code"`
+	ErrorResponses []*ErrorResponseDefinition `json:"error_responses,omitempty"`
+	// ストリーミングで出力する場合の形式。 OutputTypeStructure は 1 件分のデータの型
+	StreamKind StreamKind `json:"stream_kind,omitempty"`
+	// Router.Version で作成したグループのバージョン。バージョンのないエンドポイントの場合は空文字
+	Version string `json:"version,omitempty"`
+	// バージョンが廃止される日時
+	Sunset *time.Time `json:"sunset,omitempty"`
+}
+
+type ErrorResponseDefinition struct {
+	StatusCode  int    `json:"status_code"`
+	Description string `json:"description,omitempty"`
+	// nil の場合は renderer.ResponseError
+	TypeStructure *TypeStructure `json:"type_structure,omitempty"`
 }
@@ -3,6 +3,7 @@ package rapi
 import (
 	"context"
 	"net/http"
+	"time"
 )
 
 type RouterElement interface {
@@ -11,6 +12,33 @@ type RouterElement interface {
 	GetEmptyOutput() any
 }
 
+// ドキュメント生成用のメタデータを持つ RouterElement
+type RouterElementWithMetadata interface {
+	RouterElement
+	GetMetadata() *RouterMetadata
+}
+
+// エンドポイントのメタデータ
+type RouterMetadata struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Deprecated  bool
+	// 成功時のステータスコード。 0 の場合は 200 として扱う
+	StatusCode     int
+	ErrorResponses []*RouterErrorResponse
+	// ストリーミングで出力する場合の形式。通常のエンドポイントの場合は空文字
+	StreamKind StreamKind
+}
+
+// エンドポイントが返すエラーレスポンスの定義
+type RouterErrorResponse struct {
+	StatusCode  int
+	Description string
+	// レスポンスボディの型情報の取得に使用する値。 nil の場合は renderer.ResponseError として扱う
+	Body any
+}
+
 type HandlerMethod[I any] interface {
 	RouterElement
 	// 共通のリクエストパラメーター受け取り処理をセット
@@ -27,4 +55,42 @@ type HandlerMethod[I any] interface {
 	AfterInput(func(ctx context.Context, r *http.Request, param *I) error)
 	// 共通のバリデーション処理の後に必要な処理があればセット
 	AfterValidate(func(ctx context.Context, param *I) error)
+	// ドキュメント用の概要をセット
+	SetSummary(summary string)
+	// ドキュメント用の説明をセット
+	SetDescription(description string)
+	// ドキュメント用のタグをセット
+	SetTags(tags ...string)
+	// 非推奨かどうかをセット
+	SetDeprecated(deprecated bool)
+	// 成功時のステータスコードをセット (未指定の場合は 200)
+	SetStatusCode(status int)
+	// 返す可能性のあるエラーレスポンスを追加。 body にはレスポンスボディの型の値を渡す
+	AddErrorResponse(status int, description string, body any)
+}
+
+// ストリーミングの出力形式
+type StreamKind string
+
+const (
+	// Server-Sent Events (text/event-stream)
+	StreamKindSSE StreamKind = "sse"
+	// 改行区切りの JSON (application/x-ndjson)
+	StreamKindNDJSON StreamKind = "ndjson"
+)
+
+// ストリーミングで出力するエンドポイント。 RenderFunc は使用されない
+type StreamHandlerMethod[I any] interface {
+	HandlerMethod[I]
+	// 接続を維持するためのハートビートの送信間隔をセット (0 以下の場合は送信しない)。
+	// 接続時にはヘッダーと最初のハートビートを常に送信するため、 handler が返すエラーはストリーミングのエラーとして送信される
+	SetHeartbeatInterval(d time.Duration)
+}
+
+// ストリーミングのレスポンスにデータを送信する
+type Emitter[O any] interface {
+	// データを送信する。クライアントが切断している場合はエラーを返す
+	Emit(output *O) error
+	// イベント名を指定してデータを送信する。 NDJSON の場合はイベント名は無視される
+	EmitEvent(event string, output *O) error
 }
@@ -8,6 +8,7 @@ import (
 func NewHandlerMethod[I, O any](f func(ctx context.Context, param *I) (*O, error)) HandlerMethod[I] {
 	return &handlerMethod[I, O]{
 		ServiceFunc: f,
+		metadata:    &RouterMetadata{},
 	}
 }
 
@@ -20,6 +21,8 @@ type handlerMethod[I, O any] struct {
 	BeforeHandleErrorFunc func(ctx context.Context, w http.ResponseWriter, r *http.Request, err error) error
 	HandleErrorFunc       func(ctx context.Context, w http.ResponseWriter, r *http.Request, err error)
 	RenderFunc            func(ctx context.Context, w http.ResponseWriter, r *http.Request, output any)
+	defaults              *handlerDefaults
+	metadata              *RouterMetadata
 }
 
 // --- handlerMethod implements ---
@@ -52,62 +55,138 @@ func (h *handlerMethod[I, O]) AfterValidate(f func(ctx context.Context, param *I
 	h.AfterValidateFunc = f
 }
 
+func (h *handlerMethod[I, O]) SetSummary(summary string) {
+	h.metadata.Summary = summary
+}
+
+func (h *handlerMethod[I, O]) SetDescription(description string) {
+	h.metadata.Description = description
+}
+
+func (h *handlerMethod[I, O]) SetTags(tags ...string) {
+	h.metadata.Tags = tags
+}
+
+func (h *handlerMethod[I, O]) SetDeprecated(deprecated bool) {
+	h.metadata.Deprecated = deprecated
+}
+
+func (h *handlerMethod[I, O]) SetStatusCode(status int) {
+	h.metadata.StatusCode = status
+}
+
+func (h *handlerMethod[I, O]) AddErrorResponse(status int, description string, body any) {
+	h.metadata.ErrorResponses = append(h.metadata.ErrorResponses, &RouterErrorResponse{
+		StatusCode:  status,
+		Description: description,
+		Body:        body,
+	})
+}
+
+func (h *handlerMethod[I, O]) setHandlerDefaults(defaults *handlerDefaults) {
+	h.defaults = defaults
+}
+
+// 個別に設定された処理がなければ Router のデフォルト処理を返す
+
+func (h *handlerMethod[I, O]) inputFunc() func(ctx context.Context, r *http.Request, param any) error {
+	if h.InputFunc == nil && h.defaults != nil {
+		return h.defaults.InputFunc
+	}
+	return h.InputFunc
+}
+
+func (h *handlerMethod[I, O]) validateFunc() func(ctx context.Context, param any) error {
+	if h.ValidateFunc == nil && h.defaults != nil {
+		return h.defaults.ValidateFunc
+	}
+	return h.ValidateFunc
+}
+
+func (h *handlerMethod[I, O]) handleErrorFunc() func(ctx context.Context, w http.ResponseWriter, r *http.Request, err error) {
+	if h.HandleErrorFunc == nil && h.defaults != nil {
+		return h.defaults.HandleErrorFunc
+	}
+	return h.HandleErrorFunc
+}
+
+func (h *handlerMethod[I, O]) renderFunc() func(ctx context.Context, w http.ResponseWriter, r *http.Request, output any) {
+	if h.RenderFunc == nil && h.defaults != nil {
+		return h.defaults.RenderFunc
+	}
+	return h.RenderFunc
+}
+
 // --- RouterElement implements ---
 
 func (h *handlerMethod[I, O]) handleError(ctx context.Context, w http.ResponseWriter, r *http.Request, err error) {
 	if h.BeforeHandleErrorFunc != nil {
 		err = h.BeforeHandleErrorFunc(ctx, w, r, err)
 	}
-	if h.HandleErrorFunc != nil {
-		h.HandleErrorFunc(ctx, w, r, err)
+	if handleErrorFunc := h.handleErrorFunc(); handleErrorFunc != nil {
+		handleErrorFunc(ctx, w, r, err)
 	}
 }
 
-func (h *handlerMethod[I, O]) GetHandleFunc() http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
+// リクエストパラメーターの受け取りとバリデーションを行う。失敗した場合はエラーをレンダリングして false を返す
+func (h *handlerMethod[I, O]) bindParam(ctx context.Context, w http.ResponseWriter, r *http.Request) (*I, bool) {
+	var param I
+	if inputFunc := h.inputFunc(); inputFunc != nil {
+		if err := inputFunc(ctx, r, &param); err != nil {
+			h.handleError(ctx, w, r, err)
+			return nil, false
+		}
+	}
+	if h.AfterInputFunc != nil {
+		if err := h.AfterInputFunc(ctx, r, &param); err != nil {
+			h.handleError(ctx, w, r, err)
+			return nil, false
+		}
+	}
 
-		var param I
-		if h.InputFunc != nil {
-			if err := h.InputFunc(ctx, r, &param); err != nil {
-				h.handleError(ctx, w, r, err)
-				return
-			}
+	if validateFunc := h.validateFunc(); validateFunc != nil {
+		if err := validateFunc(ctx, &param); err != nil {
+			h.handleError(ctx, w, r, err)
+			return nil, false
 		}
-		if h.AfterInputFunc != nil {
-			if err := h.AfterInputFunc(ctx, r, &param); err != nil {
-				h.handleError(ctx, w, r, err)
-				return
-			}
+	}
+
+	if h.AfterValidateFunc != nil {
+		if err := h.AfterValidateFunc(ctx, &param); err != nil {
+			h.handleError(ctx, w, r, err)
+			return nil, false
 		}
+	}
+	return &param, true
+}
 
-		if h.ValidateFunc != nil {
-			if err := h.ValidateFunc(ctx, &param); err != nil {
-				h.handleError(ctx, w, r, err)
-				return
-			}
+func (h *handlerMethod[I, O]) GetHandleFunc() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if h.metadata.StatusCode != 0 {
+			ctx = setContextStatusCode(ctx, h.metadata.StatusCode)
 		}
 
-		if h.AfterValidateFunc != nil {
-			if err := h.AfterValidateFunc(ctx, &param); err != nil {
-				h.handleError(ctx, w, r, err)
-				return
-			}
+		param, ok := h.bindParam(ctx, w, r)
+		if !ok {
+			return
 		}
+
 		var output *O
 		var err error
 		if h.ServiceFunc != nil {
-			output, err = h.ServiceFunc(ctx, &param)
+			output, err = h.ServiceFunc(ctx, param)
 			if err != nil {
 				h.handleError(ctx, w, r, err)
 				return
 			}
 		}
 
-		if h.RenderFunc == nil {
+		renderFunc := h.renderFunc()
+		if renderFunc == nil {
 			panic("RenderFunc is required")
 		}
-		h.RenderFunc(ctx, w, r, output)
+		renderFunc(ctx, w, r, output)
 	}
 }
 
@@ -118,3 +197,7 @@ func (h *handlerMethod[I, O]) GetEmptyInput() any {
 func (h *handlerMethod[I, O]) GetEmptyOutput() any {
 	return *new(O)
 }
+
+func (h *handlerMethod[I, O]) GetMetadata() *RouterMetadata {
+	return h.metadata
+}
@@ -0,0 +1,242 @@
+package rapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rabee-inc/go-pkg/errcode"
+	"github.com/rabee-inc/go-pkg/log"
+	"github.com/rabee-inc/go-pkg/renderer"
+)
+
+const defaultHeartbeatInterval = 15 * time.Second
+
+// ストリーミングで出力するエンドポイントを作成する。
+// リクエストパラメーターの受け取りとバリデーションは NewHandlerMethod と同じ処理を行い、
+// f に渡される ctx はクライアントが切断した場合にキャンセルされる
+func NewStreamHandlerMethod[I, O any](kind StreamKind, f func(ctx context.Context, param *I, emitter Emitter[O]) error) StreamHandlerMethod[I] {
+	h := &streamHandlerMethod[I, O]{
+		handlerMethod: &handlerMethod[I, O]{
+			metadata: &RouterMetadata{
+				StreamKind: kind,
+			},
+		},
+		StreamFunc:        f,
+		kind:              kind,
+		heartbeatInterval: defaultHeartbeatInterval,
+	}
+	return h
+}
+
+type streamHandlerMethod[I, O any] struct {
+	*handlerMethod[I, O]
+	StreamFunc        func(ctx context.Context, param *I, emitter Emitter[O]) error
+	kind              StreamKind
+	heartbeatInterval time.Duration
+}
+
+func (h *streamHandlerMethod[I, O]) SetHeartbeatInterval(d time.Duration) {
+	h.heartbeatInterval = d
+}
+
+func (h *streamHandlerMethod[I, O]) GetHandleFunc() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		param, ok := h.bindParam(ctx, w, r)
+		if !ok {
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			err := log.Errorc(ctx, http.StatusInternalServerError, "http.ResponseWriter does not support streaming")
+			h.handleError(ctx, w, r, err)
+			return
+		}
+
+		streamCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		emitter := &streamEmitter[O]{
+			ctx:     streamCtx,
+			w:       w,
+			flusher: flusher,
+			kind:    h.kind,
+		}
+		// プロキシが最初のデータを待たずにタイムアウトしないよう、ヘッダーと最初のハートビートを送信する
+		if err := emitter.open(); err != nil {
+			log.Warning(ctx, err)
+			return
+		}
+
+		// ハートビートを送信する
+		wg := &sync.WaitGroup{}
+		stop := make(chan struct{})
+		if h.heartbeatInterval > 0 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ticker := time.NewTicker(h.heartbeatInterval)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-streamCtx.Done():
+						return
+					case <-stop:
+						return
+					case <-ticker.C:
+						if err := emitter.heartbeat(); err != nil {
+							cancel()
+							return
+						}
+					}
+				}
+			}()
+		}
+
+		var err error
+		if h.StreamFunc != nil {
+			err = h.StreamFunc(streamCtx, param, emitter)
+		}
+		// handler の終了後に ResponseWriter に書き込まないよう、ハートビートの停止を待つ
+		close(stop)
+		wg.Wait()
+
+		if err == nil {
+			return
+		}
+		// クライアントが切断した場合はレスポンスを返せない
+		if ctx.Err() != nil {
+			log.Warningf(ctx, "stream canceled: %s", err.Error())
+			return
+		}
+		if h.BeforeHandleErrorFunc != nil {
+			err = h.BeforeHandleErrorFunc(ctx, w, r, err)
+		}
+		status, ok := errcode.Get(err)
+		if !ok {
+			status = http.StatusInternalServerError
+		}
+		if status >= http.StatusInternalServerError {
+			log.Errorf(ctx, "%d %s", status, err.Error())
+		} else {
+			log.Warningf(ctx, "%d %s", status, err.Error())
+		}
+		if err := emitter.emitError(renderer.NewResponseError(status, err.Error())); err != nil {
+			log.Warning(ctx, err)
+		}
+	}
+}
+
+type streamEmitter[O any] struct {
+	ctx     context.Context
+	w       http.ResponseWriter
+	flusher http.Flusher
+	kind    StreamKind
+	mutex   sync.Mutex
+}
+
+func (e *streamEmitter[O]) Emit(output *O) error {
+	return e.EmitEvent("", output)
+}
+
+func (e *streamEmitter[O]) EmitEvent(event string, output *O) error {
+	b, err := json.Marshal(output)
+	if err != nil {
+		log.Error(e.ctx, err)
+		return err
+	}
+	return e.write(event, b)
+}
+
+// NDJSON の場合、エラーは {"error": {...}} の行として送信する
+func (e *streamEmitter[O]) emitError(res *renderer.ResponseError) error {
+	var v any = res
+	if e.kind == StreamKindNDJSON {
+		v = map[string]any{"error": res}
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return e.write("error", b)
+}
+
+func (e *streamEmitter[O]) heartbeat() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if err := e.ctx.Err(); err != nil {
+		return err
+	}
+	return e.writeHeartbeat()
+}
+
+// mutex のロック中に呼び出すこと
+func (e *streamEmitter[O]) writeHeartbeat() error {
+	var line string
+	switch e.kind {
+	case StreamKindSSE:
+		// コメント行はクライアントで無視される
+		line = ": heartbeat\n\n"
+	default:
+		// 空行はクライアントで無視する
+		line = "\n"
+	}
+	if _, err := e.w.Write([]byte(line)); err != nil {
+		return err
+	}
+	e.flusher.Flush()
+	return nil
+}
+
+func (e *streamEmitter[O]) write(event string, data []byte) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if err := e.ctx.Err(); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	switch e.kind {
+	case StreamKindSSE:
+		if event != "" {
+			fmt.Fprintf(&buf, "event: %s\n", event)
+		}
+		fmt.Fprintf(&buf, "data: %s\n\n", data)
+	default:
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	if _, err := e.w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	e.flusher.Flush()
+	return nil
+}
+
+// ヘッダーと最初のハートビートを送信してストリーミングを開始する
+func (e *streamEmitter[O]) open() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	header := e.w.Header()
+	switch e.kind {
+	case StreamKindSSE:
+		header.Set("Content-Type", "text/event-stream")
+		header.Set("Connection", "keep-alive")
+	default:
+		header.Set("Content-Type", "application/x-ndjson")
+	}
+	header.Set("Cache-Control", "no-cache")
+	// リバースプロキシでバッファリングされないようにする
+	header.Set("X-Accel-Buffering", "no")
+	e.w.WriteHeader(http.StatusOK)
+	log.SetResponseStatus(e.ctx, http.StatusOK)
+	return e.writeHeartbeat()
+}
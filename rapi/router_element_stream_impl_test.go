@@ -0,0 +1,200 @@
+package rapi_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rabee-inc/go-pkg/errcode"
+	"github.com/rabee-inc/go-pkg/rapi"
+)
+
+type streamInput struct {
+	Count int `form:"count"`
+}
+
+type streamOutput struct {
+	N int `json:"n"`
+}
+
+func Test_StreamHandlerMethod(t *testing.T) {
+	type args struct {
+		kind      rapi.StreamKind
+		query     string
+		heartbeat time.Duration
+		stream    func(ctx context.Context, param *streamInput, emitter rapi.Emitter[streamOutput]) error
+	}
+	type want struct {
+		status      int
+		contentType string
+		body        string
+		// 本文の前に送信されたハートビートの最小数 (NDJSON のみ)
+		heartbeats int
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	emitAll := func(ctx context.Context, param *streamInput, emitter rapi.Emitter[streamOutput]) error {
+		if err := emitter.Emit(&streamOutput{N: 1}); err != nil {
+			return err
+		}
+		return emitter.EmitEvent("last", &streamOutput{N: 2})
+	}
+	failAfterEmit := func(ctx context.Context, param *streamInput, emitter rapi.Emitter[streamOutput]) error {
+		if err := emitter.Emit(&streamOutput{N: 1}); err != nil {
+			return err
+		}
+		return errcode.Set(errors.New("conflict"), http.StatusConflict)
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "SSE",
+			args: args{
+				kind:   rapi.StreamKindSSE,
+				stream: emitAll,
+			},
+			want: want{
+				status:      http.StatusOK,
+				contentType: "text/event-stream",
+				body:        ": heartbeat\n\ndata: {\"n\":1}\n\nevent: last\ndata: {\"n\":2}\n\n",
+			},
+		},
+		{
+			name: "NDJSON",
+			args: args{
+				kind:   rapi.StreamKindNDJSON,
+				stream: emitAll,
+			},
+			want: want{
+				status:      http.StatusOK,
+				contentType: "application/x-ndjson",
+				body:        "\n{\"n\":1}\n{\"n\":2}\n",
+			},
+		},
+		{
+			name: "SSEの途中のエラー",
+			args: args{
+				kind:   rapi.StreamKindSSE,
+				stream: failAfterEmit,
+			},
+			want: want{
+				status:      http.StatusOK,
+				contentType: "text/event-stream",
+				body:        ": heartbeat\n\ndata: {\"n\":1}\n\nevent: error\ndata: {\"status\":409,\"message\":\"conflict\"}\n\n",
+			},
+		},
+		{
+			name: "NDJSONの途中のエラー",
+			args: args{
+				kind:   rapi.StreamKindNDJSON,
+				stream: failAfterEmit,
+			},
+			want: want{
+				status:      http.StatusOK,
+				contentType: "application/x-ndjson",
+				body:        "\n{\"n\":1}\n{\"error\":{\"status\":409,\"message\":\"conflict\"}}\n",
+			},
+		},
+		{
+			name: "接続時にヘッダーとハートビートを送信する",
+			args: args{
+				kind: rapi.StreamKindSSE,
+				stream: func(ctx context.Context, param *streamInput, emitter rapi.Emitter[streamOutput]) error {
+					return nil
+				},
+			},
+			want: want{
+				status:      http.StatusOK,
+				contentType: "text/event-stream",
+				body:        ": heartbeat\n\n",
+			},
+		},
+		{
+			name: "送信前のエラーもストリーミングのエラーとして送信する",
+			args: args{
+				kind: rapi.StreamKindSSE,
+				stream: func(ctx context.Context, param *streamInput, emitter rapi.Emitter[streamOutput]) error {
+					return errcode.Set(errors.New("not found"), http.StatusNotFound)
+				},
+			},
+			want: want{
+				status:      http.StatusOK,
+				contentType: "text/event-stream",
+				body:        ": heartbeat\n\nevent: error\ndata: {\"status\":404,\"message\":\"not found\"}\n\n",
+			},
+		},
+		{
+			name: "パラメーターのエラーは通常のエラーレスポンス",
+			args: args{
+				kind:   rapi.StreamKindNDJSON,
+				query:  "?count=x",
+				stream: emitAll,
+			},
+			want: want{
+				status:      http.StatusBadRequest,
+				contentType: "application/json; charset=UTF-8",
+			},
+		},
+		{
+			name: "送信間隔ごとにハートビートを送信する",
+			args: args{
+				kind:      rapi.StreamKindNDJSON,
+				heartbeat: time.Millisecond,
+				stream: func(ctx context.Context, param *streamInput, emitter rapi.Emitter[streamOutput]) error {
+					// ハートビートの送信間隔より長く待つ
+					time.Sleep(20 * time.Millisecond)
+					return emitter.Emit(&streamOutput{N: 1})
+				},
+			},
+			want: want{
+				status:      http.StatusOK,
+				contentType: "application/x-ndjson",
+				body:        "{\"n\":1}",
+				heartbeats:  2,
+			},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			r := rapi.NewRouter()
+			r.SetDefaultInputFunc(rapi.DefaultInputFunc)
+			r.SetDefaultHandleErrorFunc(rapi.DefaultHandleErrorFunc)
+			h := rapi.NewStreamHandlerMethod(tc.args.kind, tc.args.stream)
+			h.SetHeartbeatInterval(tc.args.heartbeat)
+			r.Get("/stream", h)
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stream"+tc.args.query, nil))
+
+			if rec.Code != tc.want.status {
+				t.Errorf("got: %v, want: %v", rec.Code, tc.want.status)
+			}
+			if got := rec.Header().Get("Content-Type"); got != tc.want.contentType {
+				t.Errorf("got: %v, want: %v", got, tc.want.contentType)
+			}
+			got := rec.Body.String()
+			if tc.want.heartbeats > 0 {
+				body := strings.TrimLeft(got, "\n")
+				if heartbeats := len(got) - len(body); heartbeats < tc.want.heartbeats {
+					t.Errorf("got: %v, want: >= %v", heartbeats, tc.want.heartbeats)
+				}
+				// データの後にもハートビートが送信される場合がある
+				got = strings.TrimRight(body, "\n")
+			}
+			if tc.want.body != "" && got != tc.want.body {
+				t.Errorf("got: %q, want: %q", got, tc.want.body)
+			}
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package rapi
 
 import (
+	"context"
 	"net/http"
+	"sort"
 
 	"github.com/go-chi/chi"
 )
@@ -12,11 +14,22 @@ func NewRouter() Router {
 		children:           []*router{},
 		authMiddlewares:    chi.Middlewares{},
 		optAuthMiddlewares: chi.Middlewares{},
+		defaults:           newHandlerDefaults(),
 	}
 	r.root = r
 	return r
 }
 
+// NewRouterWithDefaults ... DefaultInputFunc, DefaultValidateFunc, DefaultHandleErrorFunc, DefaultRenderFunc をデフォルトの処理に設定した Router を作成する
+func NewRouterWithDefaults() Router {
+	r := NewRouter()
+	r.SetDefaultInputFunc(DefaultInputFunc)
+	r.SetDefaultValidateFunc(DefaultValidateFunc)
+	r.SetDefaultHandleErrorFunc(DefaultHandleErrorFunc)
+	r.SetDefaultRenderFunc(DefaultRenderFunc)
+	return r
+}
+
 type router struct {
 	method             string
 	path               string
@@ -28,6 +41,16 @@ type router struct {
 	children           []*router
 	authMiddlewares    chi.Middlewares
 	optAuthMiddlewares chi.Middlewares
+	defaults           *handlerDefaults
+	typeMappings       []*typeMapping
+	version            *routerVersion
+	versions           []*routerVersion
+	versionNegotiation *versionNegotiation
+}
+
+type typeMapping struct {
+	value   any
+	mapping *TypeStructure
 }
 
 func (r *router) sub() *router {
@@ -47,6 +70,10 @@ func (r *router) handle(method string, pattern string, re RouterElement) {
 	subRouter.path = pattern
 	subRouter.method = method
 
+	if receiver, ok := re.(handlerDefaultsReceiver); ok {
+		receiver.setHandlerDefaults(r.root.defaults)
+	}
+
 	switch method {
 	case http.MethodConnect:
 		subRouter.chiRouter.Connect(pattern, re.GetHandleFunc())
@@ -74,9 +101,20 @@ func (r *router) GetChiRouter() chi.Router {
 }
 
 func (r *router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if r == r.root && r.versionNegotiation != nil {
+		w.Header().Add("Vary", r.versionNegotiation.header)
+		req = r.negotiateVersion(req)
+	}
 	r.chiRouter.ServeHTTP(w, req)
 }
 
+func (r *router) fullPath() string {
+	if r.parent == nil {
+		return r.path
+	}
+	return r.parent.fullPath() + r.path
+}
+
 func (r *router) Route(pattern string, fn func(r Router)) Router {
 	subRouter := r.sub()
 	subRouter.path = pattern
@@ -93,6 +131,37 @@ func (r *router) Route(pattern string, fn func(r Router)) Router {
 	return subRouter
 }
 
+func (r *router) Version(version string, opt *VersionOption, fn func(r Router)) Router {
+	if opt == nil {
+		opt = &VersionOption{}
+	}
+	v := &routerVersion{
+		name:       version,
+		option:     opt,
+		parentPath: r.fullPath(),
+	}
+	r.root.versions = append(r.root.versions, v)
+
+	subRouter := r.sub()
+	subRouter.path = "/" + version
+	subRouter.version = v
+	r.chiRouter.Route(subRouter.path, func(chiRouter chi.Router) {
+		chiRouter.Use(v.middleware)
+		subRouter.chiRouter = chiRouter
+		if fn != nil {
+			fn(subRouter)
+		}
+	})
+	return subRouter
+}
+
+func (r *router) SetVersionNegotiation(header string, defaultVersion string) {
+	r.root.versionNegotiation = &versionNegotiation{
+		header:         header,
+		defaultVersion: defaultVersion,
+	}
+}
+
 func (r *router) Use(middlewares ...func(http.Handler) http.Handler) {
 	r.chiRouter.Use(middlewares...)
 }
@@ -105,6 +174,34 @@ func (r *router) SetOptAuthMiddleware(middlewares ...func(http.Handler) http.Han
 	r.root.optAuthMiddlewares = append(r.optAuthMiddlewares, middlewares...)
 }
 
+func (r *router) SetDefaultInputFunc(f func(ctx context.Context, r *http.Request, param any) error) {
+	if f == nil {
+		panic("default InputFunc is required")
+	}
+	r.root.defaults.InputFunc = f
+}
+
+func (r *router) SetDefaultValidateFunc(f func(ctx context.Context, param any) error) {
+	if f == nil {
+		panic("default ValidateFunc is required")
+	}
+	r.root.defaults.ValidateFunc = f
+}
+
+func (r *router) SetDefaultHandleErrorFunc(f func(ctx context.Context, w http.ResponseWriter, r *http.Request, err error)) {
+	if f == nil {
+		panic("default HandleErrorFunc is required")
+	}
+	r.root.defaults.HandleErrorFunc = f
+}
+
+func (r *router) SetDefaultRenderFunc(f func(ctx context.Context, w http.ResponseWriter, r *http.Request, output any)) {
+	if f == nil {
+		panic("default RenderFunc is required")
+	}
+	r.root.defaults.RenderFunc = f
+}
+
 func (r *router) With(middlewares ...func(http.Handler) http.Handler) Router {
 	return r.with(middlewares...)
 }
@@ -163,33 +260,90 @@ func (r *router) Trace(pattern string, re RouterElement) {
 	r.handle(http.MethodTrace, pattern, re)
 }
 
+func (r *router) AddTypeMapping(value any, mapping *TypeStructure) {
+	r.root.typeMappings = append(r.root.typeMappings, &typeMapping{value, mapping})
+}
+
 func (r *router) GetRouterDefinition() ([]*RouterDefinition, map[string]*TypeStructure) {
 	ts := NewTypeScanner()
 	ts.DisableStructField()
-	ts.AddStructTagName("json", "form")
+	// url を先頭にすることで json:"-" が併記されたパスパラメーターも拾えるようにする
+	ts.AddStructTagName("url", "json", "form")
+	for _, tm := range r.root.typeMappings {
+		ts.AddTypeMapping(tm.value, tm.mapping)
+	}
 
 	routerDefinitions := []*RouterDefinition{}
 
 	// 再帰で全てのRouter定義をappend
-	var appendRouterDefinition func(r *router, parentPath string)
-	appendRouterDefinition = func(r *router, parentPath string) {
+	var appendRouterDefinition func(r *router, parentPath string, parentWithAuth bool, parentVersion *routerVersion)
+	appendRouterDefinition = func(r *router, parentPath string, parentWithAuth bool, parentVersion *routerVersion) {
+		// Auth(), OptAuth() の子孫は全て認証ありとして扱う
+		withAuth := parentWithAuth || r.withAuth
+		version := parentVersion
+		if r.version != nil {
+			version = r.version
+		}
 		if r.element != nil {
 			routerDefinition := &RouterDefinition{
 				FullPathName:        parentPath + r.path,
 				CurrentPathName:     r.path,
 				Method:              r.method,
-				WithAuth:            r.withAuth,
+				WithAuth:            withAuth,
 				InputTypeStructure:  ts.Scan(r.element.GetEmptyInput()),
 				OutputTypeStructure: ts.Scan(r.element.GetEmptyOutput()),
+				StatusCode:          http.StatusOK,
+			}
+			if re, ok := r.element.(RouterElementWithMetadata); ok {
+				setRouterMetadata(routerDefinition, re.GetMetadata(), ts)
+			}
+			if version != nil {
+				setRouterVersion(routerDefinition, version)
 			}
 			routerDefinitions = append(routerDefinitions, routerDefinition)
 		}
 
 		for _, child := range r.children {
-			appendRouterDefinition(child, parentPath+r.path)
+			appendRouterDefinition(child, parentPath+r.path, withAuth, version)
 		}
 	}
 
-	appendRouterDefinition(r.root, "")
+	appendRouterDefinition(r.root, "", false, nil)
 	return routerDefinitions, ts.Export()
 }
+
+func setRouterMetadata(rd *RouterDefinition, metadata *RouterMetadata, ts TypeScanner) {
+	if metadata == nil {
+		return
+	}
+	rd.Summary = metadata.Summary
+	rd.Description = metadata.Description
+	rd.Tags = metadata.Tags
+	rd.Deprecated = metadata.Deprecated
+	// ストリーミングの場合は常に 200 を返す
+	if metadata.StatusCode != 0 && metadata.StreamKind == "" {
+		rd.StatusCode = metadata.StatusCode
+	}
+	rd.StreamKind = metadata.StreamKind
+	for _, errRes := range metadata.ErrorResponses {
+		rd.ErrorResponses = append(rd.ErrorResponses, &ErrorResponseDefinition{
+			StatusCode:    errRes.StatusCode,
+			Description:   errRes.Description,
+			TypeStructure: ts.Scan(errRes.Body),
+		})
+	}
+	sort.SliceStable(rd.ErrorResponses, func(i, j int) bool {
+		return rd.ErrorResponses[i].StatusCode < rd.ErrorResponses[j].StatusCode
+	})
+}
+
+func setRouterVersion(rd *RouterDefinition, version *routerVersion) {
+	rd.Version = version.name
+	if version.isDeprecated() {
+		rd.Deprecated = true
+	}
+	if !version.option.SunsetAt.IsZero() {
+		sunset := version.option.SunsetAt
+		rd.Sunset = &sunset
+	}
+}
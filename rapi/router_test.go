@@ -0,0 +1,267 @@
+package rapi_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rabee-inc/go-pkg/rapi"
+)
+
+type metadataInput struct {
+	ID string `url:"id"`
+}
+
+type metadataOutput struct {
+	ID string `json:"id"`
+}
+
+type metadataError struct {
+	Code string `json:"code"`
+}
+
+func Test_GetRouterDefinition_Metadata(t *testing.T) {
+	type args struct {
+		register func(r rapi.Router)
+	}
+	type want struct {
+		definitions []string
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "メタデータなし",
+			args: args{
+				register: func(r rapi.Router) {
+					r.Get("/items/{id}", rapi.NewHandlerMethod(func(ctx context.Context, param *metadataInput) (*metadataOutput, error) {
+						return nil, nil
+					}))
+				},
+			},
+			want: want{
+				definitions: []string{"GET /items/{id} summary= description= tags=[] deprecated=false status=200 errors=[] stream="},
+			},
+		},
+		{
+			name: "概要、説明、タグ、非推奨、ステータスコード",
+			args: args{
+				register: func(r rapi.Router) {
+					h := rapi.NewHandlerMethod(func(ctx context.Context, param *metadataInput) (*metadataOutput, error) {
+						return nil, nil
+					})
+					h.SetSummary("作成")
+					h.SetDescription("アイテムを作成する")
+					h.SetTags("items", "admin")
+					h.SetDeprecated(true)
+					h.SetStatusCode(http.StatusCreated)
+					r.Post("/items", h)
+				},
+			},
+			want: want{
+				definitions: []string{"POST /items summary=作成 description=アイテムを作成する tags=[items admin] deprecated=true status=201 errors=[] stream="},
+			},
+		},
+		{
+			name: "エラーレスポンスはステータスコード順",
+			args: args{
+				register: func(r rapi.Router) {
+					h := rapi.NewHandlerMethod(func(ctx context.Context, param *metadataInput) (*metadataOutput, error) {
+						return nil, nil
+					})
+					h.AddErrorResponse(http.StatusNotFound, "not found", nil)
+					h.AddErrorResponse(http.StatusBadRequest, "invalid", &metadataError{})
+					r.Get("/items/{id}", h)
+				},
+			},
+			want: want{
+				definitions: []string{"GET /items/{id} summary= description= tags=[] deprecated=false status=200 errors=[400:invalid:rapi_test.metadataError 404:not found:<nil>] stream="},
+			},
+		},
+		{
+			name: "ストリーミングは常に200",
+			args: args{
+				register: func(r rapi.Router) {
+					h := rapi.NewStreamHandlerMethod(rapi.StreamKindSSE, func(ctx context.Context, param *metadataInput, emitter rapi.Emitter[metadataOutput]) error {
+						return nil
+					})
+					h.SetStatusCode(http.StatusAccepted)
+					r.Get("/items/{id}/events", h)
+				},
+			},
+			want: want{
+				definitions: []string{"GET /items/{id}/events summary= description= tags=[] deprecated=false status=200 errors=[] stream=sse"},
+			},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			r := rapi.NewRouter()
+			tc.args.register(r)
+			routerDefinitions, _ := r.GetRouterDefinition()
+
+			definitions := []string{}
+			for _, rd := range routerDefinitions {
+				errors := []string{}
+				for _, errRes := range rd.ErrorResponses {
+					name := "<nil>"
+					if errRes.TypeStructure != nil {
+						name = errRes.TypeStructure.Name
+					}
+					errors = append(errors, fmt.Sprintf("%d:%s:%s", errRes.StatusCode, errRes.Description, name))
+				}
+				definitions = append(definitions, fmt.Sprintf(
+					"%s %s summary=%s description=%s tags=%v deprecated=%v status=%d errors=%v stream=%s",
+					rd.Method, rd.FullPathName, rd.Summary, rd.Description, rd.Tags, rd.Deprecated, rd.StatusCode, errors, rd.StreamKind,
+				))
+			}
+			if fmt.Sprint(definitions) != fmt.Sprint(tc.want.definitions) {
+				t.Errorf("got: %v, want: %v", definitions, tc.want.definitions)
+			}
+		})
+	}
+}
+
+type defaultsInput struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type defaultsOutput struct {
+	Name string `json:"name"`
+}
+
+func Test_NewRouterWithDefaults(t *testing.T) {
+	type args struct {
+		body string
+	}
+	type want struct {
+		status int
+		body   string
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "JSON をレンダリングする",
+			args: args{
+				body: `{"name":"foo"}`,
+			},
+			want: want{
+				status: http.StatusOK,
+				body:   `{"name":"foo"}`,
+			},
+		},
+		{
+			name: "バリデーションエラー",
+			args: args{
+				body: `{}`,
+			},
+			want: want{
+				status: http.StatusBadRequest,
+			},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			r := rapi.NewRouterWithDefaults()
+			r.Post("/items", rapi.NewHandlerMethod(func(ctx context.Context, param *defaultsInput) (*defaultsOutput, error) {
+				return &defaultsOutput{Name: param.Name}, nil
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(tc.args.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tc.want.status {
+				t.Errorf("got: %v, want: %v", rec.Code, tc.want.status)
+			}
+			if tc.want.body != "" && strings.TrimSpace(rec.Body.String()) != tc.want.body {
+				t.Errorf("got: %v, want: %v", rec.Body.String(), tc.want.body)
+			}
+		})
+	}
+}
+
+func Test_Router_SetDefaultNil(t *testing.T) {
+	type args struct {
+		set func(r rapi.Router)
+	}
+	type want struct {
+		panic string
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "SetDefaultInputFunc",
+			args: args{
+				set: func(r rapi.Router) { r.SetDefaultInputFunc(nil) },
+			},
+			want: want{
+				panic: "default InputFunc is required",
+			},
+		},
+		{
+			name: "SetDefaultValidateFunc",
+			args: args{
+				set: func(r rapi.Router) { r.SetDefaultValidateFunc(nil) },
+			},
+			want: want{
+				panic: "default ValidateFunc is required",
+			},
+		},
+		{
+			name: "SetDefaultHandleErrorFunc",
+			args: args{
+				set: func(r rapi.Router) { r.SetDefaultHandleErrorFunc(nil) },
+			},
+			want: want{
+				panic: "default HandleErrorFunc is required",
+			},
+		},
+		{
+			name: "SetDefaultRenderFunc",
+			args: args{
+				set: func(r rapi.Router) { r.SetDefaultRenderFunc(nil) },
+			},
+			want: want{
+				panic: "default RenderFunc is required",
+			},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func() {
+				if got := recover(); got != tc.want.panic {
+					t.Errorf("got: %v, want: %v", got, tc.want.panic)
+				}
+			}()
+			tc.args.set(rapi.NewRouter())
+		})
+	}
+}
@@ -0,0 +1,202 @@
+// Code generated by rapi. DO NOT EDIT.
+
+export type TypeScriptStatus = "active" | "inactive";
+
+export interface TypeScriptEmpty {
+}
+
+export interface TypeScriptListUsersInput {
+  cursor: string;
+  ids?: string[];
+  status?: TypeScriptStatus;
+}
+
+export interface TypeScriptUpdateUserInput {
+  dry_run?: boolean;
+  name: string;
+  tags?: Record<string, string>;
+  user_id: string;
+}
+
+export interface TypeScriptUser {
+  created_at: string;
+  id: string;
+  name: string;
+  note?: string;
+  status: TypeScriptStatus;
+}
+
+export interface TypeScriptUsers {
+  users: TypeScriptUser[];
+}
+
+export interface ApiRequest {
+  method: string;
+  path: string;
+  query: [string, string][];
+  body?: unknown;
+  withAuth: boolean;
+}
+
+export class ApiError extends Error {
+  constructor(
+    public readonly status: number,
+    public readonly body: unknown,
+  ) {
+    super(`api error: status ${status}`);
+  }
+}
+
+export interface ApiClientOption {
+  baseURL: string;
+  headers?: (req: ApiRequest) => Record<string, string> | Promise<Record<string, string>>;
+  fetch?: typeof fetch;
+}
+
+const appendQuery = (query: [string, string][], key: string, value: unknown): void => {
+  if (value === undefined || value === null) {
+    return;
+  }
+  if (Array.isArray(value)) {
+    value.forEach((v) => query.push([`${key}[]`, String(v)]));
+    return;
+  }
+  query.push([key, String(value)]);
+};
+
+export const createClient = (option: ApiClientOption) => {
+  const send = async (req: ApiRequest): Promise<Response> => {
+    const headers: Record<string, string> = { ...(await option.headers?.(req)) };
+    if (req.body !== undefined) {
+      headers["Content-Type"] = "application/json";
+    }
+    const query = new URLSearchParams(req.query).toString();
+    return (option.fetch ?? fetch)(option.baseURL + req.path + (query ? `?${query}` : ""), {
+      method: req.method,
+      headers,
+      body: req.body === undefined ? undefined : JSON.stringify(req.body),
+    });
+  };
+
+  const request = async <T>(req: ApiRequest): Promise<T> => {
+    const res = await send(req);
+    const text = await res.text();
+    const data: unknown = text ? JSON.parse(text) : undefined;
+    if (!res.ok) {
+      throw new ApiError(res.status, data);
+    }
+    return data as T;
+  };
+
+  const stream = async function* <T>(req: ApiRequest, kind: "sse" | "ndjson"): AsyncGenerator<T> {
+    const res = await send(req);
+    if (!res.ok || !res.body) {
+      const text = await res.text();
+      throw new ApiError(res.status, text ? JSON.parse(text) : undefined);
+    }
+    const reader = res.body.pipeThrough(new TextDecoderStream()).getReader();
+    let buffer = "";
+    let event = "";
+    let data: string[] = [];
+    for (;;) {
+      const { done, value } = await reader.read();
+      if (done) {
+        return;
+      }
+      buffer += value;
+      let index: number;
+      while ((index = buffer.indexOf("\n")) >= 0) {
+        const line = buffer.slice(0, index).replace(/\r$/, "");
+        buffer = buffer.slice(index + 1);
+        if (kind === "ndjson") {
+          if (!line) {
+            continue;
+          }
+          const item = JSON.parse(line);
+          if (item && typeof item === "object" && "error" in item) {
+            throw new ApiError(item.error.status, item.error);
+          }
+          yield item as T;
+          continue;
+        }
+        if (line.startsWith(":")) {
+          continue;
+        }
+        if (line.startsWith("event:")) {
+          event = line.slice(6).trim();
+          continue;
+        }
+        if (line.startsWith("data:")) {
+          data.push(line.slice(5).trimStart());
+          continue;
+        }
+        if (line === "" && data.length > 0) {
+          const item = JSON.parse(data.join("\n"));
+          const name = event;
+          event = "";
+          data = [];
+          if (name === "error") {
+            throw new ApiError(item.status, item);
+          }
+          yield item as T;
+        }
+      }
+    }
+  };
+
+  return {
+    /**
+     * ユーザー一覧
+     */
+    getV1Users: (input: Omit<TypeScriptListUsersInput, "cursor">): Promise<TypeScriptUsers> => {
+      const query: [string, string][] = [];
+      appendQuery(query, "ids", input.ids);
+      appendQuery(query, "status", input.status);
+      return request<TypeScriptUsers>({
+        method: "GET",
+        path: `/v1/users`,
+        query,
+        withAuth: false,
+      });
+    },
+    getV1UsersStream: (): AsyncGenerator<TypeScriptUser> => {
+      const query: [string, string][] = [];
+      return stream<TypeScriptUser>({
+        method: "GET",
+        path: `/v1/users/stream`,
+        query,
+        withAuth: false,
+      }, "ndjson");
+    },
+    /**
+     * @deprecated
+     */
+    deleteV1UsersByUserId: (input: Omit<TypeScriptUpdateUserInput, "name" | "tags">): Promise<void> => {
+      const query: [string, string][] = [];
+      appendQuery(query, "dry_run", input.dry_run);
+      return request<void>({
+        method: "DELETE",
+        path: `/v1/users/${encodeURIComponent(String(input.user_id))}`,
+        query,
+        withAuth: true,
+      });
+    },
+    /**
+     * @throws {ApiError} 404 not found
+     */
+    putV1UsersByUserId: (input: TypeScriptUpdateUserInput): Promise<TypeScriptUser> => {
+      const query: [string, string][] = [];
+      appendQuery(query, "dry_run", input.dry_run);
+      return request<TypeScriptUser>({
+        method: "PUT",
+        path: `/v1/users/${encodeURIComponent(String(input.user_id))}`,
+        query,
+        body: {
+          name: input.name,
+          tags: input.tags,
+        },
+        withAuth: true,
+      });
+    },
+  };
+};
@@ -11,6 +11,9 @@ type TypeScanner interface {
 	EnableStructField() TypeScanner
 	DisableStructField() TypeScanner
 	AddStructTagName(tagName ...string) TypeScanner
+	// value の型を scan した際に、 mapping の型情報として扱うように登録する。
+	// mapping の Name, GoTypeName が空の場合は value の型名を使用する
+	AddTypeMapping(value any, mapping *TypeStructure) TypeScanner
 }
 
 // 一つの型情報
@@ -28,6 +31,10 @@ type TypeStructure struct {
 	InlineEmbeddedFields map[string]*TypeStructure `json:"-"`
 	OmitEmpty            bool                      `json:"omit_empty,omitempty"`
 	Validate             string                    `json:"validate,omitempty"`
+	// struct の field の場合、フィールド名の取得元となった struct tag 名。タグがない場合は空文字
+	TagName string `json:"tag_name,omitempty"`
+	// 自身の scan 中に再帰的に参照された struct の場合 true 。 fields は Name で types から参照する
+	Recursive bool `json:"recursive,omitempty"`
 }
 
 type UnionStructure struct {
@@ -46,4 +53,7 @@ const (
 	TypeKindMap    = "map"
 	TypeKindStruct = "struct"
 	TypeKindAny    = "any"
+
+	// time.Time などの RFC 3339 形式の日時文字列
+	TypeKindDateTime = "date_time"
 )
@@ -1,9 +1,14 @@
 package rapi
 
 import (
+	"encoding"
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"reflect"
+	"regexp"
 	"strings"
+	"time"
 
 	"golang.org/x/exp/slices"
 )
@@ -13,7 +18,9 @@ type typeScanner struct {
 	unions             map[string]*UnionStructure
 	structFieldEnabled bool
 	structTagNames     []string
-	unnamedCount       int
+	typeMappings       map[reflect.Type]*TypeStructure
+	// scan 中の struct (再帰的な参照の検出に使用する)
+	scanning map[string]bool
 }
 
 func NewTypeScanner() TypeScanner {
@@ -22,9 +29,17 @@ func NewTypeScanner() TypeScanner {
 		unions:             map[string]*UnionStructure{},
 		structFieldEnabled: true,
 		structTagNames:     []string{},
+		typeMappings:       map[reflect.Type]*TypeStructure{},
+		scanning:           map[string]bool{},
 	}
 }
 
+var (
+	reflectTypeTime          = reflect.TypeOf(time.Time{})
+	reflectTypeJSONMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	reflectTypeTextMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
 func (t *typeScanner) EnableStructField() TypeScanner {
 	t.structFieldEnabled = true
 	return t
@@ -40,6 +55,18 @@ func (t *typeScanner) AddStructTagName(tagName ...string) TypeScanner {
 	return t
 }
 
+func (t *typeScanner) AddTypeMapping(value any, mapping *TypeStructure) TypeScanner {
+	rt := reflect.TypeOf(value)
+	if rt == nil || mapping == nil {
+		return t
+	}
+	for rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	t.typeMappings[rt] = mapping
+	return t
+}
+
 func (t *typeScanner) Scan(value any) *TypeStructure {
 	return t.scan(reflect.TypeOf(value), false)
 }
@@ -62,12 +89,16 @@ func (t *typeScanner) scan(rt reflect.Type, ignoreField bool) *TypeStructure {
 		rt = rt.Elem()
 	}
 
+	if ts := t.scanSpecialType(rt, ignoreField); ts != nil {
+		return ts
+	}
+
 	var typeKind string
 
 	switch rt.Kind() {
 	case reflect.String:
 		typeKind = TypeKindString
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
 		typeKind = TypeKindInt
 	case reflect.Float32, reflect.Float64:
 		typeKind = TypeKindFloat
@@ -79,10 +110,9 @@ func (t *typeScanner) scan(rt reflect.Type, ignoreField bool) *TypeStructure {
 		typeKind = TypeKindArray
 	case reflect.Struct:
 		typeKind = TypeKindStruct
-	case reflect.Interface:
-		typeKind = TypeKindAny
 	default:
-		panic(fmt.Sprintf("Invalid type: %s. (%s)", rt.Kind().String(), rt.String()))
+		// interface や JSON で表現できない型 (func, complex など) は any として扱う
+		typeKind = TypeKindAny
 	}
 
 	ts := &TypeStructure{
@@ -92,29 +122,28 @@ func (t *typeScanner) scan(rt reflect.Type, ignoreField bool) *TypeStructure {
 	switch rt.Kind() {
 	// map
 	case reflect.Map:
-		ts.Name = rt.Name()
-		ts.GoTypeName = rt.String()
+		ts.Name = readableTypeName(rt.Name())
+		ts.GoTypeName = readableTypeName(rt.String())
 		ts.KeyType = t.scan(rt.Key(), true)
 		ts.ElemType = t.scan(rt.Elem(), true)
 
 	// array
 	case reflect.Slice, reflect.Array, reflect.Chan:
-		ts.Name = rt.Name()
-		ts.GoTypeName = rt.String()
+		ts.Name = readableTypeName(rt.Name())
+		ts.GoTypeName = readableTypeName(rt.String())
 		ts.ElemType = t.scan(rt.Elem(), true)
 
 	// struct
 	case reflect.Struct:
-		name := rt.Name()
-
-		if name == "" {
-			name = "__unnamed__." + string(rune(t.unnamedCount))
-			t.unnamedCount++
-		} else {
-			name = rt.String()
-		}
+		name := structTypeName(rt)
 
 		if v, ok := t.types[name]; ok {
+			// scan 中の struct を参照している場合は再帰的な参照として扱う
+			if t.scanning[name] {
+				copied := v.getFieldsRemovedStruct()
+				copied.Recursive = true
+				return copied
+			}
 			if ignoreField {
 				return v.getFieldsRemovedStruct()
 			}
@@ -122,12 +151,15 @@ func (t *typeScanner) scan(rt reflect.Type, ignoreField bool) *TypeStructure {
 		}
 
 		ts.Name = name
-		ts.GoTypeName = rt.String()
+		ts.GoTypeName = readableTypeName(rt.String())
 		ts.Fields = map[string]*TypeStructure{}
 
 		t.types[name] = ts
+		t.scanning[name] = true
+		defer delete(t.scanning, name)
 		for i := 0; i < rt.NumField(); i++ {
 			keyName := ""
+			keyTagName := ""
 			field := rt.Field(i)
 
 			hasSkip := false
@@ -143,6 +175,7 @@ func (t *typeScanner) scan(rt reflect.Type, ignoreField bool) *TypeStructure {
 				}
 				keyName = tagValue
 				if keyName != "" {
+					keyTagName = tagName
 					omitEmpty = slices.Contains(values[1:], "omitempty")
 					break
 				}
@@ -178,6 +211,7 @@ func (t *typeScanner) scan(rt reflect.Type, ignoreField bool) *TypeStructure {
 			if fieldTs != nil {
 				fieldTs.OmitEmpty = omitEmpty
 				fieldTs.Validate = field.Tag.Get("validate")
+				fieldTs.TagName = keyTagName
 				ts.Fields[keyName] = fieldTs
 			}
 		}
@@ -189,13 +223,99 @@ func (t *typeScanner) scan(rt reflect.Type, ignoreField bool) *TypeStructure {
 
 	// primitive or other
 	default:
-		ts.Name = rt.Name()
-		ts.GoTypeName = rt.String()
+		ts.Name = readableTypeName(rt.Name())
+		ts.GoTypeName = readableTypeName(rt.String())
 	}
 
 	return ts
 }
 
+// 登録された型のマッピング、日時、 JSON のエンコード方法を独自に定義している型を処理する。該当しない場合は nil
+func (t *typeScanner) scanSpecialType(rt reflect.Type, ignoreField bool) *TypeStructure {
+	if mapping, ok := t.typeMappings[rt]; ok {
+		ts := *mapping
+		if ts.Name == "" {
+			ts.Name = readableTypeName(rt.Name())
+			if ts.Kind == TypeKindStruct {
+				ts.Name = structTypeName(rt)
+			}
+		}
+		if ts.GoTypeName == "" {
+			ts.GoTypeName = readableTypeName(rt.String())
+		}
+		if ts.Kind == TypeKindStruct {
+			if _, ok := t.types[ts.Name]; !ok {
+				t.types[ts.Name] = &ts
+			}
+			if ignoreField {
+				return ts.getFieldsRemovedStruct()
+			}
+		}
+		return &ts
+	}
+
+	var kind string
+	switch {
+	case rt == reflectTypeTime:
+		kind = TypeKindDateTime
+	// JSON の形式が不明なため any として扱う (AddTypeMapping で上書きできる)
+	case implementsDirectly(rt, reflectTypeJSONMarshaler):
+		kind = TypeKindAny
+	// encoding/json では文字列としてエンコードされる
+	case implementsDirectly(rt, reflectTypeTextMarshaler):
+		kind = TypeKindString
+	default:
+		return nil
+	}
+	return &TypeStructure{
+		Name:       readableTypeName(rt.Name()),
+		GoTypeName: readableTypeName(rt.String()),
+		Kind:       kind,
+	}
+}
+
+// 値とポインタのどちらかのレシーバーで interface を実装しているか
+func implementsEither(rt reflect.Type, it reflect.Type) bool {
+	return rt.Implements(it) || reflect.PointerTo(rt).Implements(it)
+}
+
+// 埋め込みフィールドから昇格したメソッドではなく、型自身が interface を実装しているか
+// (例: time.Time を埋め込んだ struct は MarshalJSON が昇格するが、特殊な型としては扱わない)
+func implementsDirectly(rt reflect.Type, it reflect.Type) bool {
+	if !implementsEither(rt, it) {
+		return false
+	}
+	if rt.Kind() == reflect.Struct {
+		for i := 0; i < rt.NumField(); i++ {
+			if f := rt.Field(i); f.Anonymous && implementsEither(f.Type, it) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// struct の types のキーとなる名前。無名 struct の場合は定義内容から生成する
+func structTypeName(rt reflect.Type) string {
+	if rt.Name() != "" {
+		return readableTypeName(rt.String())
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(rt.String()))
+	return fmt.Sprintf("__unnamed__.%08x", h.Sum32())
+}
+
+var regexpPackagePath = regexp.MustCompile(`(?:[\w.~-]+/)+`)
+
+// ジェネリクスの型引数に含まれるパッケージのパスを取り除く
+// (例: "rapi.Page[github.com/foo/bar/model.User]" -> "rapi.Page[model.User]")
+func readableTypeName(name string) string {
+	if !strings.Contains(name, "/") {
+		return name
+	}
+	return regexpPackagePath.ReplaceAllString(name, "")
+}
+
 func (t *typeScanner) formatScannedTypeStructure(ts *TypeStructure) {
 	for _, embeddedTs := range ts.InlineEmbeddedFields {
 		for k, v := range embeddedTs.Fields {
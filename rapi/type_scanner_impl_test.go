@@ -0,0 +1,109 @@
+package rapi_test
+
+import (
+	"encoding/json"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rabee-inc/go-pkg/rapi"
+)
+
+type scannerJSONValue struct {
+	Value string
+}
+
+func (v scannerJSONValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.Value)
+}
+
+type scannerTextValue struct {
+	Value string
+}
+
+func (v *scannerTextValue) MarshalText() ([]byte, error) {
+	return []byte(v.Value), nil
+}
+
+type scannerEmbeddedTime struct {
+	time.Time
+	Name string `json:"name"`
+}
+
+type scannerEmbeddedJSONValue struct {
+	*scannerJSONValue
+	Name string `json:"name"`
+}
+
+func Test_TypeScanner_Scan_SpecialType(t *testing.T) {
+	type args struct {
+		value any
+	}
+	type want struct {
+		kind       string
+		goTypeName string
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "time.Time",
+			args: args{value: time.Time{}},
+			want: want{kind: rapi.TypeKindDateTime, goTypeName: "time.Time"},
+		},
+		{
+			name: "time.Time のポインタ",
+			args: args{value: &time.Time{}},
+			want: want{kind: rapi.TypeKindDateTime, goTypeName: "time.Time"},
+		},
+		{
+			name: "json.RawMessage",
+			args: args{value: json.RawMessage{}},
+			want: want{kind: rapi.TypeKindAny, goTypeName: reflect.TypeOf(json.RawMessage{}).String()},
+		},
+		{
+			name: "json.Marshaler を実装した型",
+			args: args{value: scannerJSONValue{}},
+			want: want{kind: rapi.TypeKindAny, goTypeName: "rapi_test.scannerJSONValue"},
+		},
+		{
+			name: "ポインタレシーバーで encoding.TextMarshaler を実装した型",
+			args: args{value: scannerTextValue{}},
+			want: want{kind: rapi.TypeKindString, goTypeName: "rapi_test.scannerTextValue"},
+		},
+		{
+			name: "encoding.TextMarshaler を実装した標準ライブラリの型",
+			args: args{value: net.IP{}},
+			want: want{kind: rapi.TypeKindString, goTypeName: "net.IP"},
+		},
+		{
+			name: "time.Time を埋め込んだ struct",
+			args: args{value: scannerEmbeddedTime{}},
+			want: want{kind: rapi.TypeKindStruct, goTypeName: "rapi_test.scannerEmbeddedTime"},
+		},
+		{
+			name: "json.Marshaler を実装した型を埋め込んだ struct",
+			args: args{value: scannerEmbeddedJSONValue{}},
+			want: want{kind: rapi.TypeKindStruct, goTypeName: "rapi_test.scannerEmbeddedJSONValue"},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := rapi.NewTypeScanner().Scan(tc.args.value)
+			if ts.Kind != tc.want.kind {
+				t.Errorf("got: %v, want: %v", ts.Kind, tc.want.kind)
+			}
+			if ts.GoTypeName != tc.want.goTypeName {
+				t.Errorf("got: %v, want: %v", ts.GoTypeName, tc.want.goTypeName)
+			}
+		})
+	}
+}
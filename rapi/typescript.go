@@ -0,0 +1,519 @@
+package rapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const typeScriptHeader = "// Code generated by rapi. DO NOT EDIT."
+
+// 生成するクライアントのコードで使用している名前
+var typeScriptReservedNames = []string{
+	"ApiRequest",
+	"ApiError",
+	"ApiClientOption",
+}
+
+var regexpTypeScriptTypeArgSeparator = regexp.MustCompile(`[\[\],\s*]+`)
+
+type TypeScriptOption struct {
+	// クライアントを生成する関数名。未指定の場合は createApiClient
+	ClientName string
+}
+
+type typeScriptGenerator struct {
+	opt    *TypeScriptOption
+	types  map[string]*TypeStructure
+	unions map[string]*UnionStructure
+	// Go の型名 -> TypeScript の型名
+	names map[string]string
+	sb    strings.Builder
+}
+
+// GetRouterDefinition の出力と ExportUnion の出力から TypeScript の型定義と fetch クライアントを生成する。
+// 出力は入力の順序に依存せず、常に同じ内容になる
+func GenerateTypeScript(routerDefinitions []*RouterDefinition, types map[string]*TypeStructure, unions map[string]*UnionStructure, opt *TypeScriptOption) string {
+	if opt == nil {
+		opt = &TypeScriptOption{}
+	}
+	if opt.ClientName == "" {
+		opt.ClientName = "createApiClient"
+	}
+	g := &typeScriptGenerator{
+		opt:    opt,
+		types:  types,
+		unions: unions,
+		names:  map[string]string{},
+	}
+	g.resolveNames()
+
+	g.sb.WriteString(typeScriptHeader + "\n")
+	g.writeUnions()
+	g.writeInterfaces()
+	g.writeClient(routerDefinitions)
+	return g.sb.String()
+}
+
+// Go の型名から TypeScript の型名を決定する。
+// パッケージ名を除いた名前が重複する場合は、パッケージ名を含めた名前にする
+func (g *typeScriptGenerator) resolveNames() {
+	goNames := []string{}
+	for name, ts := range g.types {
+		if ts.Kind == TypeKindStruct {
+			goNames = append(goNames, name)
+		}
+	}
+	for name := range g.unions {
+		goNames = append(goNames, name)
+	}
+	sort.Strings(goNames)
+
+	counts := map[string]int{}
+	for _, goName := range goNames {
+		counts[typeScriptBaseName(goName, false)]++
+	}
+	used := map[string]bool{}
+	for _, name := range typeScriptReservedNames {
+		used[name] = true
+	}
+	for _, goName := range goNames {
+		name := typeScriptBaseName(goName, false)
+		if counts[name] > 1 || used[name] {
+			name = typeScriptBaseName(goName, true)
+		}
+		base := name
+		for i := 2; used[name]; i++ {
+			name = base + strconv.Itoa(i)
+		}
+		used[name] = true
+		g.names[goName] = name
+	}
+}
+
+// 例: model.User -> User, rapi.Page[github.com/xxx/model.User] -> PageUser
+func typeScriptBaseName(goName string, withPackage bool) string {
+	base, args, hasArgs := strings.Cut(goName, "[")
+	pkg := ""
+	if i := strings.LastIndex(base, "."); i >= 0 {
+		pkg, base = base[:i], base[i+1:]
+	}
+	name := toUpperCamel(base)
+	// 無名 struct の名前はハッシュ値のため、識別子として使えるように接頭辞をつける
+	if name == "" || pkg == "__unnamed__" {
+		name = "Unnamed" + name
+	}
+	if withPackage {
+		name = toUpperCamel(pkg) + name
+	}
+	if hasArgs {
+		for _, arg := range regexpTypeScriptTypeArgSeparator.Split(args, -1) {
+			if i := strings.LastIndex(arg, "."); i >= 0 {
+				arg = arg[i+1:]
+			}
+			name += toUpperCamel(arg)
+		}
+	}
+	return name
+}
+
+func (g *typeScriptGenerator) sortedGoNames(filter func(goName string) bool) []string {
+	goNames := []string{}
+	for goName := range g.names {
+		if filter(goName) {
+			goNames = append(goNames, goName)
+		}
+	}
+	sort.Slice(goNames, func(i, j int) bool {
+		return g.names[goNames[i]] < g.names[goNames[j]]
+	})
+	return goNames
+}
+
+func (g *typeScriptGenerator) writeUnions() {
+	goNames := g.sortedGoNames(func(goName string) bool {
+		_, ok := g.unions[goName]
+		return ok
+	})
+	for _, goName := range goNames {
+		us := g.unions[goName]
+		values := []string{}
+		for _, v := range us.Values {
+			b, err := json.Marshal(v)
+			if err != nil {
+				panic(err)
+			}
+			values = append(values, string(b))
+		}
+		fmt.Fprintf(&g.sb, "\nexport type %s = %s;\n", g.names[goName], strings.Join(values, " | "))
+	}
+}
+
+func (g *typeScriptGenerator) writeInterfaces() {
+	goNames := g.sortedGoNames(func(goName string) bool {
+		_, ok := g.types[goName]
+		return ok
+	})
+	for _, goName := range goNames {
+		ts := g.types[goName]
+		fmt.Fprintf(&g.sb, "\nexport interface %s {\n", g.names[goName])
+		for _, key := range sortedFieldKeys(ts.Fields) {
+			field := ts.Fields[key]
+			optional := ""
+			if isTypeScriptOptionalField(field) {
+				optional = "?"
+			}
+			fmt.Fprintf(&g.sb, "  %s%s: %s;\n", typeScriptPropertyName(key), optional, g.typeName(field))
+		}
+		g.sb.WriteString("}\n")
+	}
+}
+
+func isTypeScriptOptionalField(field *TypeStructure) bool {
+	switch field.TagName {
+	case "url":
+		return false
+	case "form":
+		return !hasValidateRule(field.Validate, "required")
+	default:
+		return field.OmitEmpty && !hasValidateRule(field.Validate, "required")
+	}
+}
+
+var regexpTypeScriptIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+func typeScriptPropertyName(key string) string {
+	if regexpTypeScriptIdentifier.MatchString(key) {
+		return key
+	}
+	return strconv.Quote(key)
+}
+
+func typeScriptPropertyAccess(obj string, key string) string {
+	if regexpTypeScriptIdentifier.MatchString(key) {
+		return obj + "." + key
+	}
+	return fmt.Sprintf("%s[%s]", obj, strconv.Quote(key))
+}
+
+func (g *typeScriptGenerator) typeName(ts *TypeStructure) string {
+	if ts == nil {
+		return "void"
+	}
+	if name, ok := g.names[ts.GoTypeName]; ok {
+		if _, ok := g.unions[ts.GoTypeName]; ok {
+			return name
+		}
+	}
+	switch ts.Kind {
+	case TypeKindString, TypeKindDateTime:
+		return "string"
+	case TypeKindInt, TypeKindFloat:
+		return "number"
+	case TypeKindBool:
+		return "boolean"
+	case TypeKindArray:
+		// []byte は encoding/json で base64 の文字列になる
+		if ts.ElemType != nil && ts.ElemType.GoTypeName == "uint8" && !regexpGoArrayType.MatchString(ts.GoTypeName) {
+			return "string"
+		}
+		elem := g.typeName(ts.ElemType)
+		if strings.Contains(elem, " ") {
+			elem = "(" + elem + ")"
+		}
+		return elem + "[]"
+	case TypeKindMap:
+		key := "string"
+		if ts.KeyType != nil {
+			if name, ok := g.names[ts.KeyType.GoTypeName]; ok {
+				key = name
+			} else if ts.KeyType.Kind == TypeKindInt || ts.KeyType.Kind == TypeKindFloat {
+				key = "number"
+			}
+		}
+		return fmt.Sprintf("Record<%s, %s>", key, g.typeName(ts.ElemType))
+	case TypeKindStruct:
+		if name, ok := g.names[ts.Name]; ok {
+			return name
+		}
+		return "unknown"
+	default:
+		return "unknown"
+	}
+}
+
+func (g *typeScriptGenerator) writeClient(routerDefinitions []*RouterDefinition) {
+	g.sb.WriteString(`
+export interface ApiRequest {
+  method: string;
+  path: string;
+  query: [string, string][];
+  body?: unknown;
+  withAuth: boolean;
+}
+
+export class ApiError extends Error {
+  constructor(
+    public readonly status: number,
+    public readonly body: unknown,
+  ) {
+    super(` + "`api error: status ${status}`" + `);
+  }
+}
+
+export interface ApiClientOption {
+  baseURL: string;
+  headers?: (req: ApiRequest) => Record<string, string> | Promise<Record<string, string>>;
+  fetch?: typeof fetch;
+}
+
+const appendQuery = (query: [string, string][], key: string, value: unknown): void => {
+  if (value === undefined || value === null) {
+    return;
+  }
+  if (Array.isArray(value)) {
+    value.forEach((v) => query.push([` + "`${key}[]`" + `, String(v)]));
+    return;
+  }
+  query.push([key, String(value)]);
+};
+`)
+
+	fmt.Fprintf(&g.sb, "\nexport const %s = (option: ApiClientOption) => {\n", g.opt.ClientName)
+	g.sb.WriteString(`  const send = async (req: ApiRequest): Promise<Response> => {
+    const headers: Record<string, string> = { ...(await option.headers?.(req)) };
+    if (req.body !== undefined) {
+      headers["Content-Type"] = "application/json";
+    }
+    const query = new URLSearchParams(req.query).toString();
+    return (option.fetch ?? fetch)(option.baseURL + req.path + (query ? ` + "`?${query}`" + ` : ""), {
+      method: req.method,
+      headers,
+      body: req.body === undefined ? undefined : JSON.stringify(req.body),
+    });
+  };
+
+  const request = async <T>(req: ApiRequest): Promise<T> => {
+    const res = await send(req);
+    const text = await res.text();
+    const data: unknown = text ? JSON.parse(text) : undefined;
+    if (!res.ok) {
+      throw new ApiError(res.status, data);
+    }
+    return data as T;
+  };
+
+  const stream = async function* <T>(req: ApiRequest, kind: "sse" | "ndjson"): AsyncGenerator<T> {
+    const res = await send(req);
+    if (!res.ok || !res.body) {
+      const text = await res.text();
+      throw new ApiError(res.status, text ? JSON.parse(text) : undefined);
+    }
+    const reader = res.body.pipeThrough(new TextDecoderStream()).getReader();
+    let buffer = "";
+    let event = "";
+    let data: string[] = [];
+    for (;;) {
+      const { done, value } = await reader.read();
+      if (done) {
+        return;
+      }
+      buffer += value;
+      let index: number;
+      while ((index = buffer.indexOf("\n")) >= 0) {
+        const line = buffer.slice(0, index).replace(/\r$/, "");
+        buffer = buffer.slice(index + 1);
+        if (kind === "ndjson") {
+          if (!line) {
+            continue;
+          }
+          const item = JSON.parse(line);
+          if (item && typeof item === "object" && "error" in item) {
+            throw new ApiError(item.error.status, item.error);
+          }
+          yield item as T;
+          continue;
+        }
+        if (line.startsWith(":")) {
+          continue;
+        }
+        if (line.startsWith("event:")) {
+          event = line.slice(6).trim();
+          continue;
+        }
+        if (line.startsWith("data:")) {
+          data.push(line.slice(5).trimStart());
+          continue;
+        }
+        if (line === "" && data.length > 0) {
+          const item = JSON.parse(data.join("\n"));
+          const name = event;
+          event = "";
+          data = [];
+          if (name === "error") {
+            throw new ApiError(item.status, item);
+          }
+          yield item as T;
+        }
+      }
+    }
+  };
+
+  return {
+`)
+
+	type endpoint struct {
+		rd         *RouterDefinition
+		path       string
+		pathParams []*openAPIPathParam
+	}
+	endpoints := []*endpoint{}
+	for _, rd := range routerDefinitions {
+		path, pathParams := convertOpenAPIPath(rd.FullPathName)
+		endpoints = append(endpoints, &endpoint{rd, path, pathParams})
+	}
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		if endpoints[i].path != endpoints[j].path {
+			return endpoints[i].path < endpoints[j].path
+		}
+		return endpoints[i].rd.Method < endpoints[j].rd.Method
+	})
+
+	usedNames := map[string]int{}
+	for _, e := range endpoints {
+		name := generateOperationName(e.rd.Method, e.path)
+		usedNames[name]++
+		if cnt := usedNames[name]; cnt > 1 {
+			name += strconv.Itoa(cnt)
+		}
+		g.writeEndpoint(name, e.rd, e.path, e.pathParams)
+	}
+	g.sb.WriteString("  };\n};\n")
+}
+
+func (g *typeScriptGenerator) writeEndpoint(name string, rd *RouterDefinition, path string, pathParams []*openAPIPathParam) {
+	var fields map[string]*TypeStructure
+	input := rd.InputTypeStructure
+	if input != nil && input.Kind == TypeKindStruct {
+		fields = input.Fields
+		if v, ok := g.types[input.Name]; ok && len(fields) == 0 {
+			fields = v.Fields
+		}
+	}
+
+	// パスパラメーターを埋め込む
+	for _, pathParam := range pathParams {
+		value := `""`
+		if field, ok := fields[pathParam.name]; ok && field.TagName == "url" {
+			value = typeScriptPropertyAccess("input", pathParam.name)
+		}
+		path = strings.Replace(path, "{"+pathParam.name+"}", fmt.Sprintf("${encodeURIComponent(String(%s))}", value), 1)
+	}
+
+	hasBody := hasRequestBody(rd.Method)
+	queryKeys := []string{}
+	bodyKeys := []string{}
+	// ボディのないメソッドの json フィールドはリクエストから受け取らないため、送信せず引数の型からも除く
+	omitKeys := []string{}
+	for _, key := range sortedFieldKeys(fields) {
+		field := fields[key]
+		switch {
+		case field.TagName == "url":
+		case field.TagName == "form":
+			queryKeys = append(queryKeys, key)
+		case field.TagName == "json" && hasBody:
+			bodyKeys = append(bodyKeys, key)
+		default:
+			omitKeys = append(omitKeys, key)
+		}
+	}
+
+	args := ""
+	if len(fields) > len(omitKeys) {
+		inputType := g.typeName(input)
+		if len(omitKeys) > 0 {
+			quoted := make([]string, len(omitKeys))
+			for i, key := range omitKeys {
+				quoted[i] = strconv.Quote(key)
+			}
+			inputType = fmt.Sprintf("Omit<%s, %s>", inputType, strings.Join(quoted, " | "))
+		}
+		args = "input: " + inputType
+	}
+	output := g.typeName(rd.OutputTypeStructure)
+	if rd.StatusCode == http.StatusNoContent {
+		output = "void"
+	}
+	g.writeEndpointComment(rd)
+	if rd.StreamKind != "" {
+		fmt.Fprintf(&g.sb, "    %s: (%s): AsyncGenerator<%s> => {\n", name, args, output)
+	} else {
+		fmt.Fprintf(&g.sb, "    %s: (%s): Promise<%s> => {\n", name, args, output)
+	}
+	g.sb.WriteString("      const query: [string, string][] = [];\n")
+	for _, key := range queryKeys {
+		fmt.Fprintf(&g.sb, "      appendQuery(query, %s, %s);\n", strconv.Quote(key), typeScriptPropertyAccess("input", key))
+	}
+	if rd.StreamKind != "" {
+		fmt.Fprintf(&g.sb, "      return stream<%s>({\n", output)
+	} else {
+		fmt.Fprintf(&g.sb, "      return request<%s>({\n", output)
+	}
+	fmt.Fprintf(&g.sb, "        method: %s,\n", strconv.Quote(rd.Method))
+	fmt.Fprintf(&g.sb, "        path: `%s`,\n", path)
+	g.sb.WriteString("        query,\n")
+	if len(bodyKeys) > 0 {
+		g.sb.WriteString("        body: {\n")
+		for _, key := range bodyKeys {
+			fmt.Fprintf(&g.sb, "          %s: %s,\n", typeScriptPropertyName(key), typeScriptPropertyAccess("input", key))
+		}
+		g.sb.WriteString("        },\n")
+	}
+	fmt.Fprintf(&g.sb, "        withAuth: %t,\n", rd.WithAuth)
+	if rd.StreamKind != "" {
+		fmt.Fprintf(&g.sb, "      }, %s);\n", strconv.Quote(string(rd.StreamKind)))
+	} else {
+		g.sb.WriteString("      });\n")
+	}
+	g.sb.WriteString("    },\n")
+}
+
+func (g *typeScriptGenerator) writeEndpointComment(rd *RouterDefinition) {
+	lines := []string{}
+	if rd.Summary != "" {
+		lines = append(lines, rd.Summary)
+	}
+	if rd.Description != "" {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, strings.Split(rd.Description, "\n")...)
+	}
+	if rd.Deprecated {
+		lines = append(lines, "@deprecated")
+	}
+	for _, errRes := range rd.ErrorResponses {
+		line := fmt.Sprintf("@throws {ApiError} %d", errRes.StatusCode)
+		if errRes.TypeStructure != nil {
+			line += ": " + g.typeName(errRes.TypeStructure)
+		}
+		if errRes.Description != "" {
+			line += " " + errRes.Description
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return
+	}
+	g.sb.WriteString("    /**\n")
+	for _, line := range lines {
+		// コメントの終端が含まれていると壊れるため置換する
+		line = strings.ReplaceAll(line, "*/", "*\\/")
+		g.sb.WriteString(strings.TrimRight("     * "+line, " ") + "\n")
+	}
+	g.sb.WriteString("     */\n")
+}
@@ -0,0 +1,134 @@
+package rapi_test
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rabee-inc/go-pkg/rapi"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+type typeScriptStatus string
+
+type typeScriptListUsersInput struct {
+	Status typeScriptStatus `form:"status"`
+	IDs    []string         `form:"ids"`
+	Cursor string           `json:"cursor"`
+}
+
+type typeScriptUpdateUserInput struct {
+	UserID string            `url:"user_id" json:"-"`
+	Name   string            `json:"name" validate:"required"`
+	Tags   map[string]string `json:"tags,omitempty"`
+	DryRun bool              `form:"dry_run"`
+}
+
+type typeScriptUser struct {
+	ID        string           `json:"id"`
+	Name      string           `json:"name"`
+	Status    typeScriptStatus `json:"status"`
+	CreatedAt time.Time        `json:"created_at"`
+	Note      *string          `json:"note,omitempty"`
+}
+
+type typeScriptUsers struct {
+	Users []*typeScriptUser `json:"users"`
+}
+
+type typeScriptEmpty struct{}
+
+func newTypeScriptRouter() rapi.Router {
+	r := rapi.NewRouter()
+	r.Route("/v1", func(r rapi.Router) {
+		listUsers := rapi.NewHandlerMethod(func(ctx context.Context, param *typeScriptListUsersInput) (*typeScriptUsers, error) {
+			return nil, nil
+		})
+		listUsers.SetSummary("ユーザー一覧")
+		r.Get("/users", listUsers)
+
+		updateUser := rapi.NewHandlerMethod(func(ctx context.Context, param *typeScriptUpdateUserInput) (*typeScriptUser, error) {
+			return nil, nil
+		})
+		updateUser.AddErrorResponse(404, "not found", nil)
+		r.Auth().Put("/users/{user_id:[a-z0-9]+}", updateUser)
+
+		deleteUser := rapi.NewHandlerMethod(func(ctx context.Context, param *typeScriptUpdateUserInput) (*typeScriptEmpty, error) {
+			return nil, nil
+		})
+		deleteUser.SetStatusCode(204)
+		deleteUser.SetDeprecated(true)
+		r.Auth().Delete("/users/{user_id}", deleteUser)
+
+		r.Get("/users/stream", rapi.NewStreamHandlerMethod(rapi.StreamKindNDJSON, func(ctx context.Context, param *typeScriptEmpty, emitter rapi.Emitter[typeScriptUser]) error {
+			return nil
+		}))
+	})
+	return r
+}
+
+func Test_GenerateTypeScript(t *testing.T) {
+	type args struct {
+		router rapi.Router
+		opt    *rapi.TypeScriptOption
+	}
+	type want struct {
+		golden string
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "型定義とクライアント",
+			args: args{
+				router: newTypeScriptRouter(),
+				opt:    &rapi.TypeScriptOption{ClientName: "createClient"},
+			},
+			want: want{
+				golden: "typescript.golden.ts",
+			},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			scanner := rapi.NewTypeScanner()
+			scanner.ScanUnion([]any{typeScriptStatus("active"), typeScriptStatus("inactive")})
+
+			routerDefinitions, types := tc.args.router.GetRouterDefinition()
+			got := rapi.GenerateTypeScript(routerDefinitions, types, scanner.ExportUnion(), tc.args.opt)
+
+			// 出力は毎回同じ内容になる
+			for range 5 {
+				routerDefinitions, types := tc.args.router.GetRouterDefinition()
+				if again := rapi.GenerateTypeScript(routerDefinitions, types, scanner.ExportUnion(), tc.args.opt); again != got {
+					t.Fatalf("got: %v, want: %v", again, got)
+				}
+			}
+
+			path := filepath.Join("testdata", tc.want.golden)
+			if *update {
+				if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != string(want) {
+				t.Errorf("got: %v, want: %v", got, string(want))
+			}
+		})
+	}
+}
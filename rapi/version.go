@@ -0,0 +1,115 @@
+package rapi
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/rabee-inc/go-pkg/log"
+	"github.com/rabee-inc/go-pkg/renderer"
+)
+
+// API のバージョンの設定
+type VersionOption struct {
+	// 非推奨になった (なる) 日時。ゼロ値の場合は非推奨ではない
+	DeprecatedAt time.Time
+	// 廃止される日時。この日時を過ぎると 410 Gone を返す。ゼロ値の場合は廃止しない
+	SunsetAt time.Time
+	// 移行先のドキュメントなどの URL 。 Link ヘッダーで返す
+	Link string
+}
+
+type routerVersion struct {
+	name   string
+	option *VersionOption
+	// バージョンのグループを登録した Router のパス (例: "/api")
+	parentPath string
+}
+
+func (v *routerVersion) isDeprecated() bool {
+	return !v.option.DeprecatedAt.IsZero()
+}
+
+func (v *routerVersion) isSunset(now time.Time) bool {
+	return !v.option.SunsetAt.IsZero() && !now.Before(v.option.SunsetAt)
+}
+
+// 非推奨のバージョンに Deprecation, Sunset, Link ヘッダーを付与し、廃止後は 410 Gone を返す
+func (v *routerVersion) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := setContextVersion(r.Context(), v.name)
+		header := w.Header()
+		// spec: https://www.rfc-editor.org/rfc/rfc9745
+		if v.isDeprecated() {
+			header.Set("Deprecation", fmt.Sprintf("@%d", v.option.DeprecatedAt.Unix()))
+		}
+		// spec: https://www.rfc-editor.org/rfc/rfc8594
+		if !v.option.SunsetAt.IsZero() {
+			header.Set("Sunset", v.option.SunsetAt.UTC().Format(http.TimeFormat))
+		}
+		if v.option.Link != "" {
+			header.Add("Link", fmt.Sprintf(`<%s>; rel="deprecation"`, v.option.Link))
+		}
+		if v.isSunset(time.Now()) {
+			log.Warningf(ctx, "api version %s has been sunset", v.name)
+			renderer.Error(ctx, w, http.StatusGone, fmt.Sprintf("api version %s is no longer available", v.name))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ヘッダーによるバージョンの指定
+type versionNegotiation struct {
+	header         string
+	defaultVersion string
+}
+
+// パスにバージョンが含まれず、バージョンのないエンドポイントにも一致しない場合、ヘッダー (未指定の場合はデフォルト) のバージョンをパスに補完する
+func (r *router) negotiateVersion(req *http.Request) *http.Request {
+	if r.versionNegotiation == nil {
+		return req
+	}
+	path := req.URL.Path
+	for _, v := range r.versions {
+		prefix := v.parentPath + "/" + v.name
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return req
+		}
+	}
+	// バージョンのないエンドポイントはそのままルーティングする
+	if r.chiRouter.Match(chi.NewRouteContext(), req.Method, path) {
+		return req
+	}
+
+	name := req.Header.Get(r.versionNegotiation.header)
+	if name == "" {
+		name = r.versionNegotiation.defaultVersion
+	}
+	for _, v := range r.versions {
+		if v.name != name {
+			continue
+		}
+		if v.parentPath != "" && path != v.parentPath && !strings.HasPrefix(path, v.parentPath+"/") {
+			continue
+		}
+		dst := req.Clone(req.Context())
+		dst.URL.Path = v.parentPath + "/" + v.name + strings.TrimPrefix(path, v.parentPath)
+		dst.URL.RawPath = ""
+		return dst
+	}
+	return req
+}
+
+// 指定したバージョンのエンドポイントのみを返す。 version が空文字の場合はバージョンのないエンドポイントを返す
+func FilterRouterDefinitionsByVersion(routerDefinitions []*RouterDefinition, version string) []*RouterDefinition {
+	dst := []*RouterDefinition{}
+	for _, rd := range routerDefinitions {
+		if rd.Version == version {
+			dst = append(dst, rd)
+		}
+	}
+	return dst
+}
@@ -0,0 +1,242 @@
+package rapi_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rabee-inc/go-pkg/rapi"
+	"github.com/rabee-inc/go-pkg/renderer"
+)
+
+type versionOutput struct {
+	Version string `json:"version"`
+}
+
+var (
+	versionDeprecatedAt = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	versionSunsetAt     = time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func newVersionRouter() rapi.Router {
+	r := rapi.NewRouter()
+	r.SetVersionNegotiation("Api-Version", "v2")
+	newHandler := func() rapi.HandlerMethod[struct{}] {
+		h := rapi.NewHandlerMethod(func(ctx context.Context, param *struct{}) (*versionOutput, error) {
+			return &versionOutput{Version: rapi.GetVersion(ctx)}, nil
+		})
+		h.SetRenderFunc(func(ctx context.Context, w http.ResponseWriter, r *http.Request, output any) {
+			renderer.Text(ctx, w, http.StatusOK, output.(*versionOutput).Version)
+		})
+		return h
+	}
+	r.Route("/api", func(r rapi.Router) {
+		r.Version("v0", &rapi.VersionOption{SunsetAt: versionDeprecatedAt}, func(r rapi.Router) {
+			r.Get("/items", newHandler())
+		})
+		r.Version("v1", &rapi.VersionOption{
+			DeprecatedAt: versionDeprecatedAt,
+			SunsetAt:     versionSunsetAt,
+			Link:         "https://example.com/migration",
+		}, func(r rapi.Router) {
+			r.Get("/items", newHandler())
+		})
+		r.Version("v2", nil, func(r rapi.Router) {
+			r.Get("/items", newHandler())
+		})
+		r.Get("/health", newHandler())
+	})
+	return r
+}
+
+func Test_Version(t *testing.T) {
+	type args struct {
+		path    string
+		version string
+	}
+	type want struct {
+		status      int
+		body        string
+		deprecation string
+		sunset      string
+		link        string
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "パスのバージョン",
+			args: args{
+				path: "/api/v2/items",
+			},
+			want: want{
+				status: http.StatusOK,
+				body:   "v2",
+			},
+		},
+		{
+			name: "非推奨のバージョン",
+			args: args{
+				path: "/api/v1/items",
+			},
+			want: want{
+				status:      http.StatusOK,
+				body:        "v1",
+				deprecation: fmt.Sprintf("@%d", versionDeprecatedAt.Unix()),
+				sunset:      "Thu, 01 Jan 2099 00:00:00 GMT",
+				link:        `<https://example.com/migration>; rel="deprecation"`,
+			},
+		},
+		{
+			name: "廃止されたバージョンは410",
+			args: args{
+				path: "/api/v0/items",
+			},
+			want: want{
+				status: http.StatusGone,
+				body:   "{\"status\":410,\"message\":\"api version v0 is no longer available\"}",
+				sunset: "Wed, 01 Jan 2020 00:00:00 GMT",
+			},
+		},
+		{
+			name: "ヘッダーのバージョン",
+			args: args{
+				path:    "/api/items",
+				version: "v1",
+			},
+			want: want{
+				status:      http.StatusOK,
+				body:        "v1",
+				deprecation: fmt.Sprintf("@%d", versionDeprecatedAt.Unix()),
+				sunset:      "Thu, 01 Jan 2099 00:00:00 GMT",
+				link:        `<https://example.com/migration>; rel="deprecation"`,
+			},
+		},
+		{
+			name: "デフォルトのバージョン",
+			args: args{
+				path: "/api/items",
+			},
+			want: want{
+				status: http.StatusOK,
+				body:   "v2",
+			},
+		},
+		{
+			name: "パスのバージョンはヘッダーより優先する",
+			args: args{
+				path:    "/api/v2/items",
+				version: "v1",
+			},
+			want: want{
+				status: http.StatusOK,
+				body:   "v2",
+			},
+		},
+		{
+			name: "バージョンのないエンドポイント",
+			args: args{
+				path:    "/api/health",
+				version: "v1",
+			},
+			want: want{
+				status: http.StatusOK,
+				body:   "",
+			},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.args.path, nil)
+			if tc.args.version != "" {
+				req.Header.Set("Api-Version", tc.args.version)
+			}
+			rec := httptest.NewRecorder()
+			newVersionRouter().ServeHTTP(rec, req)
+
+			if rec.Code != tc.want.status {
+				t.Errorf("got: %v, want: %v", rec.Code, tc.want.status)
+			}
+			if got := rec.Body.String(); got != tc.want.body {
+				t.Errorf("got: %v, want: %v", got, tc.want.body)
+			}
+			header := rec.Header()
+			if got := header.Get("Vary"); got != "Api-Version" {
+				t.Errorf("got: %v, want: %v", got, "Api-Version")
+			}
+			for key, want := range map[string]string{
+				"Deprecation": tc.want.deprecation,
+				"Sunset":      tc.want.sunset,
+				"Link":        tc.want.link,
+			} {
+				if got := header.Get(key); got != want {
+					t.Errorf("%s got: %v, want: %v", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func Test_FilterRouterDefinitionsByVersion(t *testing.T) {
+	type args struct {
+		version string
+	}
+	type want struct {
+		definitions []string
+	}
+	type testCase struct {
+		name string
+		args args
+		want want
+	}
+
+	// テストケース
+	tcs := []testCase{
+		{
+			name: "非推奨のバージョン",
+			args: args{
+				version: "v1",
+			},
+			want: want{
+				definitions: []string{"/api/v1/items:v1:true:2099-01-01T00:00:00Z"},
+			},
+		},
+		{
+			name: "バージョンのないエンドポイント",
+			args: args{
+				version: "",
+			},
+			want: want{
+				definitions: []string{"/api/health::false:<nil>"},
+			},
+		},
+	}
+
+	// 実行
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			routerDefinitions, _ := newVersionRouter().GetRouterDefinition()
+			definitions := []string{}
+			for _, rd := range rapi.FilterRouterDefinitionsByVersion(routerDefinitions, tc.args.version) {
+				sunset := "<nil>"
+				if rd.Sunset != nil {
+					sunset = rd.Sunset.Format(time.RFC3339)
+				}
+				definitions = append(definitions, fmt.Sprintf("%s:%s:%v:%s", rd.FullPathName, rd.Version, rd.Deprecated, sunset))
+			}
+			if fmt.Sprint(definitions) != fmt.Sprint(tc.want.definitions) {
+				t.Errorf("got: %v, want: %v", definitions, tc.want.definitions)
+			}
+		})
+	}
+}